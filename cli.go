@@ -4,8 +4,10 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"log/slog"
+	"net/http"
 	"os"
-	"strconv"
+	"sort"
 	"strings"
 	"text/tabwriter"
 	"time"
@@ -25,41 +27,67 @@ const (
 	usage       = "Bulk delete AWS EBS snapshot"
 	description = "Bulk delete AWS EBS snapshot with tags and expiration date."
 
-	flagNameRegion          = "region"
-	flagNameProfile         = "profile"
-	flagNameAccessKeyID     = "access-key-id"
-	flagNameSecretAccessKey = "secret-access-key"
-	flagNameSessionToken    = "session-token"
-	flagNameVerbose         = "verbose"
-	flagNamePlan            = "plan"
-	flagNameAge             = "age"
-	flagNameTags            = "tags"
-	flagShowProperties      = "show-properties"
-	flagShowTags            = "show-tags"
-)
+	flagNameRegion           = "region"
+	flagNameProfile          = "profile"
+	flagNameAccessKeyID      = "access-key-id"
+	flagNameSecretAccessKey  = "secret-access-key"
+	flagNameSessionToken     = "session-token"
+	flagNameVerbose          = "verbose"
+	flagNameLogLevel         = "log-level"
+	flagNameLogFormat        = "log-format"
+	flagNamePlan             = "plan"
+	flagNameAge              = "age"
+	flagNameTags             = "tags"
+	flagNameOlderThan        = "older-than"
+	flagNameCreatedBefore    = "created-before"
+	flagNameCreatedAfter     = "created-after"
+	flagNameSnapshotIDsFrom  = "snapshot-ids-from"
+	flagNameMinSize          = "min-size"
+	flagNameMaxSize          = "max-size"
+	flagNameEncrypted        = "encrypted"
+	flagNameKmsKeyID         = "kms-key-id"
+	flagNameStorageTier      = "storage-tier"
+	flagNameState            = "state"
+	flagNameVolumeID         = "volume-id"
+	flagNameDescriptionRegex = "description-regex"
+	flagNameShared           = "shared"
+	flagNameAction           = "action"
+	flagShowProperties       = "show-properties"
+	flagShowTags             = "show-tags"
+	flagNameResultFile       = "result-file"
+	flagNameFrom             = "from"
+	flagNamePriceFile        = "price-file"
+	flagNameSavingsTag       = "savings-tag"
+	flagNameSummarizeBy      = "summarize-by"
+	flagNameSummaryOnly      = "summary-only"
+	flagNameSortBy           = "sort-by"
+	flagNameAutoApprove      = "auto-approve"
+	flagNameSnapshotIDs      = "snapshot-ids"
 
-var defaultProperties = []string{
-	"Description",
-	"Encrypted",
-	"OwnerAlias",
-	"OwnerId",
-	"Progress",
-	"SnapshotId",
-	"StartTime",
-	"State",
-	"StorageTier",
-	"VolumeId",
-	"VolumeSize",
-	"Tags",
-}
+	flagNameRequireRecycleBin = "require-recycle-bin"
+	flagNameUnlockGovernance  = "unlock-governance"
+	flagNameIncludeManaged    = "include-managed"
 
-var defaultPropertiesSet = make(map[string]struct{})
+	flagNameLaunchTemplateVersions = "launch-template-versions"
 
-func init() {
-	for _, v := range defaultProperties {
-		defaultPropertiesSet[v] = struct{}{}
-	}
-}
+	flagNameCopyToRegion  = "copy-to-region"
+	flagNameCopyToAccount = "copy-to-account"
+	flagNameCopyRoleARN   = "copy-role-arn"
+	flagNameCopyKmsKeyID  = "copy-kms-key-id"
+
+	flagNameNotifyWebhook  = "notify-webhook"
+	flagNameNotifyOn       = "notify-on"
+	flagNameNotifyTemplate = "notify-template"
+
+	flagNameEventsSNSTopicARN = "events-sns-topic-arn"
+	flagNameEventsBus         = "events-bus"
+
+	flagNameMetricsTextfile    = "metrics-textfile"
+	flagNameMetricsPushgateway = "metrics-pushgateway"
+
+	flagNameExplain   = "explain"
+	flagNameExplainID = "explain-id"
+)
 
 func toEnvVarCase(prefix, name string) string {
 	// eg. foo-bar-baz to PREFIX_FOO_BAR_BAZ
@@ -76,12 +104,18 @@ func app() *cli.App {
 	app.Name = appName
 	app.Usage = usage
 	app.Description = description
-	app.Flags = []cli.Flag{
+	app.Flags = legacyFlags()
+	app.Action = action
+	app.Commands = commands()
+	return app
+}
+
+func globalFlags() []cli.Flag {
+	return []cli.Flag{
 		&cli.StringFlag{
-			Name:     flagNameRegion,
-			EnvVars:  []string{toEnvVarCase("AWS", flagNameRegion)},
-			Usage:    "AWS region",
-			Required: true,
+			Name:    flagNameRegion,
+			EnvVars: []string{toEnvVarCase("AWS", flagNameRegion)},
+			Usage:   "AWS region, required by the commands which call the AWS API",
 		},
 		&cli.StringFlag{
 			Name:    flagNameProfile,
@@ -107,44 +141,430 @@ func app() *cli.App {
 			Name:  flagNameVerbose,
 			Usage: "verbose mode (enable connection debugging)",
 		},
-		&cli.BoolFlag{
-			Name:  flagNamePlan,
-			Usage: "don't make any changes; instead, try to predict some of the changes that may occur",
+		&cli.StringFlag{
+			Name:  flagNameLogLevel,
+			Usage: "log the decision on each snapshot, retries and timing to stderr at the level (levels: debug, info, warn, error)",
 		},
+		&cli.StringFlag{
+			Name:  flagNameLogFormat,
+			Usage: "format of the log (formats: text, json) (default: text)",
+		},
+	}
+}
+
+// legacyFlags are the flags of the root action, which is kept as a deprecated
+// alias of the plan and apply commands.
+func legacyFlags() []cli.Flag {
+	var flags []cli.Flag
+	flags = append(flags, globalFlags()...)
+	flags = append(flags, &cli.BoolFlag{
+		Name:  flagNamePlan,
+		Usage: "don't make any changes; instead, try to predict some of the changes that may occur",
+	})
+	flags = append(flags, selectorFlags()...)
+	flags = append(flags, actionFlags()...)
+	flags = append(flags, protectionFlags()...)
+	flags = append(flags, copyFlags()...)
+	flags = append(flags, displayFlags()...)
+	flags = append(flags, savingsFlags()...)
+	flags = append(flags, resultFlags()...)
+	flags = append(flags, notifyFlags()...)
+	flags = append(flags, eventFlags()...)
+	flags = append(flags, metricsFlags()...)
+	return flags
+}
+
+func selectorFlags() []cli.Flag {
+	return []cli.Flag{
 		&cli.UintFlag{
 			Name:  flagNameAge,
 			Usage: "snapshot retention period (days)",
 		},
+		&cli.StringFlag{
+			Name:  flagNameOlderThan,
+			Usage: "snapshot age as a duration (eg. 36h, 3d, 2w)",
+		},
+		&cli.StringFlag{
+			Name:  flagNameCreatedBefore,
+			Usage: "select snapshots created before the time (eg. 2026-01-01T00:00:00Z or 2026-01-01)",
+		},
+		&cli.StringFlag{
+			Name:  flagNameCreatedAfter,
+			Usage: "select snapshots created at or after the time (eg. 2026-01-01T00:00:00Z or 2026-01-01)",
+		},
 		&cli.StringSliceFlag{
 			Name:  flagNameTags,
 			Usage: "snapshot tags (eg. Name=foo OR Name=\"foo,bar,baz)\"",
 		},
+		&cli.StringFlag{
+			Name:  flagNameSnapshotIDsFrom,
+			Usage: "read snapshot ids to select from the file, or stdin with \"-\" (one per line, or separated by spaces or commas)",
+		},
+		&cli.Int64Flag{
+			Name:  flagNameMinSize,
+			Usage: "select snapshots whose volume size is at least the GiB",
+		},
+		&cli.Int64Flag{
+			Name:  flagNameMaxSize,
+			Usage: "select snapshots whose volume size is at most the GiB",
+		},
+		&cli.StringFlag{
+			Name:  flagNameEncrypted,
+			Usage: "select encrypted (true) or unencrypted (false) snapshots",
+		},
+		&cli.StringFlag{
+			Name:  flagNameKmsKeyID,
+			Usage: "select snapshots encrypted with the KMS key id or ARN",
+		},
+		&cli.StringFlag{
+			Name:  flagNameStorageTier,
+			Usage: "select snapshots in the storage tier (standard, archive)",
+		},
+		&cli.StringFlag{
+			Name:  flagNameState,
+			Usage: "select snapshots in the state (completed, error, pending)",
+		},
+		&cli.StringFlag{
+			Name:  flagNameVolumeID,
+			Usage: "select snapshots of the volume",
+		},
+		&cli.StringFlag{
+			Name:  flagNameDescriptionRegex,
+			Usage: "select snapshots whose description matches the regular expression",
+		},
+		&cli.StringFlag{
+			Name:  flagNameShared,
+			Usage: "select snapshots shared publicly, with external accounts, or either (public, external, any)",
+		},
+	}
+}
+
+func actionFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:  flagNameAction,
+			Usage: "action to apply to the selected snapshots (delete, unshare); unshare removes the permissions selected by --shared instead of deleting",
+		},
+	}
+}
+
+func protectionFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.BoolFlag{
+			Name:  flagNameRequireRecycleBin,
+			Usage: "abort unless a Recycle Bin retention rule covers every snapshot to delete",
+		},
+		&cli.BoolFlag{
+			Name:  flagNameUnlockGovernance,
+			Usage: "unlock snapshots locked in governance mode before deleting them (requires ec2:UnlockSnapshot)",
+		},
+		&cli.BoolFlag{
+			Name:  flagNameIncludeManaged,
+			Usage: "include snapshots managed by Data Lifecycle Manager or AWS Backup",
+		},
+		&cli.StringFlag{
+			Name:  flagNameLaunchTemplateVersions,
+			Usage: "launch template versions whose block device mappings protect snapshots from deletion (versions: latest, all) (default: latest, which scans $Latest and $Default)",
+		},
+	}
+}
+
+func copyFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:  flagNameCopyToRegion,
+			Usage: "copy each snapshot to the region before deleting it",
+		},
+		&cli.StringFlag{
+			Name:  flagNameCopyToAccount,
+			Usage: "copy each snapshot to the AWS account before deleting it (requires --copy-role-arn)",
+		},
+		&cli.StringFlag{
+			Name:  flagNameCopyRoleARN,
+			Usage: "IAM role in the --copy-to-account account to copy snapshots with",
+		},
+		&cli.StringFlag{
+			Name:  flagNameCopyKmsKeyID,
+			Usage: "KMS key of the target to re-encrypt copies with",
+		},
+	}
+}
+
+func displayFlags() []cli.Flag {
+	return []cli.Flag{
 		&cli.StringSliceFlag{
 			Name:  flagShowProperties,
-			Usage: "show properties in stdout (properties: Description, Encrypted, OwnerAlias, OwnerId, Progress, SnapshotId, StartTime, State, StorageTier, VolumeId, VolumeSize, Tags)",
+			Usage: "show properties in stdout (properties: " + strings.Join(propertyNames(), ", ") + ", tag:<key>)",
 		},
 		&cli.StringSliceFlag{
 			Name:  flagShowTags,
 			Usage: "show tags in stdout",
 		},
+		&cli.StringFlag{
+			Name:  flagNameSortBy,
+			Usage: "sort snapshots in stdout by the property, prefix with '-' for descending order (eg. -VolumeSize)",
+		},
+		&cli.StringFlag{
+			Name:  flagNameSummarizeBy,
+			Usage: "show aggregated counts per group (groups: volume, tag:<key>, owner, region, tier, month)",
+		},
+		&cli.BoolFlag{
+			Name:  flagNameSummaryOnly,
+			Usage: "show only the summary instead of each snapshot (requires --summarize-by)",
+		},
+	}
+}
+
+func savingsFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:  flagNamePriceFile,
+			Usage: "JSON price table used to estimate savings (eg. {\"us-east-1\": {\"standard\": 0.05, \"archive\": 0.0125}})",
+		},
+		&cli.StringFlag{
+			Name:  flagNameSavingsTag,
+			Usage: "tag key to break down estimated savings by",
+		},
+	}
+}
+
+func explainFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.BoolFlag{
+			Name:  flagNameExplain,
+			Usage: "show every described snapshot with the selector or protection which included or excluded it",
+		},
+		&cli.StringFlag{
+			Name:  flagNameExplainID,
+			Usage: "show the decisions on a single snapshot (eg. snap-0123456789abcdef0)",
+		},
+	}
+}
+
+func resultFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:  flagNameResultFile,
+			Usage: "write the result as JSON to the file",
+		},
+	}
+}
+
+func notifyFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:  flagNameNotifyWebhook,
+			Usage: "POST a notification to the webhook URL, prefix with slack= or teams= for their formats (eg. slack=https://hooks.slack.com/services/...)",
+		},
+		&cli.StringSliceFlag{
+			Name:  flagNameNotifyOn,
+			Usage: "events to notify (events: plan, success, failure) (default: success, failure)",
+		},
+		&cli.StringFlag{
+			Name:  flagNameNotifyTemplate,
+			Usage: "text/template file rendering the JSON payload of --notify-webhook (eg. {\"text\": {{ json .Text }}})",
+		},
+	}
+}
+
+func eventFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:  flagNameEventsSNSTopicARN,
+			Usage: "publish run events (RunStarted, PlanCreated, SnapshotDeleted, SnapshotDeleteFailed, RunFinished) to the SNS topic",
+		},
+		&cli.StringFlag{
+			Name:  flagNameEventsBus,
+			Usage: "publish run events to the EventBridge bus name or ARN",
+		},
+	}
+}
+
+// newLogger returns nil without --log-level, so that nothing is logged.
+func newLogger(c *cli.Context) (*slog.Logger, error) {
+	s := c.String(flagNameLogLevel)
+	if s == "" {
+		return nil, nil
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return nil, fmt.Errorf("invalid log level: %s", s)
+	}
+	opts := &slog.HandlerOptions{Level: level}
+	switch format := c.String(flagNameLogFormat); format {
+	case "", "text":
+		return slog.New(slog.NewTextHandler(c.App.ErrWriter, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(c.App.ErrWriter, opts)), nil
+	default:
+		return nil, fmt.Errorf("invalid log format: %s", format)
+	}
+}
+
+func metricsFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:  flagNameMetricsTextfile,
+			Usage: "write run metrics to the file for the textfile collector of node_exporter (eg. /var/lib/node_exporter/snapshots.prom)",
+		},
+		&cli.StringFlag{
+			Name:  flagNameMetricsPushgateway,
+			Usage: "push run metrics to the Prometheus Pushgateway URL (eg. http://pushgateway:9091)",
+		},
 	}
-	app.Action = action
-	return app
 }
 
 func action(c *cli.Context) error {
-	cfg := parseConfig(c)
+	_, _ = fmt.Fprintf(c.App.ErrWriter, "Warning: running without a command is deprecated, use \"plan\" or \"apply\" instead.\n\n")
+	cfg, err := parseSelectorConfig(c)
+	if err != nil {
+		return err
+	}
+	return runAction(c, cfg)
+}
+
+func runAction(c *cli.Context, cfg *snapshot.BulkDeleteConfig) error {
 	bulkDelete, err := snapshot.NewBulkDelete(cfg)
 	if err != nil {
 		return err
 	}
-	showPropertiesSet := initShowPropertiesSet(c.StringSlice(flagShowProperties))
+	opts, err := newOptions(c, cfg, snapshot.NewRunID(), "")
+	if err != nil {
+		return err
+	}
+	return runWithMetrics(c, cfg, opts, func(opts snapshot.Options) error {
+		return bulkDelete.RunWithOptions(context.Background(), opts)
+	})
+}
+
+// runWithMetrics writes the metrics of the run even if it fails, so that a
+// failing cron run is visible.
+func runWithMetrics(c *cli.Context, cfg *snapshot.BulkDeleteConfig, opts snapshot.Options, run func(opts snapshot.Options) error) error {
+	textfile := c.String(flagNameMetricsTextfile)
+	gateway := c.String(flagNameMetricsPushgateway)
+	if textfile == "" && gateway == "" {
+		return run(opts)
+	}
+	metrics := snapshot.NewMetrics(cfg.Region, cfg.Action)
+	err := run(snapshot.ChainOptions(metrics.Options(), opts))
+	metrics.Finish(err)
+	if textfile != "" {
+		if err := metrics.WriteTextfile(textfile); err != nil {
+			_, _ = fmt.Fprintf(c.App.ErrWriter, "Warning: failed to write metrics: %v\n", err)
+		}
+	}
+	if gateway != "" {
+		client := &http.Client{Timeout: 10 * time.Second}
+		if err := metrics.Push(context.Background(), client, gateway); err != nil {
+			_, _ = fmt.Fprintf(c.App.ErrWriter, "Warning: failed to push metrics: %v\n", err)
+		}
+	}
+	return err
+}
+
+func readResultFile(name string) (*snapshot.Result, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	result, err := snapshot.ReadResult(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read result %s: %w", name, err)
+	}
+	return result, nil
+}
+
+func readPrices(name string) (snapshot.Prices, error) {
+	if name == "" {
+		return snapshot.DefaultPrices(), nil
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	prices, err := snapshot.ReadPrices(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prices %s: %w", name, err)
+	}
+	return prices, nil
+}
+
+func writeResultFile(name string, result *snapshot.Result) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := result.Write(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func newOptions(c *cli.Context, cfg *snapshot.BulkDeleteConfig, runID, retryOf string) (snapshot.Options, error) {
+	showProperties, err := initShowProperties(c.StringSlice(flagShowProperties))
+	if err != nil {
+		return snapshot.Options{}, err
+	}
+	sortBy, err := parseSortBy(c.String(flagNameSortBy))
+	if err != nil {
+		return snapshot.Options{}, err
+	}
 	showTagsSet := initShowTagsSet(c.StringSlice(flagShowTags))
-	var bar *pb.ProgressBar
-	return bulkDelete.RunWithOptions(context.Background(), snapshot.Options{
-		AfterDescribeSnapshotsFunc: func(snapshots []*ec2.Snapshot) error {
-			writeSnapshotDeletionPlan(os.Stdout, snapshots, showPropertiesSet, showTagsSet)
-			if cfg.Plan {
+	resultFile := c.String(flagNameResultFile)
+	autoApprove := c.Bool(flagNameAutoApprove)
+	savingsTag := c.String(flagNameSavingsTag)
+	prices, err := readPrices(c.String(flagNamePriceFile))
+	if err != nil {
+		return snapshot.Options{}, err
+	}
+	summarizeBy, err := parseSummarizeBy(c.String(flagNameSummarizeBy))
+	if err != nil {
+		return snapshot.Options{}, err
+	}
+	summaryOnly := c.Bool(flagNameSummaryOnly)
+	if summaryOnly && summarizeBy == nil {
+		return snapshot.Options{}, fmt.Errorf("--%s requires --%s", flagNameSummaryOnly, flagNameSummarizeBy)
+	}
+	notifier, err := newNotifier(c, cfg, runID)
+	if err != nil {
+		return snapshot.Options{}, err
+	}
+	publisher, err := newEventPublisher(c, cfg, runID)
+	if err != nil {
+		return snapshot.Options{}, err
+	}
+	var (
+		bar       *pb.ProgressBar
+		startedAt time.Time
+		copies    = make(map[string]string)
+	)
+	opts := snapshot.Options{
+		BeforeDescribeSnapshotsFunc: func() error {
+			startedAt = time.Now()
+			return nil
+		},
+		AfterPlanFunc: func(plan *snapshot.Plan) error {
+			snapshots := plan.Snapshots
+			for _, v := range plan.Warnings {
+				_, _ = fmt.Fprintf(c.App.ErrWriter, "Warning: %s\n", v)
+			}
+			if summarizeBy != nil {
+				writeSummary(os.Stdout, *summarizeBy, snapshot.Summarize(cfg.Region, *summarizeBy, snapshots, nil))
+			}
+			if summaryOnly {
+				writePlanFooter(os.Stdout, plan)
+			} else {
+				writeSnapshotDeletionPlan(os.Stdout, plan, sortBy, showProperties, showTagsSet)
+			}
+			if cfg.Action != snapshot.ActionUnshare {
+				// Unsharing keeps the snapshots, so it saves nothing.
+				savings, err := snapshot.EstimateSavings(cfg.Region, snapshots, prices, savingsTag)
+				writeSavings(os.Stdout, savings, err)
+			}
+			if cfg.Plan || autoApprove {
 				return nil
 			}
 			writeConfirmMessage(os.Stdout)
@@ -158,37 +578,206 @@ func action(c *cli.Context) error {
 			bar.Increment()
 			return nil
 		},
+		EachCopySnapshotFunc: func(snapshot *ec2.Snapshot, copySnapshotID string) error {
+			copies[aws.StringValue(snapshot.SnapshotId)] = copySnapshotID
+			return nil
+		},
 		AfterDeleteSnapshotsFunc: func(successful []*ec2.Snapshot, failed []*snapshot.ErrorWithSnapshot) error {
 			bar.Finish()
-			writeSnapshotDeletionResult(os.Stdout, successful, failed, showPropertiesSet, showTagsSet)
-			return nil
+			if summarizeBy != nil {
+				writeSummary(os.Stdout, *summarizeBy, snapshot.Summarize(cfg.Region, *summarizeBy, successful, failed))
+			}
+			if summaryOnly {
+				writeResultFooter(os.Stdout, successful, failed)
+			} else {
+				writeSnapshotDeletionResult(os.Stdout, sortBy.sorted(successful), sortBy.sortedErrors(failed), showProperties, showTagsSet, copyColumns(copies)...)
+			}
+			if resultFile == "" {
+				return nil
+			}
+			result := snapshot.NewResult(runID, successful, failed)
+			result.RetryOf = retryOf
+			result.Action = cfg.Action
+			result.Shared = cfg.Shared
+			result.SetCopySnapshotIDs(copies)
+			result.Region = cfg.Region
+			result.StartedAt = startedAt
+			result.FinishedAt = time.Now()
+			if cfg.Action != snapshot.ActionUnshare {
+				result.Savings, _ = snapshot.EstimateSavings(cfg.Region, successful, prices, savingsTag)
+			}
+			return writeResultFile(resultFile, result)
+		},
+	}
+	if notifier == nil && publisher == nil {
+		return opts, nil
+	}
+	// Events are published before the confirmation prompt of opts, so that
+	// PlanCreated is sent when the plan is created rather than approved.
+	var chain []snapshot.Options
+	if publisher != nil {
+		chain = append(chain, publisher.Options(context.Background()))
+	}
+	chain = append(chain, opts)
+	if notifier != nil {
+		chain = append(chain, notifier.Options(context.Background()))
+	}
+	return snapshot.ChainOptions(chain...), nil
+}
+
+// newEventPublisher returns nil without --events-sns-topic-arn and --events-bus.
+func newEventPublisher(c *cli.Context, cfg *snapshot.BulkDeleteConfig, runID string) (*snapshot.EventPublisher, error) {
+	topic := c.String(flagNameEventsSNSTopicARN)
+	bus := c.String(flagNameEventsBus)
+	if topic == "" && bus == "" {
+		return nil, nil
+	}
+	return snapshot.NewEventPublisher(context.Background(), cfg, &snapshot.EventPublisherConfig{
+		RunID:        runID,
+		SNSTopicARN:  topic,
+		EventBusName: bus,
+		OnError: func(err error) {
+			_, _ = fmt.Fprintf(c.App.ErrWriter, "Warning: %v\n", err)
+		},
+	})
+}
+
+// newNotifier returns nil without --notify-webhook.
+func newNotifier(c *cli.Context, cfg *snapshot.BulkDeleteConfig, runID string) (*snapshot.Notifier, error) {
+	urls := c.StringSlice(flagNameNotifyWebhook)
+	if len(urls) == 0 {
+		return nil, nil
+	}
+	var tmpl string
+	if name := c.String(flagNameNotifyTemplate); name != "" {
+		b, err := os.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		tmpl = string(b)
+	}
+	var webhooks []*snapshot.Webhook
+	for _, v := range urls {
+		w, err := snapshot.ParseWebhook(v)
+		if err != nil {
+			return nil, err
+		}
+		w.Template = tmpl
+		webhooks = append(webhooks, w)
+	}
+	var on []snapshot.NotifyEvent
+	for _, v := range c.StringSlice(flagNameNotifyOn) {
+		on = append(on, snapshot.NotifyEvent(strings.TrimSpace(v)))
+	}
+	return snapshot.NewNotifier(&snapshot.NotifierConfig{
+		RunID:    runID,
+		Region:   cfg.Region,
+		Action:   cfg.Action,
+		Webhooks: webhooks,
+		On:       on,
+		OnError: func(err error) {
+			_, _ = fmt.Fprintf(c.App.ErrWriter, "Warning: %v\n", err)
 		},
 	})
 }
 
 func parseConfig(c *cli.Context) *snapshot.BulkDeleteConfig {
 	return &snapshot.BulkDeleteConfig{
-		Region:          c.String(flagNameRegion),
-		Profile:         c.String(flagNameProfile),
-		AccessKeyID:     c.String(flagNameAccessKeyID),
-		SecretAccessKey: c.String(flagNameSecretAccessKey),
-		SessionToken:    c.String(flagNameSessionToken),
-		Verbose:         c.Bool(flagNameVerbose),
-		Plan:            c.Bool(flagNamePlan),
-		Age:             c.Uint(flagNameAge),
-		Tags:            c.StringSlice(flagNameTags),
+		Region:           c.String(flagNameRegion),
+		Profile:          c.String(flagNameProfile),
+		AccessKeyID:      c.String(flagNameAccessKeyID),
+		SecretAccessKey:  c.String(flagNameSecretAccessKey),
+		SessionToken:     c.String(flagNameSessionToken),
+		Verbose:          c.Bool(flagNameVerbose),
+		Plan:             c.Bool(flagNamePlan),
+		Age:              c.Uint(flagNameAge),
+		Tags:             c.StringSlice(flagNameTags),
+		OlderThan:        c.String(flagNameOlderThan),
+		CreatedBefore:    c.String(flagNameCreatedBefore),
+		CreatedAfter:     c.String(flagNameCreatedAfter),
+		MinSize:          c.Int64(flagNameMinSize),
+		MaxSize:          c.Int64(flagNameMaxSize),
+		Encrypted:        c.String(flagNameEncrypted),
+		KmsKeyID:         c.String(flagNameKmsKeyID),
+		StorageTier:      c.String(flagNameStorageTier),
+		State:            c.String(flagNameState),
+		VolumeID:         c.String(flagNameVolumeID),
+		DescriptionRegex: c.String(flagNameDescriptionRegex),
+		Shared:           snapshot.Shared(c.String(flagNameShared)),
+		Action:           snapshot.Action(c.String(flagNameAction)),
+
+		RequireRecycleBin: c.Bool(flagNameRequireRecycleBin),
+		UnlockGovernance:  c.Bool(flagNameUnlockGovernance),
+		IncludeManaged:    c.Bool(flagNameIncludeManaged),
+
+		LaunchTemplateVersions: snapshot.LaunchTemplateVersions(c.String(flagNameLaunchTemplateVersions)),
+
+		CopyToRegion:  c.String(flagNameCopyToRegion),
+		CopyToAccount: c.String(flagNameCopyToAccount),
+		CopyRoleARN:   c.String(flagNameCopyRoleARN),
+		CopyKmsKeyID:  c.String(flagNameCopyKmsKeyID),
 	}
 }
 
-func initShowPropertiesSet(showProperties []string) map[string]struct{} {
-	if len(showProperties) == 0 {
-		return defaultPropertiesSet
+// errNoRegion is checked by the commands which call the AWS API, so that
+// serve and api run with the regions of their configs.
+var errNoRegion = fmt.Errorf("required flag %q not set", flagNameRegion)
+
+// parseAWSConfig is parseConfig which requires --region.
+func parseAWSConfig(c *cli.Context) (*snapshot.BulkDeleteConfig, error) {
+	cfg := parseConfig(c)
+	if cfg.Region == "" {
+		return nil, errNoRegion
 	}
-	set := make(map[string]struct{})
-	for _, v := range showProperties {
-		set[strings.TrimSpace(v)] = struct{}{}
+	return cfg, nil
+}
+
+// parseSelectorConfig is parseAWSConfig which also reads --snapshot-ids-from.
+func parseSelectorConfig(c *cli.Context) (*snapshot.BulkDeleteConfig, error) {
+	cfg, err := parseAWSConfig(c)
+	if err != nil {
+		return nil, err
 	}
-	return set
+	cfg.Logger, err = newLogger(c)
+	if err != nil {
+		return nil, err
+	}
+	name := c.String(flagNameSnapshotIDsFrom)
+	if name == "" {
+		return cfg, nil
+	}
+	cfg.SnapshotIDs, err = readSnapshotIDs(name, c.App.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func readSnapshotIDs(name string, stdin io.Reader) ([]string, error) {
+	if name == "-" {
+		return snapshot.ReadSnapshotIDs(stdin)
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	ids, err := snapshot.ReadSnapshotIDs(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot ids %s: %w", name, err)
+	}
+	return ids, nil
+}
+
+func parseSummarizeBy(s string) (*snapshot.GroupBy, error) {
+	if s == "" {
+		return nil, nil
+	}
+	by, err := snapshot.ParseGroupBy(s)
+	if err != nil {
+		return nil, err
+	}
+	return &by, nil
 }
 
 func initShowTagsSet(showTags []string) map[string]struct{} {
@@ -206,7 +795,7 @@ func runConfirmPrompt() error {
 	}
 	_, err := prompt.Run()
 	if err != nil {
-		return fmt.Errorf("\nApply cancelled.")
+		return fmt.Errorf("\nApply %w.", snapshot.ErrCancelled)
 	}
 	return nil
 }
@@ -219,94 +808,220 @@ Only 'y' will be accepted to approve.
 `, appName)
 }
 
-func writeSnapshotDeletionPlan(w io.Writer, snapshots []*ec2.Snapshot, showPropertiesSet map[string]struct{}, showTagsSet map[string]struct{}) {
+func writeSnapshotDeletionPlan(w io.Writer, plan *snapshot.Plan, sortBy *sortBy, showProperties []string, showTagsSet map[string]struct{}) {
+	writeSnapshotTable(w, sortBy.sorted(plan.Snapshots), showProperties, showTagsSet, planColumns(plan)...)
+	if len(plan.Excluded) > 0 {
+		writeExcludedTable(w, plan.Excluded)
+	}
+	for _, id := range plan.NotFound {
+		_, _ = fmt.Fprintf(w, "%s was not found.\n", id)
+	}
+	if len(plan.NotFound) > 0 {
+		_, _ = fmt.Fprintf(w, "\n")
+	}
+	writePlanFooter(w, plan)
+}
+
+func writeExcludedTable(w io.Writer, excluded []*snapshot.ExcludedSnapshot) {
+	tw := tabwriter.NewWriter(w, 0, 1, 4, ' ', tabwriter.TabIndent)
+	_, _ = tw.Write([]byte("Excluded\tSnapshotId\tVolumeId\tReason\tDetail\t\n"))
+	for _, v := range excluded {
+		_, _ = fmt.Fprintf(tw, "excluded\t%s\t%s\t%s\t%s\t\n",
+			aws.StringValue(v.Snapshot.SnapshotId),
+			aws.StringValue(v.Snapshot.VolumeId),
+			v.Reason,
+			v.Detail)
+	}
+	_ = tw.Flush()
+	_, _ = fmt.Fprintf(w, "\n")
+}
+
+func writeExplainTable(w io.Writer, explanations []*snapshot.Explanation) {
 	tw := tabwriter.NewWriter(w, 0, 1, 4, ' ', tabwriter.TabIndent)
-	headerLine := buildHeaderLine(showPropertiesSet)
+	_, _ = tw.Write([]byte("SnapshotId\tVolumeId\tStartTime\tDecision\tReason\t\n"))
+	var included int
+	for _, v := range explanations {
+		volumeID, startTime := "-", "-"
+		if v.Snapshot != nil {
+			volumeID = aws.StringValue(v.Snapshot.VolumeId)
+			startTime = aws.TimeValue(v.Snapshot.StartTime).Format(time.RFC3339)
+		}
+		decision := "excluded"
+		if v.Included {
+			decision = "included"
+			included++
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t\n", v.SnapshotID, volumeID, startTime, decision, v.Reason())
+	}
+	_ = tw.Flush()
+	_, _ = fmt.Fprintf(w, "\nExplained: %d snapshots, %d included, %d excluded.\n\n",
+		len(explanations), included, len(explanations)-included)
+}
+
+func writeExplainTrace(w io.Writer, e *snapshot.Explanation) {
+	id := e.SnapshotID
+	if e.Snapshot == nil {
+		_, _ = fmt.Fprintf(w, "%s was not returned by DescribeSnapshots.\n\n", id)
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 1, 4, ' ', tabwriter.TabIndent)
+	_, _ = tw.Write([]byte("Stage\tRule\tDecision\tDetail\t\n"))
+	for _, d := range e.Decisions {
+		decision := "excluded"
+		if d.Included {
+			decision = "included"
+		}
+		detail := d.Detail
+		if detail == "" {
+			detail = "-"
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t\n", d.Stage, d.Rule, decision, detail)
+	}
+	_ = tw.Flush()
+	result := "excluded by " + e.Reason()
+	if e.Included {
+		result = "included"
+	}
+	_, _ = fmt.Fprintf(w, "\n%s: %s.\n\n", id, result)
+}
+
+func writeSnapshotTable(w io.Writer, snapshots []*ec2.Snapshot, showProperties []string, showTagsSet map[string]struct{}, extras ...extraColumn) {
+	tw := tabwriter.NewWriter(w, 0, 1, 4, ' ', tabwriter.TabIndent)
+	headerLine := buildHeaderLine(showProperties)
+	for _, e := range extras {
+		headerLine += e.name + "\t"
+	}
 	_, _ = tw.Write([]byte(headerLine + "\t\n"))
 	for _, v := range snapshots {
-		line := buildPropertiesLine(v, showPropertiesSet, showTagsSet)
+		line := buildPropertiesLine(v, showProperties, showTagsSet)
+		for _, e := range extras {
+			line += e.value(v) + "\t"
+		}
 		_, _ = tw.Write([]byte(line + "\t\n"))
 	}
 	_ = tw.Flush()
 	_, _ = fmt.Fprintf(w, "\n")
-	_, _ = fmt.Fprintf(w, "Plan: %d to delete.\n\n", len(snapshots))
 }
 
-func writeSnapshotDeletionResult(w io.Writer, successful []*ec2.Snapshot, failed []*snapshot.ErrorWithSnapshot, showPropertiesSet map[string]struct{}, showTagsSet map[string]struct{}) {
+func writeRecycleBinTable(w io.Writer, snapshots []*ec2.SnapshotRecycleBinInfo) {
 	tw := tabwriter.NewWriter(w, 0, 1, 4, ' ', tabwriter.TabIndent)
-	headerLine := buildHeaderLine(showPropertiesSet)
-	_, _ = tw.Write([]byte("Result\t" + headerLine + "error\t\n"))
+	_, _ = tw.Write([]byte("SnapshotId\tVolumeId\tDescription\tRecycleBinEnterTime\tRecycleBinExitTime\t\n"))
+	for _, v := range snapshots {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t\n",
+			aws.StringValue(v.SnapshotId),
+			aws.StringValue(v.VolumeId),
+			aws.StringValue(v.Description),
+			aws.TimeValue(v.RecycleBinEnterTime).Format(time.RFC3339),
+			aws.TimeValue(v.RecycleBinExitTime).Format(time.RFC3339))
+	}
+	_ = tw.Flush()
+	_, _ = fmt.Fprintf(w, "\n")
+}
+
+func writePlanFooter(w io.Writer, plan *snapshot.Plan) {
+	_, _ = fmt.Fprintf(w, "Plan: %d to %s", len(plan.Snapshots), plan.Action)
+	if len(plan.Excluded) > 0 {
+		_, _ = fmt.Fprintf(w, ", %d excluded", len(plan.Excluded))
+	}
+	if len(plan.NotFound) > 0 {
+		_, _ = fmt.Fprintf(w, ", %d not found", len(plan.NotFound))
+	}
+	_, _ = fmt.Fprintf(w, ".\n\n")
+}
+
+func writeSavings(w io.Writer, savings *snapshot.Savings, err error) {
+	if err != nil {
+		_, _ = fmt.Fprintf(w, "Estimated monthly savings: unknown (%v).\n\n", err)
+		return
+	}
+	_, _ = fmt.Fprintf(w, "Estimated monthly savings: $%.2f", savings.Total)
+	var regions []string
+	for region := range savings.ByRegion {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+	for _, region := range regions {
+		_, _ = fmt.Fprintf(w, ", %s: $%.2f", region, savings.ByRegion[region])
+	}
+	_, _ = fmt.Fprintf(w, ".\n")
+	if savings.TagKey != "" {
+		var values []string
+		for value := range savings.ByTag {
+			values = append(values, value)
+		}
+		sort.Strings(values)
+		for _, value := range values {
+			label := value
+			if label == "" {
+				label = "-"
+			}
+			_, _ = fmt.Fprintf(w, "  %s=%s: $%.2f\n", savings.TagKey, label, savings.ByTag[value])
+		}
+	}
+	_, _ = fmt.Fprintf(w, "\n")
+}
+
+func writeSnapshotDeletionResult(w io.Writer, successful []*ec2.Snapshot, failed []*snapshot.ErrorWithSnapshot, showProperties []string, showTagsSet map[string]struct{}, extras ...extraColumn) {
+	tw := tabwriter.NewWriter(w, 0, 1, 4, ' ', tabwriter.TabIndent)
+	headerLine := buildHeaderLine(showProperties)
+	for _, e := range extras {
+		headerLine += e.name + "\t"
+	}
+	_, _ = tw.Write([]byte("Result\t" + headerLine + "kind\terror\t\n"))
 	for _, v := range successful {
-		line := buildPropertiesLine(v, showPropertiesSet, showTagsSet)
-		_, _ = tw.Write([]byte("successful\t" + line + "-\t\n"))
+		line := buildPropertiesLine(v, showProperties, showTagsSet)
+		for _, e := range extras {
+			line += e.value(v) + "\t"
+		}
+		_, _ = tw.Write([]byte("successful\t" + line + "-\t-\t\n"))
 	}
 	for _, v := range failed {
-		line := buildPropertiesLine(v.Snapshot, showPropertiesSet, showTagsSet)
-		_, _ = tw.Write([]byte("failed\t" + line + v.Error.Error() + "\t\n"))
+		line := buildPropertiesLine(v.Snapshot, showProperties, showTagsSet)
+		for _, e := range extras {
+			line += e.value(v.Snapshot) + "\t"
+		}
+		_, _ = tw.Write([]byte("failed\t" + line + string(v.Kind) + "\t" + v.Error.Error() + "\t\n"))
 	}
 	_ = tw.Flush()
 	_, _ = fmt.Fprintf(w, "\n")
-	_, _ = fmt.Fprintf(w, "Result: %d to successful, %d to failed.\n\n", len(successful), len(failed))
+	writeResultFooter(w, successful, failed)
 }
 
-func buildHeaderLine(showPropertiesSet map[string]struct{}) string {
-	var b strings.Builder
-	for _, dp := range defaultProperties {
-		if _, ok := showPropertiesSet[dp]; ok {
-			b.WriteString(dp + "\t")
-		}
+func writeResultFooter(w io.Writer, successful []*ec2.Snapshot, failed []*snapshot.ErrorWithSnapshot) {
+	_, _ = fmt.Fprintf(w, "Result: %d to successful, %d to failed.\n", len(successful), len(failed))
+	if len(failed) > 0 {
+		_, _ = fmt.Fprintf(w, "Failed: %s.\n", buildFailureKindsLine(failed))
 	}
-	return b.String()
+	_, _ = fmt.Fprintf(w, "\n")
 }
 
-func buildTagsLine(tags []*ec2.Tag, showTagsSet map[string]struct{}) string {
-	tagsMap := make(map[string]string)
-	for _, tag := range tags {
-		key := aws.StringValue(tag.Key)
-		value := aws.StringValue(tag.Value)
-		if _, ok := showTagsSet[key]; ok || len(showTagsSet) == 0 {
-			tagsMap["\""+key+"\""] = "\"" + value + "\""
+func writeSummary(w io.Writer, by snapshot.GroupBy, groups []*snapshot.Group) {
+	tw := tabwriter.NewWriter(w, 0, 1, 4, ' ', tabwriter.TabIndent)
+	_, _ = tw.Write([]byte(by.String() + "\tCount\tTotalGiB\tOldest\tNewest\tFailed\t\n"))
+	for _, g := range groups {
+		key := g.Key
+		if key == "" {
+			key = "-"
 		}
+		_, _ = fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%s\t%d\t\n", key, g.Count, g.TotalGiB,
+			g.Oldest.Format(time.RFC3339), g.Newest.Format(time.RFC3339), g.Failed)
 	}
-	tagsLine := fmt.Sprintf("%v", tagsMap)
-	tagsLine = strings.TrimPrefix(tagsLine, "map[")
-	tagsLine = strings.TrimSuffix(tagsLine, "]")
-	return tagsLine
+	_ = tw.Flush()
+	_, _ = fmt.Fprintf(w, "\n")
 }
 
-func buildPropertiesLine(snapshot *ec2.Snapshot, showPropertiesSet map[string]struct{}, showTagsSet map[string]struct{}) string {
-	var b strings.Builder
-	for _, dp := range defaultProperties {
-		if _, ok := showPropertiesSet[dp]; !ok {
-			continue
-		}
-		switch dp {
-		case "Description":
-			b.WriteString(aws.StringValue(snapshot.Description))
-		case "Encrypted":
-			b.WriteString(strconv.FormatBool(aws.BoolValue(snapshot.Encrypted)))
-		case "OwnerAlias":
-			b.WriteString(aws.StringValue(snapshot.OwnerAlias))
-		case "OwnerId":
-			b.WriteString(aws.StringValue(snapshot.OwnerId))
-		case "Progress":
-			b.WriteString(aws.StringValue(snapshot.Progress))
-		case "SnapshotId":
-			b.WriteString(aws.StringValue(snapshot.SnapshotId))
-		case "StartTime":
-			b.WriteString(aws.TimeValue(snapshot.StartTime).Format(time.RFC3339))
-		case "State":
-			b.WriteString(aws.StringValue(snapshot.State))
-		case "StorageTier":
-			b.WriteString(aws.StringValue(snapshot.StorageTier))
-		case "VolumeId":
-			b.WriteString(aws.StringValue(snapshot.VolumeId))
-		case "VolumeSize":
-			b.WriteString(strconv.FormatInt(aws.Int64Value(snapshot.VolumeSize), 10))
-		case "Tags":
-			b.WriteString(buildTagsLine(snapshot.Tags, showTagsSet))
+func buildFailureKindsLine(failed []*snapshot.ErrorWithSnapshot) string {
+	counts := make(map[snapshot.FailureKind]int)
+	for _, v := range failed {
+		counts[v.Kind]++
+	}
+	var parts []string
+	for _, kind := range snapshot.FailureKinds {
+		part := fmt.Sprintf("%d %s", counts[kind], kind)
+		if kind == snapshot.FailureKindNotFound {
+			part += " (already gone)"
 		}
-		b.WriteString("\t")
+		parts = append(parts, part)
 	}
-	return b.String()
+	return strings.Join(parts, ", ")
 }
@@ -1,10 +1,15 @@
 package main
 
 import (
+	"bytes"
 	"flag"
+	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/vvatanabe/aws-snapshot-bulk-delete/snapshot"
 
 	"github.com/urfave/cli/v2"
@@ -21,10 +26,9 @@ func Test_app(t *testing.T) {
 	{
 		want := []cli.Flag{
 			&cli.StringFlag{
-				Name:     "region",
-				EnvVars:  []string{"AWS_REGION"},
-				Usage:    "AWS region",
-				Required: true,
+				Name:    "region",
+				EnvVars: []string{"AWS_REGION"},
+				Usage:   "AWS region, required by the commands which call the AWS API",
 			},
 			&cli.StringFlag{
 				Name:    "profile",
@@ -50,6 +54,14 @@ func Test_app(t *testing.T) {
 				Name:  "verbose",
 				Usage: "verbose mode (enable connection debugging)",
 			},
+			&cli.StringFlag{
+				Name:  "log-level",
+				Usage: "log the decision on each snapshot, retries and timing to stderr at the level (levels: debug, info, warn, error)",
+			},
+			&cli.StringFlag{
+				Name:  "log-format",
+				Usage: "format of the log (formats: text, json) (default: text)",
+			},
 			&cli.BoolFlag{
 				Name:  "plan",
 				Usage: "don't make any changes; instead, try to predict some of the changes that may occur",
@@ -58,18 +70,158 @@ func Test_app(t *testing.T) {
 				Name:  "age",
 				Usage: "snapshot retention period (days)",
 			},
+			&cli.StringFlag{
+				Name:  "older-than",
+				Usage: "snapshot age as a duration (eg. 36h, 3d, 2w)",
+			},
+			&cli.StringFlag{
+				Name:  "created-before",
+				Usage: "select snapshots created before the time (eg. 2026-01-01T00:00:00Z or 2026-01-01)",
+			},
+			&cli.StringFlag{
+				Name:  "created-after",
+				Usage: "select snapshots created at or after the time (eg. 2026-01-01T00:00:00Z or 2026-01-01)",
+			},
 			&cli.StringSliceFlag{
 				Name:  "tags",
 				Usage: "snapshot tags (eg. Name=foo OR Name=\"foo,bar,baz)\"",
 			},
+			&cli.StringFlag{
+				Name:  "snapshot-ids-from",
+				Usage: "read snapshot ids to select from the file, or stdin with \"-\" (one per line, or separated by spaces or commas)",
+			},
+			&cli.Int64Flag{
+				Name:  "min-size",
+				Usage: "select snapshots whose volume size is at least the GiB",
+			},
+			&cli.Int64Flag{
+				Name:  "max-size",
+				Usage: "select snapshots whose volume size is at most the GiB",
+			},
+			&cli.StringFlag{
+				Name:  "encrypted",
+				Usage: "select encrypted (true) or unencrypted (false) snapshots",
+			},
+			&cli.StringFlag{
+				Name:  "kms-key-id",
+				Usage: "select snapshots encrypted with the KMS key id or ARN",
+			},
+			&cli.StringFlag{
+				Name:  "storage-tier",
+				Usage: "select snapshots in the storage tier (standard, archive)",
+			},
+			&cli.StringFlag{
+				Name:  "state",
+				Usage: "select snapshots in the state (completed, error, pending)",
+			},
+			&cli.StringFlag{
+				Name:  "volume-id",
+				Usage: "select snapshots of the volume",
+			},
+			&cli.StringFlag{
+				Name:  "description-regex",
+				Usage: "select snapshots whose description matches the regular expression",
+			},
+			&cli.StringFlag{
+				Name:  "shared",
+				Usage: "select snapshots shared publicly, with external accounts, or either (public, external, any)",
+			},
+			&cli.StringFlag{
+				Name:  "action",
+				Usage: "action to apply to the selected snapshots (delete, unshare); unshare removes the permissions selected by --shared instead of deleting",
+			},
+			&cli.BoolFlag{
+				Name:  "require-recycle-bin",
+				Usage: "abort unless a Recycle Bin retention rule covers every snapshot to delete",
+			},
+			&cli.BoolFlag{
+				Name:  "unlock-governance",
+				Usage: "unlock snapshots locked in governance mode before deleting them (requires ec2:UnlockSnapshot)",
+			},
+			&cli.BoolFlag{
+				Name:  "include-managed",
+				Usage: "include snapshots managed by Data Lifecycle Manager or AWS Backup",
+			},
+			&cli.StringFlag{
+				Name:  "launch-template-versions",
+				Usage: "launch template versions whose block device mappings protect snapshots from deletion (versions: latest, all) (default: latest, which scans $Latest and $Default)",
+			},
+			&cli.StringFlag{
+				Name:  "copy-to-region",
+				Usage: "copy each snapshot to the region before deleting it",
+			},
+			&cli.StringFlag{
+				Name:  "copy-to-account",
+				Usage: "copy each snapshot to the AWS account before deleting it (requires --copy-role-arn)",
+			},
+			&cli.StringFlag{
+				Name:  "copy-role-arn",
+				Usage: "IAM role in the --copy-to-account account to copy snapshots with",
+			},
+			&cli.StringFlag{
+				Name:  "copy-kms-key-id",
+				Usage: "KMS key of the target to re-encrypt copies with",
+			},
 			&cli.StringSliceFlag{
 				Name:  "show-properties",
-				Usage: "show properties in stdout (properties: Description, Encrypted, OwnerAlias, OwnerId, Progress, SnapshotId, StartTime, State, StorageTier, VolumeId, VolumeSize, Tags)",
+				Usage: "show properties in stdout (properties: DataEncryptionKeyId, Description, Encrypted, KmsKeyId, OutpostArn, OwnerAlias, OwnerId, Progress, RestoreExpiryTime, SnapshotId, SseType, StartTime, State, StateMessage, StorageTier, Tags, VolumeId, VolumeSize, tag:<key>)",
 			},
 			&cli.StringSliceFlag{
 				Name:  "show-tags",
 				Usage: "show tags in stdout",
 			},
+			&cli.StringFlag{
+				Name:  "sort-by",
+				Usage: "sort snapshots in stdout by the property, prefix with '-' for descending order (eg. -VolumeSize)",
+			},
+			&cli.StringFlag{
+				Name:  "summarize-by",
+				Usage: "show aggregated counts per group (groups: volume, tag:<key>, owner, region, tier, month)",
+			},
+			&cli.BoolFlag{
+				Name:  "summary-only",
+				Usage: "show only the summary instead of each snapshot (requires --summarize-by)",
+			},
+			&cli.StringFlag{
+				Name:  "price-file",
+				Usage: "JSON price table used to estimate savings (eg. {\"us-east-1\": {\"standard\": 0.05, \"archive\": 0.0125}})",
+			},
+			&cli.StringFlag{
+				Name:  "savings-tag",
+				Usage: "tag key to break down estimated savings by",
+			},
+			&cli.StringFlag{
+				Name:  "result-file",
+				Usage: "write the result as JSON to the file",
+			},
+			&cli.StringSliceFlag{
+				Name:  "notify-webhook",
+				Usage: "POST a notification to the webhook URL, prefix with slack= or teams= for their formats (eg. slack=https://hooks.slack.com/services/...)",
+			},
+			&cli.StringSliceFlag{
+				Name:  "notify-on",
+				Usage: "events to notify (events: plan, success, failure) (default: success, failure)",
+			},
+			&cli.StringFlag{
+				Name:  "notify-template",
+				Usage: "text/template file rendering the JSON payload of --notify-webhook (eg. {\"text\": {{ json .Text }}})",
+			},
+			&cli.StringFlag{
+				Name:  "events-sns-topic-arn",
+				Usage: "publish run events (RunStarted, PlanCreated, SnapshotDeleted, SnapshotDeleteFailed, RunFinished) to the SNS topic",
+			},
+			&cli.StringFlag{
+				Name:  "events-bus",
+				Usage: "publish run events to the EventBridge bus name or ARN",
+			},
+			&cli.StringFlag{
+				Name:  "metrics-textfile",
+				Usage: "write run metrics to the file for the textfile collector of node_exporter (eg. /var/lib/node_exporter/snapshots.prom)",
+			},
+			&cli.StringFlag{
+				Name:  "metrics-pushgateway",
+				Usage: "push run metrics to the Prometheus Pushgateway URL (eg. http://pushgateway:9091)",
+			},
 		}
 		if len(got.Flags) != len(want) {
 			t.Errorf("got %d, want %d", len(got.Flags), len(want))
@@ -80,7 +232,16 @@ func Test_app(t *testing.T) {
 			}
 		}
 	}
-
+	{
+		want := []string{"list", "plan", "apply", "report", "restore", "retry", "serve", "api"}
+		var names []string
+		for _, v := range got.Commands {
+			names = append(names, v.Name)
+		}
+		if !reflect.DeepEqual(names, want) {
+			t.Errorf("got = %v, want %v", names, want)
+		}
+	}
 }
 
 func Test_toEnvVarCase(t *testing.T) {
@@ -145,45 +306,168 @@ func Test_parseConfig(t *testing.T) {
 	}
 }
 
-func Test_initShowPropertiesSet(t *testing.T) {
-	type args struct {
-		showProperties []string
+func Test_parseAWSConfig(t *testing.T) {
+	set := flag.NewFlagSet("test", flag.ContinueOnError)
+	set.String("region", "", "")
+	c := cli.NewContext(app(), set, nil)
+	if _, err := parseAWSConfig(c); err != errNoRegion {
+		t.Errorf("parseAWSConfig() error = %v, want %v", err, errNoRegion)
 	}
+	_ = set.Set("region", "us-east-1")
+	cfg, err := parseAWSConfig(c)
+	if err != nil || cfg.Region != "us-east-1" {
+		t.Errorf("parseAWSConfig() = %v, %v", cfg, err)
+	}
+}
+
+func Test_retryResultFile(t *testing.T) {
 	tests := []struct {
-		name string
-		args args
-		want map[string]struct{}
+		from string
+		want string
 	}{
+		{from: "result.json", want: "retry-run-2.json"},
+		{from: "/var/lib/results/result.json", want: "/var/lib/results/retry-run-2.json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.from, func(t *testing.T) {
+			if got := retryResultFile(tt.from, "run-2"); got != filepath.FromSlash(tt.want) {
+				t.Errorf("retryResultFile() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_buildFailureKindsLine(t *testing.T) {
+	failed := []*snapshot.ErrorWithSnapshot{
+		{Kind: snapshot.FailureKindInUse},
+		{Kind: snapshot.FailureKindInUse},
+		{Kind: snapshot.FailureKindNotFound},
+	}
+	want := "0 retryable, 2 in-use, 1 not-found (already gone), 0 denied, 0 other"
+	if got := buildFailureKindsLine(failed); got != want {
+		t.Errorf("buildFailureKindsLine() = %v, want %v", got, want)
+	}
+}
+
+func Test_writeSavings(t *testing.T) {
+	var buf bytes.Buffer
+	writeSavings(&buf, &snapshot.Savings{
+		Total:    6,
+		ByRegion: map[string]float64{"us-east-1": 6},
+		TagKey:   "Team",
+		ByTag:    map[string]float64{"foo": 5, "": 1},
+	}, nil)
+	want := `Estimated monthly savings: $6.00, us-east-1: $6.00.
+  Team=-: $1.00
+  Team=foo: $5.00
+
+`
+	if got := buf.String(); got != want {
+		t.Errorf("writeSavings() = %q, want %q", got, want)
+	}
+}
+
+func Test_writeExplainTable(t *testing.T) {
+	startTime := time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC)
+	explanations := []*snapshot.Explanation{
 		{
-			name: "default",
-			args: args{
-				showProperties: []string{},
+			SnapshotID: "snap-1",
+			Snapshot:   &ec2.Snapshot{SnapshotId: aws.String("snap-1"), VolumeId: aws.String("vol-1"), StartTime: aws.Time(startTime)},
+			Included:   true,
+			Decisions: []*snapshot.Decision{
+				{Stage: snapshot.DecisionStageDescribe, Rule: "describe", Included: true},
+				{Stage: snapshot.DecisionStagePlan, Rule: "delete", Included: true},
 			},
-			want: defaultPropertiesSet,
 		},
 		{
-			name: "some tags",
-			args: args{
-				showProperties: []string{
-					"Description ",
-					" SnapshotId",
-					" StartTime ",
-					"  Tags  ",
-				},
-			},
-			want: map[string]struct{}{
-				"Description": {},
-				"SnapshotId":  {},
-				"StartTime":   {},
-				"Tags":        {},
+			SnapshotID: "snap-2",
+			Snapshot:   &ec2.Snapshot{SnapshotId: aws.String("snap-2"), VolumeId: aws.String("vol-1"), StartTime: aws.Time(startTime)},
+			Decisions: []*snapshot.Decision{
+				{Stage: snapshot.DecisionStageDescribe, Rule: "describe", Included: true},
+				{Stage: snapshot.DecisionStageSelector, Rule: "tags", Detail: "Env=dev"},
 			},
 		},
+		{
+			SnapshotID: "snap-3",
+			Decisions: []*snapshot.Decision{
+				{Stage: snapshot.DecisionStageDescribe, Rule: "snapshot-ids", Detail: "not returned by DescribeSnapshots"},
+			},
+		},
+	}
+	var buf bytes.Buffer
+	writeExplainTable(&buf, explanations)
+	// The columns are padded, because each line ends with a tab like the other tables.
+	want := "SnapshotId    VolumeId    StartTime               Decision    Reason                                             \n" +
+		"snap-1        vol-1       2023-01-02T15:04:05Z    included    delete                                             \n" +
+		"snap-2        vol-1       2023-01-02T15:04:05Z    excluded    tags: Env=dev                                      \n" +
+		"snap-3        -           -                       excluded    snapshot-ids: not returned by DescribeSnapshots    \n" +
+		"\nExplained: 3 snapshots, 1 included, 2 excluded.\n\n"
+	if got := buf.String(); got != want {
+		t.Errorf("writeExplainTable() = %q, want %q", got, want)
+	}
+
+	buf.Reset()
+	writeExplainTrace(&buf, explanations[1])
+	want = "Stage       Rule        Decision    Detail     \n" +
+		"describe    describe    included    -          \n" +
+		"selector    tags        excluded    Env=dev    \n" +
+		"\nsnap-2: excluded by tags: Env=dev.\n\n"
+	if got := buf.String(); got != want {
+		t.Errorf("writeExplainTrace() = %q, want %q", got, want)
+	}
+
+	buf.Reset()
+	writeExplainTrace(&buf, explanations[2])
+	want = "snap-3 was not returned by DescribeSnapshots.\n\n"
+	if got := buf.String(); got != want {
+		t.Errorf("writeExplainTrace() = %q, want %q", got, want)
+	}
+}
+
+func Test_newLogger(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantNil bool
+		wantErr bool
+	}{
+		{
+			name:    "disabled",
+			args:    nil,
+			wantNil: true,
+		},
+		{
+			name: "json",
+			args: []string{"--log-level", "debug", "--log-format", "json"},
+		},
+		{
+			name:    "invalid level",
+			args:    []string{"--log-level", "verbose"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid format",
+			args:    []string{"--log-level", "info", "--log-format", "yaml"},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := initShowPropertiesSet(tt.args.showProperties)
-			if len(got) != len(tt.want) {
-				t.Errorf("initShowPropertiesSet() = %v, want %v", got, tt.want)
+			set := flag.NewFlagSet("test", flag.ContinueOnError)
+			set.String("log-level", "", "")
+			set.String("log-format", "", "")
+			if err := set.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			a := app()
+			a.ErrWriter = &bytes.Buffer{}
+			got, err := newLogger(cli.NewContext(a, set, nil))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("newLogger() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && (got == nil) != tt.wantNil {
+				t.Errorf("newLogger() = %v, wantNil %v", got, tt.wantNil)
 			}
 		})
 	}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// checkpoint holds the snapshots which an interrupted run did not process,
+// so that the next invocation continues with them.
+type checkpoint struct {
+	RunID       string   `json:"run_id"`
+	SnapshotIDs []string `json:"snapshot_ids"`
+	// CopySnapshotIDs holds the copies of the remaining snapshots, eg. one
+	// whose wait was interrupted, so that they are not copied again.
+	CopySnapshotIDs map[string]string `json:"copy_snapshot_ids,omitempty"`
+	CreatedAt       time.Time         `json:"created_at"`
+}
+
+type checkpointStore interface {
+	// load returns nil if there is no checkpoint.
+	load(ctx context.Context) (*checkpoint, error)
+	save(ctx context.Context, cp *checkpoint) error
+	clear(ctx context.Context) error
+}
+
+type s3API interface {
+	GetObjectWithContext(ctx aws.Context, input *s3.GetObjectInput, opts ...request.Option) (*s3.GetObjectOutput, error)
+	PutObjectWithContext(ctx aws.Context, input *s3.PutObjectInput, opts ...request.Option) (*s3.PutObjectOutput, error)
+	DeleteObjectWithContext(ctx aws.Context, input *s3.DeleteObjectInput, opts ...request.Option) (*s3.DeleteObjectOutput, error)
+}
+
+type s3CheckpointStore struct {
+	svc    s3API
+	bucket string
+	key    string
+}
+
+func (s *s3CheckpointStore) load(ctx context.Context) (*checkpoint, error) {
+	out, err := s.svc.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key),
+	})
+	var aerr awserr.Error
+	if errors.As(err, &aerr) && aerr.Code() == s3.ErrCodeNoSuchKey {
+		return nil, nil
+	}
+	// S3 denies a missing key instead of NoSuchKey without s3:ListBucket,
+	// which looks the same as a key which can not be read.
+	if errors.As(err, &aerr) && aerr.Code() == "AccessDenied" {
+		return nil, fmt.Errorf("failed to load checkpoint s3://%s/%s, the role needs s3:GetObject on the key and s3:ListBucket on the bucket: %w", s.bucket, s.key, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer out.Body.Close()
+	var cp checkpoint
+	if err := json.NewDecoder(out.Body).Decode(&cp); err != nil {
+		return nil, err
+	}
+	return &cp, nil
+}
+
+func (s *s3CheckpointStore) save(ctx context.Context, cp *checkpoint) error {
+	b, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	_, err = s.svc.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(s.key),
+		Body:        bytes.NewReader(b),
+		ContentType: aws.String("application/json"),
+	})
+	return err
+}
+
+func (s *s3CheckpointStore) clear(ctx context.Context) error {
+	// Deleting a missing key succeeds, so a run without a checkpoint can
+	// clear it too.
+	_, err := s.svc.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key),
+	})
+	return err
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+type fakeS3 struct {
+	s3API
+	body string
+	err  error
+}
+
+func (f *fakeS3) GetObjectWithContext(ctx aws.Context, input *s3.GetObjectInput, opts ...request.Option) (*s3.GetObjectOutput, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &s3.GetObjectOutput{Body: io.NopCloser(strings.NewReader(f.body))}, nil
+}
+
+func Test_s3CheckpointStore_load(t *testing.T) {
+	tests := []struct {
+		name       string
+		svc        *fakeS3
+		wantRunID  string
+		wantNil    bool
+		wantErrMsg string
+	}{
+		{
+			name:      "checkpoint",
+			svc:       &fakeS3{body: `{"run_id": "run-1", "snapshot_ids": ["snap-1"]}`},
+			wantRunID: "run-1",
+		},
+		{
+			name:    "no such key",
+			svc:     &fakeS3{err: awserr.New(s3.ErrCodeNoSuchKey, "not found", nil)},
+			wantNil: true,
+		},
+		{
+			// A missing key without s3:ListBucket.
+			name:       "access denied",
+			svc:        &fakeS3{err: awserr.New("AccessDenied", "Access Denied", nil)},
+			wantErrMsg: "s3:ListBucket",
+		},
+		{
+			name:       "other error",
+			svc:        &fakeS3{err: errors.New("connection reset")},
+			wantErrMsg: "connection reset",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &s3CheckpointStore{svc: tt.svc, bucket: "bucket", key: "dev.json"}
+			got, err := s.load(context.Background())
+			if tt.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErrMsg) {
+					t.Errorf("load() error = %v, want %s", err, tt.wantErrMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantNil {
+				if got != nil {
+					t.Errorf("load() = %+v, want nil", got)
+				}
+				return
+			}
+			if got == nil || got.RunID != tt.wantRunID {
+				t.Errorf("load() = %+v, want run id %s", got, tt.wantRunID)
+			}
+		})
+	}
+}
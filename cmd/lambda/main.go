@@ -0,0 +1,241 @@
+// Command lambda runs a BulkDeleteConfig on AWS Lambda, eg. on an EventBridge
+// schedule, and returns the result as JSON.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/vvatanabe/aws-snapshot-bulk-delete/snapshot"
+)
+
+const (
+	// defaultMaxSnapshots is the guard of an event without MaxSnapshots.
+	defaultMaxSnapshots = 100
+	// deadlineMargin is kept before the deadline of the invocation to save
+	// the checkpoint and return the result. It is at most a fifth of the
+	// time of the invocation.
+	deadlineMargin = 10 * time.Second
+)
+
+var (
+	errCredentials        = errors.New("credentials are not accepted, the function uses its role")
+	errCheckpointNoBucket = errors.New("CheckpointKey requires CheckpointBucket")
+)
+
+// Event is the payload of an invocation, which is a BulkDeleteConfig with the
+// field names of the library, plus the options of the function.
+//
+//	{"Name": "dev-30d", "Region": "us-east-1", "Age": 30, "Tags": ["Env=dev"], "CheckpointBucket": "my-bucket"}
+type Event struct {
+	snapshot.BulkDeleteConfig
+
+	// Name tells the policies apart in the logs and the default checkpoint
+	// key.
+	Name string
+	// MaxSnapshots refuses to delete when the plan has more snapshots, so
+	// that a mistaken selector does not delete far more than intended. It
+	// is defaultMaxSnapshots if zero, and no limit if negative.
+	MaxSnapshots int
+	// CheckpointBucket and CheckpointKey keep the snapshots which an
+	// invocation could not process before its deadline, so that the next
+	// invocation continues with them. CheckpointKey is
+	// aws-snapshot-bulk-delete/<Name>.json by default.
+	CheckpointBucket string
+	CheckpointKey    string
+}
+
+func (ev *Event) checkpointKey() string {
+	if ev.CheckpointKey != "" {
+		return ev.CheckpointKey
+	}
+	name := ev.Name
+	if name == "" {
+		name = "default"
+	}
+	return "aws-snapshot-bulk-delete/" + name + ".json"
+}
+
+// Response is the result of an invocation.
+type Response struct {
+	Name  string `json:"name,omitempty"`
+	RunID string `json:"run_id"`
+	// ContinuedFrom is the run id of the checkpoint the invocation continued.
+	ContinuedFrom string           `json:"continued_from,omitempty"`
+	Plan          bool             `json:"plan"`
+	Planned       int              `json:"planned"`
+	Excluded      int              `json:"excluded"`
+	NotFound      []string         `json:"not_found,omitempty"`
+	Remaining     int              `json:"remaining"`
+	Checkpoint    string           `json:"checkpoint,omitempty"`
+	Result        *snapshot.Result `json:"result,omitempty"`
+}
+
+type handler struct {
+	logger *slog.Logger
+	// runFunc and newCheckpointStore call the AWS API, and are replaced in
+	// tests.
+	runFunc            func(ctx context.Context, cfg *snapshot.BulkDeleteConfig, opts snapshot.Options) error
+	newCheckpointStore func(ev *Event) checkpointStore
+}
+
+// withDeadlineMargin cancels the context before the deadline of the
+// invocation, so that an interrupted run can still save its checkpoint.
+func withDeadlineMargin(ctx context.Context) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return context.WithCancel(ctx)
+	}
+	margin := deadlineMargin
+	if d := time.Until(deadline) / 5; d < margin {
+		margin = d
+	}
+	return context.WithDeadline(ctx, deadline.Add(-margin))
+}
+
+func (h *handler) handle(ctx context.Context, ev *Event) (*Response, error) {
+	cfg := ev.BulkDeleteConfig
+	if cfg.AccessKeyID != "" || cfg.SecretAccessKey != "" || cfg.SessionToken != "" {
+		return nil, errCredentials
+	}
+	if ev.CheckpointKey != "" && ev.CheckpointBucket == "" {
+		return nil, errCheckpointNoBucket
+	}
+	maxSnapshots := ev.MaxSnapshots
+	if maxSnapshots == 0 {
+		maxSnapshots = defaultMaxSnapshots
+	}
+	resp := &Response{Name: ev.Name, RunID: snapshot.NewRunID(), Plan: cfg.Plan}
+	logger := h.logger.With(slog.String("name", ev.Name), slog.String("run_id", resp.RunID))
+	cfg.Logger = logger
+
+	var store checkpointStore
+	if ev.CheckpointBucket != "" {
+		store = h.newCheckpointStore(ev)
+		resp.Checkpoint = "s3://" + ev.CheckpointBucket + "/" + ev.checkpointKey()
+		cp, err := store.load(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load checkpoint %s: %w", resp.Checkpoint, err)
+		}
+		if cp != nil && len(cp.SnapshotIDs) > 0 {
+			// The selectors still apply, so a snapshot which no longer
+			// matches them is not deleted.
+			cfg.SnapshotIDs = cp.SnapshotIDs
+			cfg.CopySnapshotIDs = cp.CopySnapshotIDs
+			resp.ContinuedFrom = cp.RunID
+			logger.Info("continuing from checkpoint", slog.String("continued_from", cp.RunID),
+				slog.Int("remaining", len(cp.SnapshotIDs)))
+		}
+	}
+
+	runCtx, cancel := withDeadlineMargin(ctx)
+	defer cancel()
+	startedAt := time.Now()
+	// The copies of the checkpoint are kept even if this invocation is
+	// interrupted before it reaches them.
+	copies := make(map[string]string)
+	for k, v := range cfg.CopySnapshotIDs {
+		copies[k] = v
+	}
+	err := h.runFunc(runCtx, &cfg, snapshot.Options{
+		AfterPlanFunc: func(plan *snapshot.Plan) error {
+			resp.Planned = len(plan.Snapshots)
+			resp.Excluded = len(plan.Excluded)
+			resp.NotFound = plan.NotFound
+			if maxSnapshots > 0 && len(plan.Snapshots) > maxSnapshots && !cfg.Plan {
+				return fmt.Errorf("plan has %d snapshots, more than MaxSnapshots %d", len(plan.Snapshots), maxSnapshots)
+			}
+			return nil
+		},
+		EachCopySnapshotFunc: func(snapshot *ec2.Snapshot, copySnapshotID string) error {
+			copies[aws.StringValue(snapshot.SnapshotId)] = copySnapshotID
+			return nil
+		},
+		AfterDeleteSnapshotsFunc: func(successful []*ec2.Snapshot, failed []*snapshot.ErrorWithSnapshot) error {
+			result := snapshot.NewResult(resp.RunID, successful, failed)
+			result.Action = cfg.Action
+			result.Shared = cfg.Shared
+			result.SetCopySnapshotIDs(copies)
+			result.Region = cfg.Region
+			result.StartedAt = startedAt
+			result.FinishedAt = time.Now()
+			resp.Result = result
+			return nil
+		},
+	})
+	var ierr *snapshot.InterruptedError
+	if errors.As(err, &ierr) {
+		resp.Remaining = len(ierr.Remaining)
+		if store == nil {
+			// The next invocation selects the remaining snapshots again.
+			return resp, nil
+		}
+		cp := &checkpoint{RunID: resp.RunID, CreatedAt: time.Now()}
+		for _, v := range ierr.Remaining {
+			id := aws.StringValue(v.SnapshotId)
+			cp.SnapshotIDs = append(cp.SnapshotIDs, id)
+			if copyID, ok := copies[id]; ok {
+				if cp.CopySnapshotIDs == nil {
+					cp.CopySnapshotIDs = make(map[string]string)
+				}
+				cp.CopySnapshotIDs[id] = copyID
+			}
+		}
+		// ctx is still alive for deadlineMargin.
+		if err := store.save(ctx, cp); err != nil {
+			return nil, fmt.Errorf("failed to save checkpoint %s: %w", resp.Checkpoint, err)
+		}
+		logger.Info("checkpoint saved", slog.String("checkpoint", resp.Checkpoint), slog.Int("remaining", resp.Remaining))
+		return resp, nil
+	}
+	if err != nil {
+		// The checkpoint is kept, so that the next invocation tries it again.
+		return nil, err
+	}
+	if store != nil && resp.ContinuedFrom != "" && !cfg.Plan {
+		if err := store.clear(ctx); err != nil {
+			return nil, fmt.Errorf("failed to clear checkpoint %s: %w", resp.Checkpoint, err)
+		}
+	}
+	return resp, nil
+}
+
+func run(ctx context.Context, cfg *snapshot.BulkDeleteConfig, opts snapshot.Options) error {
+	bulkDelete, err := snapshot.NewBulkDelete(cfg)
+	if err != nil {
+		return err
+	}
+	return bulkDelete.RunWithOptions(ctx, opts)
+}
+
+func newLogger() *slog.Logger {
+	// AWS_LAMBDA_LOG_LEVEL is set by the advanced logging controls of Lambda.
+	var level slog.Level
+	if s := os.Getenv("AWS_LAMBDA_LOG_LEVEL"); s != "" {
+		if err := level.UnmarshalText([]byte(s)); err != nil {
+			level = slog.LevelInfo
+		}
+	}
+	return slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+}
+
+func main() {
+	sess := session.Must(session.NewSession())
+	h := &handler{
+		logger:  newLogger(),
+		runFunc: run,
+		newCheckpointStore: func(ev *Event) checkpointStore {
+			return &s3CheckpointStore{svc: s3.New(sess), bucket: ev.CheckpointBucket, key: ev.checkpointKey()}
+		},
+	}
+	lambda.StartWithOptions(h.handle, lambda.WithDisallowUnknownFields(true))
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/vvatanabe/aws-snapshot-bulk-delete/snapshot"
+)
+
+type fakeCheckpointStore struct {
+	cp      *checkpoint
+	cleared bool
+}
+
+func (s *fakeCheckpointStore) load(ctx context.Context) (*checkpoint, error) {
+	return s.cp, nil
+}
+
+func (s *fakeCheckpointStore) save(ctx context.Context, cp *checkpoint) error {
+	s.cp = cp
+	return nil
+}
+
+func (s *fakeCheckpointStore) clear(ctx context.Context) error {
+	s.cp = nil
+	s.cleared = true
+	return nil
+}
+
+func snapshots(ids ...string) []*ec2.Snapshot {
+	var v []*ec2.Snapshot
+	for _, id := range ids {
+		v = append(v, &ec2.Snapshot{SnapshotId: aws.String(id)})
+	}
+	return v
+}
+
+// newTestHandler returns a handler whose run plans snapshots, deletes the
+// first deleted of them and is interrupted while copying the next one if
+// interrupt is set.
+func newTestHandler(store *fakeCheckpointStore, planned []*ec2.Snapshot, deleted int, interrupt bool) (*handler, *snapshot.BulkDeleteConfig) {
+	var got snapshot.BulkDeleteConfig
+	h := &handler{
+		logger: slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil)),
+		newCheckpointStore: func(ev *Event) checkpointStore {
+			return store
+		},
+	}
+	h.runFunc = func(ctx context.Context, cfg *snapshot.BulkDeleteConfig, opts snapshot.Options) error {
+		got = *cfg
+		if err := opts.AfterPlanFunc(&snapshot.Plan{Snapshots: planned}); err != nil {
+			return err
+		}
+		if cfg.Plan {
+			return nil
+		}
+		if err := opts.AfterDeleteSnapshotsFunc(planned[:deleted], nil); err != nil {
+			return err
+		}
+		if interrupt {
+			// The copy of the snapshot in flight is made, but its wait
+			// is interrupted.
+			v := planned[deleted]
+			if err := opts.EachCopySnapshotFunc(v, "copy-of-"+aws.StringValue(v.SnapshotId)); err != nil {
+				return err
+			}
+			return &snapshot.InterruptedError{Remaining: planned[deleted:], Err: context.DeadlineExceeded}
+		}
+		return nil
+	}
+	return h, &got
+}
+
+func Test_handler_handle(t *testing.T) {
+	store := &fakeCheckpointStore{}
+	h, _ := newTestHandler(store, snapshots("snap-1", "snap-2", "snap-3"), 1, true)
+	ev := &Event{Name: "dev", CheckpointBucket: "bucket"}
+	ev.Region = "us-east-1"
+	resp, err := h.handle(context.Background(), ev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Planned != 3 || resp.Remaining != 2 || len(resp.Result.Successful) != 1 ||
+		resp.Checkpoint != "s3://bucket/aws-snapshot-bulk-delete/dev.json" {
+		t.Errorf("handle() = %+v", resp)
+	}
+	if store.cp == nil || store.cp.RunID != resp.RunID || !reflect.DeepEqual(store.cp.SnapshotIDs, []string{"snap-2", "snap-3"}) ||
+		!reflect.DeepEqual(store.cp.CopySnapshotIDs, map[string]string{"snap-2": "copy-of-snap-2"}) {
+		t.Fatalf("checkpoint = %+v", store.cp)
+	}
+
+	// The next invocation continues with the remaining snapshots.
+	runID := resp.RunID
+	h, got := newTestHandler(store, snapshots("snap-2", "snap-3"), 2, false)
+	resp, err = h.handle(context.Background(), ev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.ContinuedFrom != runID || resp.Remaining != 0 || len(resp.Result.Successful) != 2 {
+		t.Errorf("handle() = %+v", resp)
+	}
+	if !reflect.DeepEqual(got.SnapshotIDs, []string{"snap-2", "snap-3"}) ||
+		!reflect.DeepEqual(got.CopySnapshotIDs, map[string]string{"snap-2": "copy-of-snap-2"}) {
+		t.Errorf("SnapshotIDs = %v, CopySnapshotIDs = %v", got.SnapshotIDs, got.CopySnapshotIDs)
+	}
+	if got := resp.Result.Successful[0].CopySnapshotID; got != "copy-of-snap-2" {
+		t.Errorf("CopySnapshotID = %v", got)
+	}
+	if !store.cleared || store.cp != nil {
+		t.Errorf("checkpoint is not cleared: %+v", store.cp)
+	}
+}
+
+func Test_handler_handle_plan(t *testing.T) {
+	store := &fakeCheckpointStore{cp: &checkpoint{RunID: "run-1", SnapshotIDs: []string{"snap-1"}}}
+	h, _ := newTestHandler(store, snapshots("snap-1"), 0, false)
+	ev := &Event{CheckpointBucket: "bucket"}
+	ev.Plan = true
+	resp, err := h.handle(context.Background(), ev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Plan || resp.Planned != 1 || resp.Result != nil {
+		t.Errorf("handle() = %+v", resp)
+	}
+	if store.cleared {
+		t.Error("checkpoint is cleared by a plan")
+	}
+}
+
+func Test_handler_handle_errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		event   *Event
+		planned int
+		wantErr string
+	}{
+		{
+			name:    "credentials",
+			event:   &Event{BulkDeleteConfig: snapshot.BulkDeleteConfig{AccessKeyID: "AKIA"}},
+			wantErr: errCredentials.Error(),
+		},
+		{
+			name:    "key without bucket",
+			event:   &Event{CheckpointKey: "key.json"},
+			wantErr: errCheckpointNoBucket.Error(),
+		},
+		{
+			name:    "more than default MaxSnapshots",
+			event:   &Event{},
+			planned: defaultMaxSnapshots + 1,
+			wantErr: "plan has 101 snapshots, more than MaxSnapshots 100",
+		},
+		{
+			name:    "more than MaxSnapshots",
+			event:   &Event{MaxSnapshots: 2},
+			planned: 3,
+			wantErr: "plan has 3 snapshots, more than MaxSnapshots 2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			planned := make([]*ec2.Snapshot, tt.planned)
+			h, _ := newTestHandler(&fakeCheckpointStore{}, planned, 0, false)
+			_, err := h.handle(context.Background(), tt.event)
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("handle() error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+
+	// A negative MaxSnapshots has no limit.
+	h, _ := newTestHandler(&fakeCheckpointStore{}, snapshots("snap-1", "snap-2"), 2, false)
+	if _, err := h.handle(context.Background(), &Event{MaxSnapshots: -1}); err != nil {
+		t.Errorf("handle() error = %v", err)
+	}
+}
+
+func Test_handler_handle_failed(t *testing.T) {
+	store := &fakeCheckpointStore{cp: &checkpoint{RunID: "run-1", SnapshotIDs: []string{"snap-1"}}}
+	h, _ := newTestHandler(store, nil, 0, false)
+	want := errors.New("throttled")
+	h.runFunc = func(ctx context.Context, cfg *snapshot.BulkDeleteConfig, opts snapshot.Options) error {
+		return want
+	}
+	if _, err := h.handle(context.Background(), &Event{CheckpointBucket: "bucket"}); !errors.Is(err, want) {
+		t.Errorf("handle() error = %v, want %v", err, want)
+	}
+	if store.cleared || store.cp == nil {
+		t.Error("checkpoint is cleared by a failed run")
+	}
+}
+
+func Test_withDeadlineMargin(t *testing.T) {
+	tests := []struct {
+		name     string
+		timeout  time.Duration
+		wantLeft time.Duration
+	}{
+		{name: "long", timeout: 15 * time.Minute, wantLeft: 15*time.Minute - deadlineMargin},
+		{name: "short", timeout: 10 * time.Second, wantLeft: 8 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+			deadline, _ := ctx.Deadline()
+			runCtx, runCancel := withDeadlineMargin(ctx)
+			defer runCancel()
+			got, ok := runCtx.Deadline()
+			if !ok {
+				t.Fatal("no deadline")
+			}
+			want := deadline.Add(tt.wantLeft - tt.timeout)
+			if d := got.Sub(want); d < -100*time.Millisecond || d > 100*time.Millisecond {
+				t.Errorf("deadline = %v, want %v", got, want)
+			}
+		})
+	}
+
+	runCtx, cancel := withDeadlineMargin(context.Background())
+	defer cancel()
+	if _, ok := runCtx.Deadline(); ok {
+		t.Error("deadline is set without the deadline of the invocation")
+	}
+}
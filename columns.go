@@ -0,0 +1,307 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/vvatanabe/aws-snapshot-bulk-delete/snapshot"
+)
+
+const (
+	propertyTags      = "Tags"
+	propertyTagPrefix = "tag:"
+)
+
+var defaultProperties = []string{
+	"Description",
+	"Encrypted",
+	"OwnerAlias",
+	"OwnerId",
+	"Progress",
+	"SnapshotId",
+	"StartTime",
+	"State",
+	"StorageTier",
+	"VolumeId",
+	"VolumeSize",
+	propertyTags,
+}
+
+type property struct {
+	name    string
+	value   func(s *ec2.Snapshot) string
+	compare func(a, b *ec2.Snapshot) int
+}
+
+// properties holds every field of ec2.Snapshot in alphabetical order.
+var properties = []property{
+	stringProperty("DataEncryptionKeyId", func(s *ec2.Snapshot) *string { return s.DataEncryptionKeyId }),
+	stringProperty("Description", func(s *ec2.Snapshot) *string { return s.Description }),
+	boolProperty("Encrypted", func(s *ec2.Snapshot) *bool { return s.Encrypted }),
+	stringProperty("KmsKeyId", func(s *ec2.Snapshot) *string { return s.KmsKeyId }),
+	stringProperty("OutpostArn", func(s *ec2.Snapshot) *string { return s.OutpostArn }),
+	stringProperty("OwnerAlias", func(s *ec2.Snapshot) *string { return s.OwnerAlias }),
+	stringProperty("OwnerId", func(s *ec2.Snapshot) *string { return s.OwnerId }),
+	stringProperty("Progress", func(s *ec2.Snapshot) *string { return s.Progress }),
+	timeProperty("RestoreExpiryTime", func(s *ec2.Snapshot) *time.Time { return s.RestoreExpiryTime }),
+	stringProperty("SnapshotId", func(s *ec2.Snapshot) *string { return s.SnapshotId }),
+	stringProperty("SseType", func(s *ec2.Snapshot) *string { return s.SseType }),
+	timeProperty("StartTime", func(s *ec2.Snapshot) *time.Time { return s.StartTime }),
+	stringProperty("State", func(s *ec2.Snapshot) *string { return s.State }),
+	stringProperty("StateMessage", func(s *ec2.Snapshot) *string { return s.StateMessage }),
+	stringProperty("StorageTier", func(s *ec2.Snapshot) *string { return s.StorageTier }),
+	{name: propertyTags},
+	stringProperty("VolumeId", func(s *ec2.Snapshot) *string { return s.VolumeId }),
+	int64Property("VolumeSize", func(s *ec2.Snapshot) *int64 { return s.VolumeSize }),
+}
+
+func stringProperty(name string, get func(s *ec2.Snapshot) *string) property {
+	return property{
+		name: name,
+		value: func(s *ec2.Snapshot) string {
+			return aws.StringValue(get(s))
+		},
+	}
+}
+
+func boolProperty(name string, get func(s *ec2.Snapshot) *bool) property {
+	return property{
+		name: name,
+		value: func(s *ec2.Snapshot) string {
+			return strconv.FormatBool(aws.BoolValue(get(s)))
+		},
+	}
+}
+
+func int64Property(name string, get func(s *ec2.Snapshot) *int64) property {
+	return property{
+		name: name,
+		value: func(s *ec2.Snapshot) string {
+			return strconv.FormatInt(aws.Int64Value(get(s)), 10)
+		},
+		compare: func(a, b *ec2.Snapshot) int {
+			x, y := aws.Int64Value(get(a)), aws.Int64Value(get(b))
+			switch {
+			case x < y:
+				return -1
+			case x > y:
+				return 1
+			}
+			return 0
+		},
+	}
+}
+
+func timeProperty(name string, get func(s *ec2.Snapshot) *time.Time) property {
+	return property{
+		name: name,
+		value: func(s *ec2.Snapshot) string {
+			t := get(s)
+			if t == nil {
+				return ""
+			}
+			return t.Format(time.RFC3339)
+		},
+		compare: func(a, b *ec2.Snapshot) int {
+			return aws.TimeValue(get(a)).Compare(aws.TimeValue(get(b)))
+		},
+	}
+}
+
+func propertyNames() []string {
+	names := make([]string, 0, len(properties))
+	for _, p := range properties {
+		names = append(names, p.name)
+	}
+	return names
+}
+
+func lookupProperty(name string) (property, error) {
+	if key, ok := strings.CutPrefix(name, propertyTagPrefix); ok {
+		if key == "" {
+			return property{}, fmt.Errorf("invalid property: %s", name)
+		}
+		return property{
+			name: name,
+			value: func(s *ec2.Snapshot) string {
+				for _, tag := range s.Tags {
+					if aws.StringValue(tag.Key) == key {
+						return aws.StringValue(tag.Value)
+					}
+				}
+				return ""
+			},
+		}, nil
+	}
+	for _, p := range properties {
+		if p.name == name {
+			return p, nil
+		}
+	}
+	return property{}, fmt.Errorf("unknown property: %s", name)
+}
+
+func initShowProperties(showProperties []string) ([]string, error) {
+	if len(showProperties) == 0 {
+		return defaultProperties, nil
+	}
+	names := make([]string, 0, len(showProperties))
+	for _, v := range showProperties {
+		name := strings.TrimSpace(v)
+		if _, err := lookupProperty(name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+func buildHeaderLine(showProperties []string) string {
+	var b strings.Builder
+	for _, name := range showProperties {
+		b.WriteString(name + "\t")
+	}
+	return b.String()
+}
+
+func buildTagsLine(tags []*ec2.Tag, showTagsSet map[string]struct{}) string {
+	tagsMap := make(map[string]string)
+	for _, tag := range tags {
+		key := aws.StringValue(tag.Key)
+		value := aws.StringValue(tag.Value)
+		if _, ok := showTagsSet[key]; ok || len(showTagsSet) == 0 {
+			tagsMap["\""+key+"\""] = "\"" + value + "\""
+		}
+	}
+	tagsLine := fmt.Sprintf("%v", tagsMap)
+	tagsLine = strings.TrimPrefix(tagsLine, "map[")
+	tagsLine = strings.TrimSuffix(tagsLine, "]")
+	return tagsLine
+}
+
+func buildPropertiesLine(snapshot *ec2.Snapshot, showProperties []string, showTagsSet map[string]struct{}) string {
+	var b strings.Builder
+	for _, name := range showProperties {
+		if name == propertyTags {
+			b.WriteString(buildTagsLine(snapshot.Tags, showTagsSet))
+		} else if p, err := lookupProperty(name); err == nil {
+			b.WriteString(p.value(snapshot))
+		}
+		b.WriteString("\t")
+	}
+	return b.String()
+}
+
+// extraColumn is a column which is not a property of ec2.Snapshot, such as
+// the Recycle Bin retention of a plan.
+type extraColumn struct {
+	name  string
+	value func(s *ec2.Snapshot) string
+}
+
+func planColumns(plan *snapshot.Plan) []extraColumn {
+	var extras []extraColumn
+	if plan.RecoverableUntil != nil {
+		extras = append(extras, extraColumn{
+			name: "RecoverableUntil",
+			value: func(s *ec2.Snapshot) string {
+				until, ok := plan.RecoverableUntil[aws.StringValue(s.SnapshotId)]
+				if !ok {
+					return "-"
+				}
+				return until.Format(time.RFC3339)
+			},
+		})
+	}
+	if plan.Unlocks != nil {
+		extras = append(extras, extraColumn{
+			name: "UnlockGovernance",
+			value: func(s *ec2.Snapshot) string {
+				lock, ok := plan.Unlocks[aws.StringValue(s.SnapshotId)]
+				if !ok || lock.LockExpiresOn == nil {
+					return "-"
+				}
+				return lock.LockExpiresOn.Format(time.RFC3339)
+			},
+		})
+	}
+	return extras
+}
+
+func copyColumns(copies map[string]string) []extraColumn {
+	if len(copies) == 0 {
+		return nil
+	}
+	return []extraColumn{{
+		name: "CopySnapshotId",
+		value: func(s *ec2.Snapshot) string {
+			id, ok := copies[aws.StringValue(s.SnapshotId)]
+			if !ok {
+				return "-"
+			}
+			return id
+		},
+	}}
+}
+
+type sortBy struct {
+	property property
+	desc     bool
+}
+
+func parseSortBy(s string) (*sortBy, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	name, desc := strings.CutPrefix(s, "-")
+	if name == propertyTags {
+		return nil, fmt.Errorf("can not sort by %s, use tag:<key> instead", propertyTags)
+	}
+	p, err := lookupProperty(name)
+	if err != nil {
+		return nil, err
+	}
+	return &sortBy{property: p, desc: desc}, nil
+}
+
+func (s *sortBy) less(a, b *ec2.Snapshot) bool {
+	var c int
+	if s.property.compare != nil {
+		c = s.property.compare(a, b)
+	} else {
+		c = strings.Compare(s.property.value(a), s.property.value(b))
+	}
+	if s.desc {
+		return c > 0
+	}
+	return c < 0
+}
+
+// sorted returns a sorted copy, so the deletion order is left as it is.
+func (s *sortBy) sorted(snapshots []*ec2.Snapshot) []*ec2.Snapshot {
+	if s == nil {
+		return snapshots
+	}
+	sorted := append([]*ec2.Snapshot(nil), snapshots...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return s.less(sorted[i], sorted[j])
+	})
+	return sorted
+}
+
+func (s *sortBy) sortedErrors(failed []*snapshot.ErrorWithSnapshot) []*snapshot.ErrorWithSnapshot {
+	if s == nil {
+		return failed
+	}
+	sorted := append([]*snapshot.ErrorWithSnapshot(nil), failed...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return s.less(sorted[i].Snapshot, sorted[j].Snapshot)
+	})
+	return sorted
+}
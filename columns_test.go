@@ -0,0 +1,173 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func Test_initShowProperties(t *testing.T) {
+	type args struct {
+		showProperties []string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "default",
+			args: args{
+				showProperties: []string{},
+			},
+			want:    defaultProperties,
+			wantErr: false,
+		},
+		{
+			name: "some properties",
+			args: args{
+				showProperties: []string{
+					"Description ",
+					" SnapshotId",
+					" KmsKeyId ",
+					"  Tags  ",
+				},
+			},
+			want:    []string{"Description", "SnapshotId", "KmsKeyId", "Tags"},
+			wantErr: false,
+		},
+		{
+			name: "tag column",
+			args: args{
+				showProperties: []string{"SnapshotId", "tag:Name"},
+			},
+			want:    []string{"SnapshotId", "tag:Name"},
+			wantErr: false,
+		},
+		{
+			name: "unknown property",
+			args: args{
+				showProperties: []string{"SnapshotId", "Foo"},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "tag column without key",
+			args: args{
+				showProperties: []string{"tag:"},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := initShowProperties(tt.args.showProperties)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("initShowProperties() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("initShowProperties() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_buildPropertiesLine(t *testing.T) {
+	s := &ec2.Snapshot{
+		SnapshotId: aws.String("snap-1"),
+		KmsKeyId:   aws.String("key-1"),
+		StartTime:  aws.Time(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)),
+		VolumeSize: aws.Int64(8),
+		Tags: []*ec2.Tag{
+			{Key: aws.String("Name"), Value: aws.String("foo")},
+		},
+	}
+	showProperties := []string{"SnapshotId", "KmsKeyId", "StartTime", "RestoreExpiryTime", "VolumeSize", "tag:Name", "Tags"}
+	want := "snap-1\tkey-1\t2023-01-01T00:00:00Z\t\t8\tfoo\t\"Name\":\"foo\"\t"
+	if got := buildPropertiesLine(s, showProperties, nil); got != want {
+		t.Errorf("buildPropertiesLine() = %q, want %q", got, want)
+	}
+}
+
+func Test_sortBy_sorted(t *testing.T) {
+	newSizedSnapshot := func(id string, size int64, name string) *ec2.Snapshot {
+		return &ec2.Snapshot{
+			SnapshotId: aws.String(id),
+			VolumeSize: aws.Int64(size),
+			Tags: []*ec2.Tag{
+				{Key: aws.String("Name"), Value: aws.String(name)},
+			},
+		}
+	}
+	snapshots := []*ec2.Snapshot{
+		newSizedSnapshot("snap-1", 8, "b"),
+		newSizedSnapshot("snap-2", 100, "c"),
+		newSizedSnapshot("snap-3", 20, "a"),
+	}
+	tests := []struct {
+		name    string
+		sortBy  string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:   "none",
+			sortBy: "",
+			want:   []string{"snap-1", "snap-2", "snap-3"},
+		},
+		{
+			name:   "numeric",
+			sortBy: "VolumeSize",
+			want:   []string{"snap-1", "snap-3", "snap-2"},
+		},
+		{
+			name:   "descending",
+			sortBy: "-VolumeSize",
+			want:   []string{"snap-2", "snap-3", "snap-1"},
+		},
+		{
+			name:   "tag",
+			sortBy: "tag:Name",
+			want:   []string{"snap-3", "snap-1", "snap-2"},
+		},
+		{
+			name:    "unknown",
+			sortBy:  "Foo",
+			wantErr: true,
+		},
+		{
+			name:    "tags",
+			sortBy:  "Tags",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := parseSortBy(tt.sortBy)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseSortBy() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+			var got []string
+			for _, v := range s.sorted(snapshots) {
+				got = append(got, aws.StringValue(v.SnapshotId))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sorted() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if aws.StringValue(snapshots[0].SnapshotId) != "snap-1" {
+		t.Errorf("sorted() modified the given snapshots")
+	}
+}
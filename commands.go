@@ -0,0 +1,366 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/urfave/cli/v2"
+	"github.com/vvatanabe/aws-snapshot-bulk-delete/snapshot"
+)
+
+func commands() []*cli.Command {
+	return []*cli.Command{
+		{
+			Name:      "list",
+			Usage:     "list snapshots matching the filters without deleting them",
+			UsageText: appName + " [global options] list [options]",
+			Flags:     concatFlags(selectorFlags(), displayFlags()),
+			Action:    listAction,
+		},
+		{
+			Name:      "plan",
+			Usage:     "show the snapshots which would be deleted",
+			UsageText: appName + " [global options] plan [options]",
+			Flags:     concatFlags(selectorFlags(), actionFlags(), protectionFlags(), copyFlags(), displayFlags(), savingsFlags(), explainFlags(), notifyFlags(), eventFlags(), metricsFlags()),
+			Action:    planAction,
+		},
+		{
+			Name:      "apply",
+			Usage:     "delete the snapshots after confirmation",
+			UsageText: appName + " [global options] apply [options]",
+			Flags: concatFlags(selectorFlags(), actionFlags(), protectionFlags(), copyFlags(), displayFlags(), savingsFlags(), resultFlags(), notifyFlags(), eventFlags(), metricsFlags(), []cli.Flag{
+				&cli.BoolFlag{
+					Name:  flagNameAutoApprove,
+					Usage: "skip the confirmation prompt",
+				},
+			}),
+			Action: applyAction,
+		},
+		{
+			Name:      "report",
+			Usage:     "show statistics of snapshots matching the filters without deleting them",
+			UsageText: appName + " [global options] report [options]",
+			Flags: concatFlags(selectorFlags(), savingsFlags(), []cli.Flag{
+				&cli.StringSliceFlag{
+					Name:  flagNameSummarizeBy,
+					Usage: "groups to aggregate by (groups: volume, tag:<key>, owner, region, tier, month)",
+					Value: cli.NewStringSlice(snapshot.GroupByTier, snapshot.GroupByMonth),
+				},
+			}),
+			Action: reportAction,
+		},
+		{
+			Name:      "restore",
+			Usage:     "list snapshots in the Recycle Bin, or restore them",
+			UsageText: appName + " [global options] restore [--snapshot-ids id | --from result.json [--tags Name=foo]] [options]",
+			Flags: []cli.Flag{
+				&cli.StringSliceFlag{
+					Name:  flagNameSnapshotIDs,
+					Usage: "snapshot ids to restore",
+				},
+				&cli.StringFlag{
+					Name:  flagNameFrom,
+					Usage: "restore the deleted snapshots of a previous result JSON file",
+				},
+				&cli.StringSliceFlag{
+					Name:  flagNameTags,
+					Usage: "restore only the snapshots of --from with the tags (eg. Name=foo OR Name=\"foo,bar,baz)\"",
+				},
+				&cli.BoolFlag{
+					Name:  flagNamePlan,
+					Usage: "don't make any changes; instead, show the snapshots which would be restored",
+				},
+				&cli.BoolFlag{
+					Name:  flagNameAutoApprove,
+					Usage: "skip the confirmation prompt",
+				},
+			},
+			Action: restoreAction,
+		},
+		{
+			Name:      "retry",
+			Usage:     "re-attempt the retryable failures of a previous result",
+			UsageText: appName + " [global options] retry --from result.json [options]",
+			Flags: concatFlags([]cli.Flag{
+				&cli.StringFlag{
+					Name:     flagNameFrom,
+					Usage:    "previous result JSON file",
+					Required: true,
+				},
+				&cli.BoolFlag{
+					Name:  flagNamePlan,
+					Usage: "don't make any changes; instead, show the snapshots which would be retried",
+				},
+				&cli.BoolFlag{
+					Name:  flagNameAutoApprove,
+					Usage: "skip the confirmation prompt",
+				},
+				&cli.StringFlag{
+					Name:  flagNameResultFile,
+					Usage: "write the result as JSON to the file (default: retry-<run id>.json next to --from)",
+				},
+			}, protectionFlags(), copyFlags(), displayFlags(), notifyFlags(), eventFlags(), metricsFlags()),
+			Action: retryAction,
+		},
+		{
+			Name:      "serve",
+			Aliases:   []string{"daemon"},
+			Usage:     "run the policies of a config on their cron schedules until interrupted",
+			UsageText: appName + " [global options] serve --config policies.json [options]",
+			Flags:     serveFlags(),
+			Action:    serveAction,
+		},
+		{
+			Name:      "api",
+			Usage:     "serve an HTTP API to create, approve and apply plans",
+			UsageText: appName + " [global options] api [--listen 127.0.0.1:8080] [--store-file plans.db]",
+			Flags:     apiFlags(),
+			Action:    apiAction,
+		},
+	}
+}
+
+func concatFlags(flags ...[]cli.Flag) []cli.Flag {
+	var result []cli.Flag
+	for _, v := range flags {
+		result = append(result, v...)
+	}
+	return result
+}
+
+func listAction(c *cli.Context) error {
+	cfg, err := parseSelectorConfig(c)
+	if err != nil {
+		return err
+	}
+	bulkDelete, err := snapshot.NewBulkDelete(cfg)
+	if err != nil {
+		return err
+	}
+	showProperties, err := initShowProperties(c.StringSlice(flagShowProperties))
+	if err != nil {
+		return err
+	}
+	sortBy, err := parseSortBy(c.String(flagNameSortBy))
+	if err != nil {
+		return err
+	}
+	summarizeBy, err := parseSummarizeBy(c.String(flagNameSummarizeBy))
+	if err != nil {
+		return err
+	}
+	snapshots, err := bulkDelete.Describe(context.Background())
+	if err != nil {
+		return err
+	}
+	if summarizeBy != nil {
+		writeSummary(os.Stdout, *summarizeBy, snapshot.Summarize(cfg.Region, *summarizeBy, snapshots, nil))
+	}
+	if !c.Bool(flagNameSummaryOnly) {
+		writeSnapshotTable(os.Stdout, sortBy.sorted(snapshots), showProperties, initShowTagsSet(c.StringSlice(flagShowTags)))
+	}
+	_, _ = fmt.Fprintf(os.Stdout, "Found: %d snapshots.\n\n", len(snapshots))
+	return nil
+}
+
+func planAction(c *cli.Context) error {
+	cfg, err := parseSelectorConfig(c)
+	if err != nil {
+		return err
+	}
+	cfg.Plan = true
+	if c.Bool(flagNameExplain) || c.IsSet(flagNameExplainID) {
+		return explainAction(c, cfg)
+	}
+	return runAction(c, cfg)
+}
+
+func explainAction(c *cli.Context, cfg *snapshot.BulkDeleteConfig) error {
+	bulkDelete, err := snapshot.NewBulkDelete(cfg)
+	if err != nil {
+		return err
+	}
+	if id := c.String(flagNameExplainID); id != "" {
+		explanation, err := bulkDelete.ExplainSnapshot(context.Background(), id)
+		if err != nil {
+			return err
+		}
+		writeExplainTrace(os.Stdout, explanation)
+		return nil
+	}
+	explanations, err := bulkDelete.Explain(context.Background())
+	if err != nil {
+		return err
+	}
+	writeExplainTable(os.Stdout, explanations)
+	return nil
+}
+
+func applyAction(c *cli.Context) error {
+	cfg, err := parseSelectorConfig(c)
+	if err != nil {
+		return err
+	}
+	cfg.Plan = false
+	return runAction(c, cfg)
+}
+
+func reportAction(c *cli.Context) error {
+	cfg, err := parseSelectorConfig(c)
+	if err != nil {
+		return err
+	}
+	bulkDelete, err := snapshot.NewBulkDelete(cfg)
+	if err != nil {
+		return err
+	}
+	var groupBys []snapshot.GroupBy
+	for _, v := range c.StringSlice(flagNameSummarizeBy) {
+		by, err := snapshot.ParseGroupBy(v)
+		if err != nil {
+			return err
+		}
+		groupBys = append(groupBys, by)
+	}
+	prices, err := readPrices(c.String(flagNamePriceFile))
+	if err != nil {
+		return err
+	}
+	snapshots, err := bulkDelete.Describe(context.Background())
+	if err != nil {
+		return err
+	}
+	var totalGiB int64
+	for _, v := range snapshots {
+		totalGiB += aws.Int64Value(v.VolumeSize)
+	}
+	_, _ = fmt.Fprintf(os.Stdout, "Snapshots: %d, total %d GiB.\n\n", len(snapshots), totalGiB)
+	for _, by := range groupBys {
+		writeSummary(os.Stdout, by, snapshot.Summarize(cfg.Region, by, snapshots, nil))
+	}
+	savings, err := snapshot.EstimateSavings(cfg.Region, snapshots, prices, c.String(flagNameSavingsTag))
+	writeSavings(os.Stdout, savings, err)
+	return nil
+}
+
+func retryAction(c *cli.Context) error {
+	from, err := readResultFile(c.String(flagNameFrom))
+	if err != nil {
+		return err
+	}
+	cfg, err := parseAWSConfig(c)
+	if err != nil {
+		return err
+	}
+	cfg.Logger, err = newLogger(c)
+	if err != nil {
+		return err
+	}
+	cfg.Action = from.Action
+	cfg.Shared = from.Shared
+	cfg.CopySnapshotIDs = from.CopySnapshotIDs()
+	bulkDelete, err := snapshot.NewApply(cfg)
+	if err != nil {
+		return err
+	}
+	snapshots := from.RetryableSnapshots()
+	if len(snapshots) == 0 {
+		_, _ = fmt.Fprintf(os.Stdout, "No retryable snapshots in run %s.\n", from.RunID)
+		return nil
+	}
+	runID := snapshot.NewRunID()
+	// The result of a retry is always written, so that it links back to
+	// the original run and its failures can be retried again.
+	resultFile := c.String(flagNameResultFile)
+	if resultFile == "" {
+		resultFile = retryResultFile(c.String(flagNameFrom), runID)
+		if err := c.Set(flagNameResultFile, resultFile); err != nil {
+			return err
+		}
+	}
+	opts, err := newOptions(c, cfg, runID, from.RunID)
+	if err != nil {
+		return err
+	}
+	err = runWithMetrics(c, cfg, opts, func(opts snapshot.Options) error {
+		return bulkDelete.ApplyWithOptions(context.Background(), snapshots, opts)
+	})
+	if err != nil {
+		return err
+	}
+	if !cfg.Plan {
+		_, _ = fmt.Fprintf(os.Stdout, "Result: %s\n\n", resultFile)
+	}
+	return nil
+}
+
+// retryResultFile returns the default result file of a retry, next to the
+// result which it retries. eg. retry-20230102T150405Z-1a2b3c4d.json
+func retryResultFile(from, runID string) string {
+	return filepath.Join(filepath.Dir(from), "retry-"+runID+".json")
+}
+
+func restoreAction(c *cli.Context) error {
+	cfg, err := parseAWSConfig(c)
+	if err != nil {
+		return err
+	}
+	restore, err := snapshot.NewRestore(cfg)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	sel := snapshot.RestoreSelector{
+		SnapshotIDs: c.StringSlice(flagNameSnapshotIDs),
+		Tags:        c.StringSlice(flagNameTags),
+	}
+	if name := c.String(flagNameFrom); name != "" {
+		sel.From, err = readResultFile(name)
+		if err != nil {
+			return err
+		}
+	}
+	if len(sel.SnapshotIDs) == 0 && sel.From == nil && len(sel.Tags) == 0 {
+		snapshots, err := restore.List(ctx)
+		if err != nil {
+			return err
+		}
+		writeRecycleBinTable(os.Stdout, snapshots)
+		_, _ = fmt.Fprintf(os.Stdout, "Recycle Bin: %d snapshots.\n\n", len(snapshots))
+		return nil
+	}
+	snapshots, missing, err := restore.Plan(ctx, sel)
+	if err != nil {
+		return err
+	}
+	writeRecycleBinTable(os.Stdout, snapshots)
+	for _, id := range missing {
+		_, _ = fmt.Fprintf(os.Stdout, "%s is not in the Recycle Bin.\n", id)
+	}
+	_, _ = fmt.Fprintf(os.Stdout, "Plan: %d to restore.\n\n", len(snapshots))
+	if c.Bool(flagNamePlan) || len(snapshots) == 0 {
+		return nil
+	}
+	if !c.Bool(flagNameAutoApprove) {
+		writeConfirmMessage(os.Stdout)
+		if err := runConfirmPrompt(); err != nil {
+			return err
+		}
+	}
+	var ids []string
+	for _, v := range snapshots {
+		ids = append(ids, aws.StringValue(v.SnapshotId))
+	}
+	successful, failed, err := restore.RestoreSnapshots(ctx, ids, nil)
+	if err != nil {
+		return err
+	}
+	for _, v := range failed {
+		_, _ = fmt.Fprintf(os.Stdout, "failed to restore %s: %s: %v\n", aws.StringValue(v.Snapshot.SnapshotId), v.Kind, v.Error)
+	}
+	_, _ = fmt.Fprintf(os.Stdout, "Result: %d to restored, %d to failed.\n\n", len(successful), len(failed))
+	return nil
+}
@@ -0,0 +1,373 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/robfig/cron/v3"
+	"github.com/urfave/cli/v2"
+	"github.com/vvatanabe/aws-snapshot-bulk-delete/snapshot"
+)
+
+const (
+	flagNameConfig     = "config"
+	flagNameHistory    = "history"
+	flagNameHistoryDir = "history-dir"
+)
+
+func serveFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:     flagNameConfig,
+			Usage:    "JSON policy config with the schedule and BulkDeleteConfig of each policy, reloaded on SIGHUP",
+			Required: true,
+		},
+		&cli.IntFlag{
+			Name:  flagNameHistory,
+			Usage: "number of run results to keep, written to stdout on SIGUSR1",
+			Value: 20,
+		},
+		&cli.StringFlag{
+			Name:  flagNameHistoryDir,
+			Usage: "directory to keep the run results in as JSON files, so that they survive a restart",
+		},
+	}
+}
+
+// runRecord is the outcome of a scheduled run of a policy.
+type runRecord struct {
+	Policy      string           `json:"policy"`
+	RunID       string           `json:"run_id"`
+	ScheduledAt time.Time        `json:"scheduled_at"`
+	StartedAt   time.Time        `json:"started_at"`
+	FinishedAt  time.Time        `json:"finished_at"`
+	Plan        bool             `json:"plan"`
+	Planned     int              `json:"planned"`
+	Excluded    int              `json:"excluded"`
+	Error       string           `json:"error,omitempty"`
+	Result      *snapshot.Result `json:"result,omitempty"`
+}
+
+// runHistory keeps the last records in memory, and in dir if it is set.
+type runHistory struct {
+	mu      sync.Mutex
+	size    int
+	dir     string
+	records []*runRecord
+}
+
+func newRunHistory(size int, dir string) (*runHistory, error) {
+	if size < 1 {
+		return nil, fmt.Errorf("invalid history: %d", size)
+	}
+	h := &runHistory{size: size, dir: dir}
+	if dir == "" {
+		return h, nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	names, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		b, err := os.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		var r runRecord
+		if err := json.Unmarshal(b, &r); err != nil {
+			return nil, fmt.Errorf("failed to read history %s: %w", name, err)
+		}
+		h.records = append(h.records, &r)
+	}
+	sort.Slice(h.records, func(i, j int) bool {
+		return h.records[i].StartedAt.Before(h.records[j].StartedAt)
+	})
+	if err := h.prune(); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+func (h *runHistory) fileName(r *runRecord) string {
+	// eg. dev-30d-20230102T150405Z-1a2b3c4d.json
+	return filepath.Join(h.dir, r.Policy+"-"+r.RunID+".json")
+}
+
+func (h *runHistory) add(r *runRecord) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records = append(h.records, r)
+	if h.dir != "" {
+		b, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(h.fileName(r), b, 0644); err != nil {
+			return err
+		}
+	}
+	return h.prune()
+}
+
+func (h *runHistory) prune() error {
+	if len(h.records) <= h.size {
+		return nil
+	}
+	old := h.records[:len(h.records)-h.size]
+	h.records = append([]*runRecord(nil), h.records[len(h.records)-h.size:]...)
+	if h.dir == "" {
+		return nil
+	}
+	for _, r := range old {
+		if err := os.Remove(h.fileName(r)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// list returns the records from the oldest.
+func (h *runHistory) list() []*runRecord {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]*runRecord(nil), h.records...)
+}
+
+func writeHistoryTable(w io.Writer, records []*runRecord) {
+	tw := tabwriter.NewWriter(w, 0, 1, 4, ' ', tabwriter.TabIndent)
+	_, _ = tw.Write([]byte("Policy\tRunId\tStartedAt\tDuration\tPlanned\tExcluded\tSuccessful\tFailed\tError\t\n"))
+	for _, r := range records {
+		successful, failed := "-", "-"
+		if r.Result != nil {
+			successful = fmt.Sprint(len(r.Result.Successful))
+			failed = fmt.Sprint(len(r.Result.Failed))
+		}
+		errMsg := r.Error
+		if errMsg == "" {
+			errMsg = "-"
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%d\t%s\t%s\t%s\t\n", r.Policy, r.RunID,
+			r.StartedAt.Format(time.RFC3339), r.FinishedAt.Sub(r.StartedAt).Round(time.Millisecond),
+			r.Planned, r.Excluded, successful, failed, errMsg)
+	}
+	_ = tw.Flush()
+	_, _ = fmt.Fprintf(w, "\n")
+}
+
+// daemon runs the policies on their schedules. Each policy has a lock, so
+// that a run which takes longer than the interval is not overlapped by the
+// next one, even across a reload.
+type daemon struct {
+	configFile string
+	history    *runHistory
+	logger     *slog.Logger
+	// runFunc runs a policy, and is replaced in tests.
+	runFunc func(ctx context.Context, p *policy, record *runRecord) error
+
+	// done is closed on stop, so that the runs waiting for the jitter are
+	// skipped instead of delaying the stop.
+	done chan struct{}
+
+	mu    sync.Mutex
+	cron  *cron.Cron
+	locks map[string]*sync.Mutex
+}
+
+func (d *daemon) lock(name string) *sync.Mutex {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.locks == nil {
+		d.locks = make(map[string]*sync.Mutex)
+	}
+	l, ok := d.locks[name]
+	if !ok {
+		l = &sync.Mutex{}
+		d.locks[name] = l
+	}
+	return l
+}
+
+// load reads the config and replaces the schedule. The current schedule is
+// kept if the config is invalid.
+func (d *daemon) load(ctx context.Context) error {
+	cfg, err := readPolicyConfig(d.configFile)
+	if err != nil {
+		return err
+	}
+	c := cron.New()
+	for _, v := range cfg.Policies {
+		p := v
+		c.Schedule(p.schedule, cron.FuncJob(func() {
+			d.runScheduled(ctx, p, time.Now())
+		}))
+	}
+	d.mu.Lock()
+	old := d.cron
+	d.cron = c
+	d.mu.Unlock()
+	if old != nil {
+		// Running jobs of the old schedule keep their locks until they finish.
+		old.Stop()
+	}
+	c.Start()
+	for _, v := range cfg.Policies {
+		d.logger.Info("policy scheduled", slog.String("policy", v.Name), slog.String("schedule", v.Schedule),
+			slog.Time("next", v.schedule.Next(time.Now())))
+	}
+	return nil
+}
+
+// stop stops the schedule and waits for the running jobs.
+func (d *daemon) stop() {
+	d.mu.Lock()
+	c := d.cron
+	d.mu.Unlock()
+	close(d.done)
+	if c != nil {
+		<-c.Stop().Done()
+	}
+}
+
+func (d *daemon) runScheduled(ctx context.Context, p *policy, scheduledAt time.Time) {
+	log := d.logger.With(slog.String("policy", p.Name))
+	l := d.lock(p.Name)
+	if !l.TryLock() {
+		log.Warn("run skipped, the previous run is still running")
+		return
+	}
+	defer l.Unlock()
+	if p.jitter > 0 {
+		delay := time.Duration(rand.Int63n(int64(p.jitter)))
+		log.Debug("waiting for jitter", slog.Duration("delay", delay))
+		select {
+		case <-time.After(delay):
+		case <-d.done:
+			return
+		}
+	}
+	record := &runRecord{
+		Policy:      p.Name,
+		RunID:       snapshot.NewRunID(),
+		ScheduledAt: scheduledAt,
+		StartedAt:   time.Now(),
+		Plan:        p.Config.Plan,
+	}
+	log = log.With(slog.String("run_id", record.RunID))
+	log.Info("run started")
+	err := d.runFunc(ctx, p, record)
+	record.FinishedAt = time.Now()
+	if err != nil {
+		record.Error = err.Error()
+		log.Error("run failed", slog.Any("error", err), slog.Duration("duration", record.FinishedAt.Sub(record.StartedAt)))
+	} else {
+		log.Info("run finished", slog.Int("planned", record.Planned), slog.Int("excluded", record.Excluded),
+			slog.Duration("duration", record.FinishedAt.Sub(record.StartedAt)))
+	}
+	if err := d.history.add(record); err != nil {
+		log.Warn("failed to keep the run result", slog.Any("error", err))
+	}
+}
+
+// runPolicy runs a policy without prompting, and records the plan and the
+// result.
+func (d *daemon) runPolicy(ctx context.Context, p *policy, record *runRecord) error {
+	cfg := *p.Config
+	cfg.Logger = d.logger.With(slog.String("policy", p.Name), slog.String("run_id", record.RunID))
+	bulkDelete, err := snapshot.NewBulkDelete(&cfg)
+	if err != nil {
+		return err
+	}
+	copies := make(map[string]string)
+	return bulkDelete.RunWithOptions(ctx, snapshot.Options{
+		AfterPlanFunc: func(plan *snapshot.Plan) error {
+			record.Planned = len(plan.Snapshots)
+			record.Excluded = len(plan.Excluded)
+			return nil
+		},
+		EachCopySnapshotFunc: func(snapshot *ec2.Snapshot, copySnapshotID string) error {
+			copies[aws.StringValue(snapshot.SnapshotId)] = copySnapshotID
+			return nil
+		},
+		AfterDeleteSnapshotsFunc: func(successful []*ec2.Snapshot, failed []*snapshot.ErrorWithSnapshot) error {
+			result := snapshot.NewResult(record.RunID, successful, failed)
+			result.Action = cfg.Action
+			result.Shared = cfg.Shared
+			result.SetCopySnapshotIDs(copies)
+			result.Region = cfg.Region
+			result.StartedAt = record.StartedAt
+			result.FinishedAt = time.Now()
+			record.Result = result
+			return nil
+		},
+	})
+}
+
+func serveAction(c *cli.Context) error {
+	logger, err := newLogger(c)
+	if err != nil {
+		return err
+	}
+	if logger == nil {
+		// A daemon without logs can not be inspected.
+		logger = slog.New(slog.NewTextHandler(c.App.ErrWriter, nil))
+	}
+	history, err := newRunHistory(c.Int(flagNameHistory), c.String(flagNameHistoryDir))
+	if err != nil {
+		return err
+	}
+	d := &daemon{
+		configFile: c.String(flagNameConfig),
+		history:    history,
+		logger:     logger,
+		done:       make(chan struct{}),
+	}
+	d.runFunc = d.runPolicy
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := d.load(ctx); err != nil {
+		return err
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM)
+	if historySignal != nil {
+		signal.Notify(signals, historySignal)
+	}
+	defer signal.Stop(signals)
+	for sig := range signals {
+		switch sig {
+		case syscall.SIGHUP:
+			if err := d.load(ctx); err != nil {
+				logger.Error("failed to reload, keeping the current policies", slog.Any("error", err))
+				continue
+			}
+			logger.Info("reloaded", slog.String("config", d.configFile))
+		case historySignal:
+			writeHistoryTable(os.Stdout, history.list())
+		default:
+			logger.Info("stopping, waiting for running policies", slog.String("signal", sig.String()))
+			// Runs are not cancelled, so that a deletion is not stopped
+			// half way.
+			d.stop()
+			return nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/vvatanabe/aws-snapshot-bulk-delete/snapshot"
+)
+
+func Test_runHistory(t *testing.T) {
+	dir := t.TempDir()
+	h, err := newRunHistory(2, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	startedAt := time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC)
+	for i, id := range []string{"run-1", "run-2", "run-3"} {
+		if err := h.add(&runRecord{Policy: "dev", RunID: id, StartedAt: startedAt.Add(time.Duration(i) * time.Hour)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runIDs := func(records []*runRecord) []string {
+		var ids []string
+		for _, v := range records {
+			ids = append(ids, v.RunID)
+		}
+		return ids
+	}
+	want := []string{"run-2", "run-3"}
+	if got := runIDs(h.list()); !reflect.DeepEqual(got, want) {
+		t.Errorf("list() = %v, want %v", got, want)
+	}
+	names, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(names) != 2 {
+		t.Errorf("history files = %v, want 2", names)
+	}
+
+	// The history is read back after a restart.
+	h, err = newRunHistory(1, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := runIDs(h.list()); !reflect.DeepEqual(got, []string{"run-3"}) {
+		t.Errorf("list() after restart = %v, want [run-3]", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "dev-run-2.json")); !os.IsNotExist(err) {
+		t.Errorf("pruned history file exists: %v", err)
+	}
+}
+
+func Test_newRunHistory_invalid(t *testing.T) {
+	if _, err := newRunHistory(0, ""); err == nil {
+		t.Error("newRunHistory() error = nil, want an error")
+	}
+}
+
+func Test_daemon_runScheduled(t *testing.T) {
+	history, _ := newRunHistory(10, "")
+	p := &policy{Name: "dev", Config: &snapshot.BulkDeleteConfig{Age: 30}}
+	started := make(chan struct{})
+	release := make(chan struct{})
+	d := &daemon{
+		history: history,
+		logger:  slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil)),
+		runFunc: func(ctx context.Context, p *policy, record *runRecord) error {
+			started <- struct{}{}
+			<-release
+			record.Planned = 3
+			return errors.New("access denied")
+		},
+	}
+	done := make(chan struct{})
+	go func() {
+		d.runScheduled(context.Background(), p, time.Now())
+		close(done)
+	}()
+	<-started
+	// The run overlapping the running one is skipped.
+	d.runScheduled(context.Background(), p, time.Now())
+	close(release)
+	<-done
+
+	records := history.list()
+	if len(records) != 1 {
+		t.Fatalf("history = %d records, want 1", len(records))
+	}
+	if r := records[0]; r.Policy != "dev" || r.Planned != 3 || r.Error != "access denied" || r.RunID == "" {
+		t.Errorf("record = %+v", r)
+	}
+}
+
+func Test_writeHistoryTable(t *testing.T) {
+	startedAt := time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC)
+	var buf bytes.Buffer
+	writeHistoryTable(&buf, []*runRecord{
+		{
+			Policy: "dev", RunID: "run-1", StartedAt: startedAt, FinishedAt: startedAt.Add(90 * time.Second), Planned: 2,
+			Result: &snapshot.Result{Successful: []*snapshot.ResultEntry{{}, {}}},
+		},
+		{Policy: "prod", RunID: "run-2", StartedAt: startedAt, FinishedAt: startedAt.Add(time.Second), Error: "access denied"},
+	})
+	want := "Policy    RunId    StartedAt               Duration    Planned    Excluded    Successful    Failed    Error            \n" +
+		"dev       run-1    2023-01-02T15:04:05Z    1m30s       2          0           2             0         -                \n" +
+		"prod      run-2    2023-01-02T15:04:05Z    1s          0          0           -             -         access denied    \n" +
+		"\n"
+	if got := buf.String(); got != want {
+		t.Errorf("writeHistoryTable() = %q, want %q", got, want)
+	}
+}
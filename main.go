@@ -1,8 +1,10 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"time"
 )
 
 const (
@@ -10,9 +12,22 @@ const (
 	exitCodeError int = iota
 )
 
+// tracingShutdownTimeout bounds the flush of spans on exit.
+const tracingShutdownTimeout = 5 * time.Second
+
 func main() {
 	app := app()
-	err := app.Run(os.Args)
+	shutdownTracing, err := setupTracing(context.Background())
+	if err != nil {
+		_, _ = fmt.Fprintf(app.ErrWriter, "Warning: failed to set up tracing: %v\n", err)
+		shutdownTracing = func(context.Context) error { return nil }
+	}
+	err = app.Run(os.Args)
+	ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+	if err := shutdownTracing(ctx); err != nil {
+		_, _ = fmt.Fprintf(app.ErrWriter, "Warning: failed to flush traces: %v\n", err)
+	}
+	cancel()
 	code := exitCodeOK
 	if err != nil {
 		if err.Error() != "" {
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/vvatanabe/aws-snapshot-bulk-delete/snapshot"
+)
+
+// policyNameRegexp restricts the policy names, which are a part of the file
+// names of the run history.
+var policyNameRegexp = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// policyConfig is the JSON file of the serve command.
+//
+//	{
+//	  "policies": [
+//	    {
+//	      "name": "dev-30d",
+//	      "schedule": "0 3 * * *",
+//	      "jitter": "10m",
+//	      "config": {"Region": "us-east-1", "Age": 30, "Tags": ["Env=dev"]}
+//	    }
+//	  ]
+//	}
+type policyConfig struct {
+	Policies []*policy `json:"policies"`
+}
+
+// policy runs BulkDeleteConfig on a cron schedule.
+type policy struct {
+	Name string `json:"name"`
+	// Schedule is a standard cron expression with five fields, or a
+	// descriptor such as @daily or @every 6h, in the local time zone unless
+	// prefixed with CRON_TZ=.
+	Schedule string `json:"schedule"`
+	// Jitter delays each run by a random duration up to it, so that the
+	// policies scheduled at the same time do not call the API at once.
+	Jitter string                     `json:"jitter,omitempty"`
+	Config *snapshot.BulkDeleteConfig `json:"config"`
+
+	schedule cron.Schedule
+	jitter   time.Duration
+}
+
+var errNoPolicies = errors.New("no policies")
+
+func readPolicyConfig(name string) (*policyConfig, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	cfg, err := parsePolicyConfig(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config %s: %w", name, err)
+	}
+	return cfg, nil
+}
+
+func parsePolicyConfig(r io.Reader) (*policyConfig, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	var cfg policyConfig
+	if err := dec.Decode(&cfg); err != nil {
+		return nil, err
+	}
+	if len(cfg.Policies) == 0 {
+		return nil, errNoPolicies
+	}
+	names := make(map[string]struct{})
+	for i, v := range cfg.Policies {
+		if v.Name == "" {
+			return nil, fmt.Errorf("policy %d has no name", i)
+		}
+		if !policyNameRegexp.MatchString(v.Name) {
+			return nil, fmt.Errorf("invalid policy name: %s: use only letters, digits, '.', '_' and '-'", v.Name)
+		}
+		if _, ok := names[v.Name]; ok {
+			return nil, fmt.Errorf("duplicate policy: %s", v.Name)
+		}
+		names[v.Name] = struct{}{}
+		if err := v.validate(); err != nil {
+			return nil, fmt.Errorf("invalid policy %s: %w", v.Name, err)
+		}
+	}
+	return &cfg, nil
+}
+
+func (p *policy) validate() error {
+	var err error
+	p.schedule, err = cron.ParseStandard(p.Schedule)
+	if err != nil {
+		return fmt.Errorf("invalid schedule: %w", err)
+	}
+	if p.Jitter != "" {
+		p.jitter, err = time.ParseDuration(p.Jitter)
+		if err != nil || p.jitter < 0 {
+			return fmt.Errorf("invalid jitter: %s", p.Jitter)
+		}
+	}
+	if p.Config == nil {
+		return errors.New("no config")
+	}
+	// NewBulkDelete validates the selectors without calling the API.
+	_, err = snapshot.NewBulkDelete(p.Config)
+	return err
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_parsePolicyConfig(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     string
+		wantJitter time.Duration
+		wantErr    bool
+	}{
+		{
+			name:       "valid",
+			config:     `{"policies": [{"name": "dev", "schedule": "0 3 * * *", "jitter": "10m", "config": {"Region": "us-east-1", "Age": 30, "Tags": ["Env=dev"]}}]}`,
+			wantJitter: 10 * time.Minute,
+		},
+		{
+			name:   "descriptor",
+			config: `{"policies": [{"name": "dev", "schedule": "@every 6h", "config": {"Age": 30}}]}`,
+		},
+		{
+			name:    "no policies",
+			config:  `{"policies": []}`,
+			wantErr: true,
+		},
+		{
+			name:    "unknown field",
+			config:  `{"policies": [{"name": "dev", "schedule": "@daily", "config": {"Age": 30, "Agee": 1}}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "no name",
+			config:  `{"policies": [{"schedule": "@daily", "config": {"Age": 30}}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "name with a path",
+			config:  `{"policies": [{"name": "../../etc/dev", "schedule": "@daily", "config": {"Age": 30}}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "name with a space",
+			config:  `{"policies": [{"name": "dev 30d", "schedule": "@daily", "config": {"Age": 30}}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "duplicate name",
+			config:  `{"policies": [{"name": "dev", "schedule": "@daily", "config": {"Age": 30}}, {"name": "dev", "schedule": "@daily", "config": {"Age": 7}}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid schedule",
+			config:  `{"policies": [{"name": "dev", "schedule": "every day", "config": {"Age": 30}}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid jitter",
+			config:  `{"policies": [{"name": "dev", "schedule": "@daily", "jitter": "-1m", "config": {"Age": 30}}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "no config",
+			config:  `{"policies": [{"name": "dev", "schedule": "@daily"}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "no age or tags",
+			config:  `{"policies": [{"name": "dev", "schedule": "@daily", "config": {"Region": "us-east-1"}}]}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePolicyConfig(strings.NewReader(tt.config))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parsePolicyConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.Policies[0].schedule == nil || got.Policies[0].jitter != tt.wantJitter {
+				t.Errorf("parsePolicyConfig() got = %+v", got.Policies[0])
+			}
+		})
+	}
+}
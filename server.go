@@ -0,0 +1,539 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/urfave/cli/v2"
+	"github.com/vvatanabe/aws-snapshot-bulk-delete/snapshot"
+)
+
+const (
+	flagNameListen    = "listen"
+	flagNameStoreFile = "store-file"
+)
+
+// serverShutdownTimeout bounds the wait for the open requests on exit, while
+// the running applies are waited for.
+const serverShutdownTimeout = 10 * time.Second
+
+// sseKeepAliveInterval keeps an idle stream open through proxies.
+const sseKeepAliveInterval = 15 * time.Second
+
+func apiFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:  flagNameListen,
+			Usage: "address to listen on, which has no authentication, so put it behind a proxy which has",
+			Value: "127.0.0.1:8080",
+		},
+		&cli.StringFlag{
+			Name:  flagNameStoreFile,
+			Usage: "BoltDB file to keep the plans in, instead of the memory",
+		},
+	}
+}
+
+var (
+	errPlanNotPlanned    = errors.New("plan is not waiting for approval")
+	errPlanCredentials   = errors.New("credentials are not accepted, the server uses its own")
+	errPlanServerOnly    = errors.New("Profile, CopyRoleARN and Verbose are not accepted, they are options of the server")
+	errStreamUnsupported = errors.New("streaming is not supported")
+	errApplyInterrupted  = errors.New("interrupted by restart")
+)
+
+// progressEvent is an event of the progress stream of a plan.
+type progressEvent struct {
+	Type       string     `json:"type"`
+	Status     planStatus `json:"status,omitempty"`
+	SnapshotID string     `json:"snapshot_id,omitempty"`
+	Planned    int        `json:"planned,omitempty"`
+	Excluded   int        `json:"excluded,omitempty"`
+	Successful int        `json:"successful,omitempty"`
+	Failed     int        `json:"failed,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+const (
+	// progressEventStatus is the first event of a stream.
+	progressEventStatus = "status"
+	// progressEventPlanned is sent when the plan is rebuilt before deleting,
+	// which excludes the snapshots locked since the plan was created.
+	progressEventPlanned  = "planned"
+	progressEventDeleted  = "deleted"
+	progressEventFinished = "finished"
+)
+
+// progressEventBuffer is the number of events a slow stream can fall behind
+// before its events are dropped. The finished event is never dropped.
+const progressEventBuffer = 256
+
+// progress fans out the events of the running applies to the streams.
+type progress struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan *progressEvent]struct{}
+}
+
+func newProgress() *progress {
+	return &progress{subscribers: make(map[string]map[chan *progressEvent]struct{})}
+}
+
+func (p *progress) subscribe(id string) (<-chan *progressEvent, func()) {
+	ch := make(chan *progressEvent, progressEventBuffer)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.subscribers[id] == nil {
+		p.subscribers[id] = make(map[chan *progressEvent]struct{})
+	}
+	p.subscribers[id][ch] = struct{}{}
+	return ch, func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		delete(p.subscribers[id], ch)
+	}
+}
+
+func (p *progress) publish(id string, e *progressEvent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for ch := range p.subscribers[id] {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// finish closes the streams of the plan, which then send the finished event
+// from the stored record.
+func (p *progress) finish(id string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for ch := range p.subscribers[id] {
+		close(ch)
+	}
+	delete(p.subscribers, id)
+}
+
+// server serves the HTTP API to create, approve and apply plans with the
+// same engine as the CLI.
+type server struct {
+	store    planStore
+	logger   *slog.Logger
+	progress *progress
+	// planFunc and applyFunc call the AWS API, and are replaced in tests.
+	planFunc  func(ctx context.Context, cfg *snapshot.BulkDeleteConfig) (*snapshot.Plan, error)
+	applyFunc func(ctx context.Context, cfg *snapshot.BulkDeleteConfig, snapshots []*ec2.Snapshot, opts snapshot.Options) error
+
+	// applies waits for the running applies on exit.
+	applies sync.WaitGroup
+	// done is closed on shutdown, which ends the streams of progress.
+	done     chan struct{}
+	doneOnce sync.Once
+}
+
+func newServer(store planStore, logger *slog.Logger) *server {
+	s := &server{
+		store:    store,
+		logger:   logger,
+		progress: newProgress(),
+		done:     make(chan struct{}),
+	}
+	s.planFunc = s.plan
+	s.applyFunc = s.apply
+	return s
+}
+
+func (s *server) shutdown() {
+	s.doneOnce.Do(func() { close(s.done) })
+}
+
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /plans", s.handleCreatePlan)
+	mux.HandleFunc("GET /plans/{id}", s.handleGetPlan)
+	mux.HandleFunc("POST /plans/{id}/apply", s.handleApplyPlan)
+	mux.HandleFunc("GET /plans/{id}/events", s.handlePlanEvents)
+	return mux
+}
+
+func (s *server) plan(ctx context.Context, cfg *snapshot.BulkDeleteConfig) (*snapshot.Plan, error) {
+	c := *cfg
+	c.Plan = true
+	c.Logger = s.logger
+	bulkDelete, err := snapshot.NewBulkDelete(&c)
+	if err != nil {
+		return nil, err
+	}
+	var plan *snapshot.Plan
+	err = bulkDelete.RunWithOptions(ctx, snapshot.Options{
+		AfterPlanFunc: func(p *snapshot.Plan) error {
+			plan = p
+			return nil
+		},
+	})
+	return plan, err
+}
+
+// apply deletes the snapshots of the plan as-is, rather than describing them
+// again, so that only the approved snapshots are deleted.
+func (s *server) apply(ctx context.Context, cfg *snapshot.BulkDeleteConfig, snapshots []*ec2.Snapshot, opts snapshot.Options) error {
+	c := *cfg
+	c.Plan = false
+	c.Logger = s.logger
+	bulkDelete, err := snapshot.NewApply(&c)
+	if err != nil {
+		return err
+	}
+	return bulkDelete.ApplyWithOptions(ctx, snapshots, opts)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeJSONError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func (s *server) writeStoreError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errPlanNotFound):
+		writeJSONError(w, http.StatusNotFound, err)
+	case errors.Is(err, errPlanNotPlanned):
+		writeJSONError(w, http.StatusConflict, err)
+	default:
+		s.logger.Error("failed to access the store", slog.Any("error", err))
+		writeJSONError(w, http.StatusInternalServerError, errors.New("internal error"))
+	}
+}
+
+// handleCreatePlan takes a BulkDeleteConfig, and describes the snapshots
+// without deleting them.
+func (s *server) handleCreatePlan(w http.ResponseWriter, r *http.Request) {
+	var cfg snapshot.BulkDeleteConfig
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Errorf("invalid config: %w", err))
+		return
+	}
+	// The config is stored and returned, so it must not hold secrets.
+	if cfg.AccessKeyID != "" || cfg.SecretAccessKey != "" || cfg.SessionToken != "" {
+		writeJSONError(w, http.StatusBadRequest, errPlanCredentials)
+		return
+	}
+	// A profile or a role would let a client act with other credentials of
+	// the server, and Verbose writes to its stdout.
+	if cfg.Profile != "" || cfg.CopyRoleARN != "" || cfg.Verbose {
+		writeJSONError(w, http.StatusBadRequest, errPlanServerOnly)
+		return
+	}
+	// NewBulkDelete validates the config without calling the API, so that
+	// an invalid config is told from a failure of the API.
+	if _, err := snapshot.NewBulkDelete(&cfg); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+	plan, err := s.planFunc(r.Context(), &cfg)
+	if err != nil {
+		s.logger.Error("failed to create a plan", slog.Any("error", err))
+		writeJSONError(w, http.StatusBadGateway, err)
+		return
+	}
+	rec := &planRecord{
+		ID:        snapshot.NewRunID(),
+		Status:    planStatusPlanned,
+		Config:    &cfg,
+		CreatedAt: time.Now(),
+		Action:    plan.Action,
+		Snapshots: plan.Snapshots,
+		Excluded:  plan.Excluded,
+		NotFound:  plan.NotFound,
+	}
+	if err := s.store.create(rec); err != nil {
+		s.writeStoreError(w, err)
+		return
+	}
+	s.logger.Info("plan created", slog.String("plan_id", rec.ID), slog.Int("planned", len(rec.Snapshots)),
+		slog.Int("excluded", len(rec.Excluded)))
+	w.Header().Set("Location", "/plans/"+rec.ID)
+	writeJSON(w, http.StatusCreated, rec)
+}
+
+func (s *server) handleGetPlan(w http.ResponseWriter, r *http.Request) {
+	rec, err := s.store.get(r.PathValue("id"))
+	if err != nil {
+		s.writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, rec)
+}
+
+// handleApplyPlan approves the plan and deletes its snapshots in the
+// background. The progress is streamed by handlePlanEvents.
+func (s *server) handleApplyPlan(w http.ResponseWriter, r *http.Request) {
+	var approval struct {
+		ApprovedBy string `json:"approved_by"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&approval); err != nil && !errors.Is(err, io.EOF) {
+		writeJSONError(w, http.StatusBadRequest, fmt.Errorf("invalid approval: %w", err))
+		return
+	}
+	rec, err := s.store.update(r.PathValue("id"), func(rec *planRecord) error {
+		if rec.Status != planStatusPlanned {
+			return errPlanNotPlanned
+		}
+		now := time.Now()
+		rec.Status = planStatusApplying
+		rec.ApprovedBy = approval.ApprovedBy
+		rec.ApprovedAt = &now
+		return nil
+	})
+	if err != nil {
+		s.writeStoreError(w, err)
+		return
+	}
+	s.logger.Info("plan approved", slog.String("plan_id", rec.ID), slog.String("approved_by", rec.ApprovedBy))
+	s.applies.Add(1)
+	go func() {
+		defer s.applies.Done()
+		s.applyPlan(rec)
+	}()
+	writeJSON(w, http.StatusAccepted, rec)
+}
+
+// applyPlan is not bound to the request, so that a deletion is not stopped
+// half way when the client goes away.
+func (s *server) applyPlan(rec *planRecord) {
+	id := rec.ID
+	result := snapshot.NewResult(id, nil, nil)
+	result.StartedAt = time.Now()
+	copies := make(map[string]string)
+	err := s.applyFunc(context.Background(), rec.Config, rec.Snapshots, snapshot.Options{
+		AfterPlanFunc: func(plan *snapshot.Plan) error {
+			s.progress.publish(id, &progressEvent{
+				Type:     progressEventPlanned,
+				Planned:  len(plan.Snapshots),
+				Excluded: len(plan.Excluded),
+			})
+			return nil
+		},
+		EachDeleteSnapshotsFunc: func(snapshot *ec2.Snapshot) error {
+			s.progress.publish(id, &progressEvent{
+				Type:       progressEventDeleted,
+				SnapshotID: aws.StringValue(snapshot.SnapshotId),
+			})
+			return nil
+		},
+		EachCopySnapshotFunc: func(snapshot *ec2.Snapshot, copySnapshotID string) error {
+			copies[aws.StringValue(snapshot.SnapshotId)] = copySnapshotID
+			return nil
+		},
+		AfterDeleteSnapshotsFunc: func(successful []*ec2.Snapshot, failed []*snapshot.ErrorWithSnapshot) error {
+			startedAt := result.StartedAt
+			result = snapshot.NewResult(id, successful, failed)
+			result.StartedAt = startedAt
+			return nil
+		},
+	})
+	result.Action = rec.Config.Action
+	result.Shared = rec.Config.Shared
+	result.Region = rec.Config.Region
+	result.FinishedAt = time.Now()
+	result.SetCopySnapshotIDs(copies)
+	_, uerr := s.store.update(id, func(rec *planRecord) error {
+		rec.Result = result
+		rec.Status = planStatusApplied
+		if err != nil {
+			rec.Status = planStatusFailed
+			rec.Error = err.Error()
+		}
+		return nil
+	})
+	if uerr != nil {
+		s.logger.Error("failed to store the result", slog.String("plan_id", id), slog.Any("error", uerr))
+	}
+	if err != nil {
+		s.logger.Error("plan failed", slog.String("plan_id", id), slog.Any("error", err))
+	} else {
+		s.logger.Info("plan applied", slog.String("plan_id", id), slog.Int("successful", len(result.Successful)),
+			slog.Int("failed", len(result.Failed)))
+	}
+	s.progress.finish(id)
+}
+
+// failInterrupted marks the plans which were applying when the server
+// stopped as failed, because nothing resumes them. It returns their ids.
+func failInterrupted(store planStore) ([]string, error) {
+	recs, err := store.list()
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, v := range recs {
+		if v.Status != planStatusApplying {
+			continue
+		}
+		_, err := store.update(v.ID, func(rec *planRecord) error {
+			rec.Status = planStatusFailed
+			rec.Error = errApplyInterrupted.Error()
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, v.ID)
+	}
+	return ids, nil
+}
+
+func finishedEvent(rec *planRecord) *progressEvent {
+	e := &progressEvent{Type: progressEventFinished, Status: rec.Status, Error: rec.Error}
+	if rec.Result != nil {
+		e.Successful = len(rec.Result.Successful)
+		e.Failed = len(rec.Result.Failed)
+	}
+	return e
+}
+
+func writeSSE(w io.Writer, e *progressEvent) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, b)
+	return err
+}
+
+// handlePlanEvents streams the progress of the plan as server-sent events,
+// starting with its status and ending with the finished event.
+func (s *server) handlePlanEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSONError(w, http.StatusInternalServerError, errStreamUnsupported)
+		return
+	}
+	id := r.PathValue("id")
+	// Subscribe before reading the record, so that no event is missed in
+	// between.
+	events, unsubscribe := s.progress.subscribe(id)
+	defer unsubscribe()
+	rec, err := s.store.get(id)
+	if err != nil {
+		s.writeStoreError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	send := func(e *progressEvent) bool {
+		if err := writeSSE(w, e); err != nil {
+			return false
+		}
+		flusher.Flush()
+		return true
+	}
+	if !send(&progressEvent{Type: progressEventStatus, Status: rec.Status}) {
+		return
+	}
+	if rec.Status.finished() {
+		send(finishedEvent(rec))
+		return
+	}
+	keepAlive := time.NewTicker(sseKeepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.done:
+			return
+		case <-keepAlive.C:
+			if _, err := io.WriteString(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case e, ok := <-events:
+			if !ok {
+				rec, err := s.store.get(id)
+				if err != nil {
+					return
+				}
+				send(finishedEvent(rec))
+				return
+			}
+			if !send(e) {
+				return
+			}
+		}
+	}
+}
+
+func apiAction(c *cli.Context) error {
+	logger, err := newLogger(c)
+	if err != nil {
+		return err
+	}
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(c.App.ErrWriter, nil))
+	}
+	var store planStore = newMemoryStore()
+	if name := c.String(flagNameStoreFile); name != "" {
+		store, err = newBoltStore(name)
+		if err != nil {
+			return fmt.Errorf("failed to open store %s: %w", name, err)
+		}
+	}
+	defer store.close()
+	ids, err := failInterrupted(store)
+	if err != nil {
+		return fmt.Errorf("failed to read store: %w", err)
+	}
+	for _, id := range ids {
+		logger.Warn("plan failed, its apply was interrupted by restart", slog.String("plan_id", id))
+	}
+	s := newServer(store, logger)
+	srv := &http.Server{
+		Addr:              c.String(flagNameListen),
+		Handler:           s.handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	srv.RegisterOnShutdown(s.shutdown)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- srv.ListenAndServe()
+	}()
+	logger.Info("listening", slog.String("addr", srv.Addr))
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	select {
+	case err := <-errs:
+		return err
+	case sig := <-signals:
+		logger.Info("stopping, waiting for running applies", slog.String("signal", sig.String()))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), serverShutdownTimeout)
+	defer cancel()
+	err = srv.Shutdown(ctx)
+	s.applies.Wait()
+	if errors.Is(err, context.DeadlineExceeded) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/vvatanabe/aws-snapshot-bulk-delete/snapshot"
+)
+
+func newTestServer(t *testing.T, applyErr error) (*server, *httptest.Server, chan struct{}) {
+	s := newServer(newMemoryStore(), slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil)))
+	s.planFunc = func(ctx context.Context, cfg *snapshot.BulkDeleteConfig) (*snapshot.Plan, error) {
+		return &snapshot.Plan{
+			Action:    snapshot.ActionDelete,
+			Snapshots: []*ec2.Snapshot{{SnapshotId: aws.String("snap-1")}, {SnapshotId: aws.String("snap-2")}},
+			Excluded:  []*snapshot.ExcludedSnapshot{{Snapshot: &ec2.Snapshot{SnapshotId: aws.String("snap-3")}, Reason: snapshot.ExclusionReasonLocked}},
+		}, nil
+	}
+	release := make(chan struct{})
+	s.applyFunc = func(ctx context.Context, cfg *snapshot.BulkDeleteConfig, snapshots []*ec2.Snapshot, opts snapshot.Options) error {
+		<-release
+		_ = opts.AfterPlanFunc(&snapshot.Plan{Snapshots: snapshots})
+		for _, v := range snapshots[:1] {
+			_ = opts.EachDeleteSnapshotsFunc(v)
+		}
+		_ = opts.AfterDeleteSnapshotsFunc(snapshots[:1], []*snapshot.ErrorWithSnapshot{
+			{Snapshot: snapshots[1], Error: errors.New("in use"), Kind: snapshot.FailureKindInUse},
+		})
+		return applyErr
+	}
+	srv := httptest.NewServer(s.handler())
+	t.Cleanup(srv.Close)
+	return s, srv, release
+}
+
+func doJSON(t *testing.T, method, url, body string, wantStatus int, v interface{}) {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != wantStatus {
+		t.Fatalf("%s %s status = %d, want %d", method, url, resp.StatusCode, wantStatus)
+	}
+	if v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func Test_server(t *testing.T) {
+	s, srv, release := newTestServer(t, nil)
+
+	var created planRecord
+	doJSON(t, http.MethodPost, srv.URL+"/plans", `{"Region": "us-east-1", "Age": 30}`, http.StatusCreated, &created)
+	if created.ID == "" || created.Status != planStatusPlanned || len(created.Snapshots) != 2 || len(created.Excluded) != 1 {
+		t.Fatalf("POST /plans = %+v", created)
+	}
+
+	var got planRecord
+	doJSON(t, http.MethodGet, srv.URL+"/plans/"+created.ID, "", http.StatusOK, &got)
+	if got.ID != created.ID || got.Config.Age != 30 {
+		t.Errorf("GET /plans/{id} = %+v", got)
+	}
+
+	resp, err := http.Get(srv.URL + "/plans/" + created.ID + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %v", ct)
+	}
+	events := bufio.NewScanner(resp.Body)
+	next := func() string {
+		for events.Scan() {
+			if line := events.Text(); strings.HasPrefix(line, "data: ") {
+				return strings.TrimPrefix(line, "data: ")
+			}
+		}
+		return ""
+	}
+	if e := next(); e != `{"type":"status","status":"planned"}` {
+		t.Errorf("event = %v", e)
+	}
+
+	var applying planRecord
+	doJSON(t, http.MethodPost, srv.URL+"/plans/"+created.ID+"/apply", `{"approved_by": "alice"}`, http.StatusAccepted, &applying)
+	if applying.Status != planStatusApplying || applying.ApprovedBy != "alice" || applying.ApprovedAt == nil {
+		t.Errorf("POST /plans/{id}/apply = %+v", applying)
+	}
+	// A plan is applied only once.
+	doJSON(t, http.MethodPost, srv.URL+"/plans/"+created.ID+"/apply", "", http.StatusConflict, nil)
+	close(release)
+
+	var gotEvents []string
+	for e := next(); e != ""; e = next() {
+		gotEvents = append(gotEvents, e)
+	}
+	wantEvents := []string{
+		`{"type":"planned","planned":2}`,
+		`{"type":"deleted","snapshot_id":"snap-1"}`,
+		`{"type":"finished","status":"applied","successful":1,"failed":1}`,
+	}
+	if !reflect.DeepEqual(gotEvents, wantEvents) {
+		t.Errorf("events = %v, want %v", gotEvents, wantEvents)
+	}
+	s.applies.Wait()
+
+	doJSON(t, http.MethodGet, srv.URL+"/plans/"+created.ID, "", http.StatusOK, &got)
+	if got.Status != planStatusApplied || got.Result == nil || len(got.Result.Successful) != 1 || got.Result.Failed[0].Kind != snapshot.FailureKindInUse {
+		t.Errorf("GET /plans/{id} after apply = %+v", got)
+	}
+	// The stream of a finished plan ends at once.
+	resp, err = http.Get(srv.URL + "/plans/" + created.ID + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	events = bufio.NewScanner(resp.Body)
+	gotEvents = nil
+	for e := next(); e != ""; e = next() {
+		gotEvents = append(gotEvents, e)
+	}
+	wantEvents = []string{
+		`{"type":"status","status":"applied"}`,
+		`{"type":"finished","status":"applied","successful":1,"failed":1}`,
+	}
+	if !reflect.DeepEqual(gotEvents, wantEvents) {
+		t.Errorf("events = %v, want %v", gotEvents, wantEvents)
+	}
+}
+
+func Test_server_applyFailed(t *testing.T) {
+	s, srv, release := newTestServer(t, errors.New("access denied"))
+	close(release)
+	var created planRecord
+	doJSON(t, http.MethodPost, srv.URL+"/plans", `{"Age": 30}`, http.StatusCreated, &created)
+	doJSON(t, http.MethodPost, srv.URL+"/plans/"+created.ID+"/apply", "", http.StatusAccepted, nil)
+	s.applies.Wait()
+	var got planRecord
+	doJSON(t, http.MethodGet, srv.URL+"/plans/"+created.ID, "", http.StatusOK, &got)
+	if got.Status != planStatusFailed || got.Error != "access denied" {
+		t.Errorf("GET /plans/{id} = %+v", got)
+	}
+}
+
+func Test_failInterrupted(t *testing.T) {
+	store := newMemoryStore()
+	for id, status := range map[string]planStatus{
+		"plan-planned":  planStatusPlanned,
+		"plan-applying": planStatusApplying,
+		"plan-applied":  planStatusApplied,
+	} {
+		if err := store.create(&planRecord{ID: id, Status: status}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ids, err := failInterrupted(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ids, []string{"plan-applying"}) {
+		t.Errorf("failInterrupted() = %v", ids)
+	}
+	want := map[string]planStatus{
+		"plan-planned":  planStatusPlanned,
+		"plan-applying": planStatusFailed,
+		"plan-applied":  planStatusApplied,
+	}
+	for id, status := range want {
+		rec, err := store.get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rec.Status != status {
+			t.Errorf("%s status = %v, want %v", id, rec.Status, status)
+		}
+	}
+	// The stream of an interrupted plan finishes from the stored record.
+	if rec, _ := store.get("plan-applying"); rec.Error != errApplyInterrupted.Error() ||
+		!reflect.DeepEqual(finishedEvent(rec), &progressEvent{Type: progressEventFinished, Status: planStatusFailed, Error: "interrupted by restart"}) {
+		t.Errorf("interrupted record = %+v", rec)
+	}
+}
+
+func Test_server_errors(t *testing.T) {
+	_, srv, _ := newTestServer(t, nil)
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		{name: "invalid json", method: http.MethodPost, path: "/plans", body: `{`, wantStatus: http.StatusBadRequest},
+		{name: "unknown field", method: http.MethodPost, path: "/plans", body: `{"Agee": 30}`, wantStatus: http.StatusBadRequest},
+		{name: "credentials", method: http.MethodPost, path: "/plans", body: `{"Age": 30, "SecretAccessKey": "secret"}`, wantStatus: http.StatusBadRequest},
+		{name: "profile", method: http.MethodPost, path: "/plans", body: `{"Age": 30, "Profile": "admin"}`, wantStatus: http.StatusBadRequest},
+		{name: "copy role", method: http.MethodPost, path: "/plans", body: `{"Age": 30, "CopyToAccount": "123456789012", "CopyRoleARN": "arn:aws:iam::123456789012:role/copy"}`, wantStatus: http.StatusBadRequest},
+		{name: "verbose", method: http.MethodPost, path: "/plans", body: `{"Age": 30, "Verbose": true}`, wantStatus: http.StatusBadRequest},
+		{name: "no age or tags", method: http.MethodPost, path: "/plans", body: `{"Region": "us-east-1"}`, wantStatus: http.StatusBadRequest},
+		{name: "plan not found", method: http.MethodGet, path: "/plans/plan-1", wantStatus: http.StatusNotFound},
+		{name: "apply not found", method: http.MethodPost, path: "/plans/plan-1/apply", wantStatus: http.StatusNotFound},
+		{name: "events not found", method: http.MethodGet, path: "/plans/plan-1/events", wantStatus: http.StatusNotFound},
+		{name: "method not allowed", method: http.MethodDelete, path: "/plans/plan-1", wantStatus: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doJSON(t, tt.method, srv.URL+tt.path, tt.body, tt.wantStatus, nil)
+		})
+	}
+}
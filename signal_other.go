@@ -0,0 +1,11 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// historySignal makes serve write the run history to stdout.
+var historySignal os.Signal = syscall.SIGUSR1
@@ -0,0 +1,6 @@
+package main
+
+import "os"
+
+// historySignal is nil, because Windows has no SIGUSR1.
+var historySignal os.Signal
@@ -0,0 +1,115 @@
+package snapshot
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+var (
+	errAgeWithOlderThan   = errors.New("age and older-than can not be specified together")
+	errCreatedAfterBefore = errors.New("created-after must be before created-before")
+)
+
+type timeRange struct {
+	olderThan     time.Duration
+	createdBefore time.Time
+	createdAfter  time.Time
+}
+
+func (cfg *BulkDeleteConfig) timeRange() (*timeRange, error) {
+	var (
+		r   timeRange
+		err error
+	)
+	if cfg.OlderThan != "" {
+		if cfg.Age > 0 {
+			return nil, errAgeWithOlderThan
+		}
+		r.olderThan, err = parseOlderThan(cfg.OlderThan)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cfg.CreatedBefore != "" {
+		r.createdBefore, err = parseCreatedTime(cfg.CreatedBefore)
+		if err != nil {
+			return nil, fmt.Errorf("invalid created-before: %w", err)
+		}
+	}
+	if cfg.CreatedAfter != "" {
+		r.createdAfter, err = parseCreatedTime(cfg.CreatedAfter)
+		if err != nil {
+			return nil, fmt.Errorf("invalid created-after: %w", err)
+		}
+	}
+	if !r.createdBefore.IsZero() && !r.createdAfter.IsZero() && !r.createdAfter.Before(r.createdBefore) {
+		return nil, errCreatedAfterBefore
+	}
+	return &r, nil
+}
+
+// parseOlderThan accepts the units of time.ParseDuration, and d for days and
+// w for weeks (eg. 36h, 2w).
+func parseOlderThan(s string) (time.Duration, error) {
+	var (
+		d   time.Duration
+		err error
+	)
+	switch {
+	case strings.HasSuffix(s, "d"), strings.HasSuffix(s, "w"):
+		unit := 24 * time.Hour
+		if strings.HasSuffix(s, "w") {
+			unit *= 7
+		}
+		var n int64
+		n, err = strconv.ParseInt(s[:len(s)-1], 10, 64)
+		d = time.Duration(n) * unit
+	default:
+		d, err = time.ParseDuration(s)
+	}
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid older-than: %s", s)
+	}
+	return d, nil
+}
+
+// parseCreatedTime accepts RFC 3339 (eg. 2026-01-01T00:00:00Z) or a date in UTC (eg. 2026-01-01).
+func parseCreatedTime(s string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+func (r *timeRange) filterFuncs(now time.Time) []filterFunc {
+	var fns []filterFunc
+	for _, v := range r.selectors(now) {
+		fns = append(fns, v.filterFunc())
+	}
+	return fns
+}
+
+func (r *timeRange) selectors(now time.Time) []*selector {
+	var selectors []*selector
+	if r.olderThan > 0 {
+		expireDate := now.Add(-r.olderThan)
+		selectors = append(selectors, newSelector("older-than",
+			fmt.Sprintf("created before %s (%s)", expireDate.Format(time.RFC3339), r.olderThan), expiredFilterFunc(expireDate)))
+	}
+	if !r.createdBefore.IsZero() {
+		selectors = append(selectors, newSelector("created-before",
+			"created before "+r.createdBefore.Format(time.RFC3339), expiredFilterFunc(r.createdBefore)))
+	}
+	if !r.createdAfter.IsZero() {
+		selectors = append(selectors, newSelector("created-after",
+			"created at or after "+r.createdAfter.Format(time.RFC3339), func(snapshot *ec2.Snapshot) bool {
+				return !snapshot.StartTime.Before(r.createdAfter)
+			}))
+	}
+	return selectors
+}
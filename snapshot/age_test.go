@@ -0,0 +1,140 @@
+package snapshot
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func Test_parseOlderThan(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    time.Duration
+		wantErr bool
+	}{
+		{name: "hours", s: "36h", want: 36 * time.Hour},
+		{name: "minutes", s: "90m", want: 90 * time.Minute},
+		{name: "days", s: "3d", want: 3 * 24 * time.Hour},
+		{name: "weeks", s: "2w", want: 2 * 7 * 24 * time.Hour},
+		{name: "zero", s: "0h", wantErr: true},
+		{name: "negative", s: "-1d", wantErr: true},
+		{name: "no unit", s: "36", wantErr: true},
+		{name: "invalid days", s: "xd", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseOlderThan(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseOlderThan() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("parseOlderThan() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfig_timeRange(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     *BulkDeleteConfig
+		want    *timeRange
+		wantErr bool
+	}{
+		{
+			name: "empty",
+			cfg:  &BulkDeleteConfig{},
+			want: &timeRange{},
+		},
+		{
+			name: "all",
+			cfg: &BulkDeleteConfig{
+				OlderThan:     "2w",
+				CreatedBefore: "2026-01-01T00:00:00Z",
+				CreatedAfter:  "2025-01-01",
+			},
+			want: &timeRange{
+				olderThan:     14 * 24 * time.Hour,
+				createdBefore: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+				createdAfter:  time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:    "age with older-than",
+			cfg:     &BulkDeleteConfig{Age: 10, OlderThan: "36h"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid created-before",
+			cfg:     &BulkDeleteConfig{CreatedBefore: "yesterday"},
+			wantErr: true,
+		},
+		{
+			name:    "created-after is not before created-before",
+			cfg:     &BulkDeleteConfig{CreatedBefore: "2025-01-01", CreatedAfter: "2025-01-01"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.cfg.timeRange()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("timeRange() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("timeRange() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_timeRange_filterFuncs(t *testing.T) {
+	now := time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)
+	snapshots := []*ec2.Snapshot{
+		newSnapshot("snap-old", time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC), nil),
+		newSnapshot("snap-window", time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC), nil),
+		newSnapshot("snap-recent", time.Date(2025, 1, 9, 12, 0, 0, 0, time.UTC), nil),
+	}
+	tests := []struct {
+		name string
+		r    *timeRange
+		want []string
+	}{
+		{
+			name: "older-than",
+			r:    &timeRange{olderThan: 36 * time.Hour},
+			want: []string{"snap-old", "snap-window"},
+		},
+		{
+			name: "created window",
+			r: &timeRange{
+				createdBefore: time.Date(2025, 1, 9, 0, 0, 0, 0, time.UTC),
+				createdAfter:  time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+			},
+			want: []string{"snap-window"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, s := range snapshots {
+				match := true
+				for _, fn := range tt.r.filterFuncs(now) {
+					match = match && fn(s)
+				}
+				if match {
+					got = append(got, aws.StringValue(s.SnapshotId))
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("filterFuncs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -7,11 +7,17 @@ import (
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/backup"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+	"github.com/aws/aws-sdk-go/service/recyclebin"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sts"
 )
 
 type ErrorWithSnapshot struct {
 	Error    error
+	Kind     FailureKind
 	Snapshot *ec2.Snapshot
 }
 
@@ -43,11 +49,19 @@ func (c *awsConfig) rawAWSConfig() *aws.Config {
 	return awsCfg
 }
 
-func newEC2SnapshotAPI(cfg *awsConfig) (EC2SnapshotAPI, error) {
+func newAWSSession(cfg *awsConfig) (*session.Session, error) {
 	sess, err := session.NewSessionWithOptions(newAWSSessionOptions(cfg))
 	if err != nil {
 		return nil, fmt.Errorf("failed to new aws sesion: %w", err)
 	}
+	return sess, nil
+}
+
+func newEC2SnapshotAPI(cfg *awsConfig) (EC2SnapshotAPI, error) {
+	sess, err := newAWSSession(cfg)
+	if err != nil {
+		return nil, err
+	}
 	return ec2.New(sess), nil
 }
 
@@ -62,4 +76,39 @@ func newAWSSessionOptions(cfg *awsConfig) session.Options {
 type EC2SnapshotAPI interface {
 	DescribeSnapshotsPagesWithContext(ctx aws.Context, input *ec2.DescribeSnapshotsInput, fn func(*ec2.DescribeSnapshotsOutput, bool) bool, opts ...request.Option) error
 	DeleteSnapshotWithContext(ctx aws.Context, input *ec2.DeleteSnapshotInput, opts ...request.Option) (*ec2.DeleteSnapshotOutput, error)
+	ListSnapshotsInRecycleBinPagesWithContext(ctx aws.Context, input *ec2.ListSnapshotsInRecycleBinInput, fn func(*ec2.ListSnapshotsInRecycleBinOutput, bool) bool, opts ...request.Option) error
+	RestoreSnapshotFromRecycleBinWithContext(ctx aws.Context, input *ec2.RestoreSnapshotFromRecycleBinInput, opts ...request.Option) (*ec2.RestoreSnapshotFromRecycleBinOutput, error)
+	DescribeLockedSnapshotsWithContext(ctx aws.Context, input *ec2.DescribeLockedSnapshotsInput, opts ...request.Option) (*ec2.DescribeLockedSnapshotsOutput, error)
+	UnlockSnapshotWithContext(ctx aws.Context, input *ec2.UnlockSnapshotInput, opts ...request.Option) (*ec2.UnlockSnapshotOutput, error)
+	DescribeLaunchTemplatesPagesWithContext(ctx aws.Context, input *ec2.DescribeLaunchTemplatesInput, fn func(*ec2.DescribeLaunchTemplatesOutput, bool) bool, opts ...request.Option) error
+	DescribeLaunchTemplateVersionsPagesWithContext(ctx aws.Context, input *ec2.DescribeLaunchTemplateVersionsInput, fn func(*ec2.DescribeLaunchTemplateVersionsOutput, bool) bool, opts ...request.Option) error
+	DescribeSnapshotAttributeWithContext(ctx aws.Context, input *ec2.DescribeSnapshotAttributeInput, opts ...request.Option) (*ec2.DescribeSnapshotAttributeOutput, error)
+	ModifySnapshotAttributeWithContext(ctx aws.Context, input *ec2.ModifySnapshotAttributeInput, opts ...request.Option) (*ec2.ModifySnapshotAttributeOutput, error)
+}
+
+type RecycleBinAPI interface {
+	ListRulesPagesWithContext(ctx aws.Context, input *recyclebin.ListRulesInput, fn func(*recyclebin.ListRulesOutput, bool) bool, opts ...request.Option) error
+	GetRuleWithContext(ctx aws.Context, input *recyclebin.GetRuleInput, opts ...request.Option) (*recyclebin.GetRuleOutput, error)
+}
+
+type BackupAPI interface {
+	ListBackupVaultsPagesWithContext(ctx aws.Context, input *backup.ListBackupVaultsInput, fn func(*backup.ListBackupVaultsOutput, bool) bool, opts ...request.Option) error
+	ListRecoveryPointsByBackupVaultPagesWithContext(ctx aws.Context, input *backup.ListRecoveryPointsByBackupVaultInput, fn func(*backup.ListRecoveryPointsByBackupVaultOutput, bool) bool, opts ...request.Option) error
+}
+
+type SnapshotCopyAPI interface {
+	CopySnapshotWithContext(ctx aws.Context, input *ec2.CopySnapshotInput, opts ...request.Option) (*ec2.CopySnapshotOutput, error)
+	WaitUntilSnapshotCompletedWithContext(ctx aws.Context, input *ec2.DescribeSnapshotsInput, opts ...request.WaiterOption) error
+}
+
+type EventBridgeAPI interface {
+	PutEventsWithContext(ctx aws.Context, input *eventbridge.PutEventsInput, opts ...request.Option) (*eventbridge.PutEventsOutput, error)
+}
+
+type SNSAPI interface {
+	PublishBatchWithContext(ctx aws.Context, input *sns.PublishBatchInput, opts ...request.Option) (*sns.PublishBatchOutput, error)
+}
+
+type CallerIdentityAPI interface {
+	GetCallerIdentityWithContext(ctx aws.Context, input *sts.GetCallerIdentityInput, opts ...request.Option) (*sts.GetCallerIdentityOutput, error)
 }
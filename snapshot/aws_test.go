@@ -8,7 +8,11 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/backup"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+	"github.com/aws/aws-sdk-go/service/recyclebin"
+	"github.com/aws/aws-sdk-go/service/sns"
 )
 
 func Test_awsConfig_hasAccessKeys(t *testing.T) {
@@ -248,8 +252,16 @@ func Test_tagsMapEC2Filters(t *testing.T) {
 }
 
 type ec2SnapshotAPIMock struct {
-	DescribeSnapshotsPagesWithContextFunc func(ctx aws.Context, input *ec2.DescribeSnapshotsInput, fn func(*ec2.DescribeSnapshotsOutput, bool) bool, opts ...request.Option) error
-	DeleteSnapshotWithContextFunc         func(ctx aws.Context, input *ec2.DeleteSnapshotInput, opts ...request.Option) (*ec2.DeleteSnapshotOutput, error)
+	DescribeSnapshotsPagesWithContextFunc              func(ctx aws.Context, input *ec2.DescribeSnapshotsInput, fn func(*ec2.DescribeSnapshotsOutput, bool) bool, opts ...request.Option) error
+	DeleteSnapshotWithContextFunc                      func(ctx aws.Context, input *ec2.DeleteSnapshotInput, opts ...request.Option) (*ec2.DeleteSnapshotOutput, error)
+	ListSnapshotsInRecycleBinPagesWithContextFunc      func(ctx aws.Context, input *ec2.ListSnapshotsInRecycleBinInput, fn func(*ec2.ListSnapshotsInRecycleBinOutput, bool) bool, opts ...request.Option) error
+	RestoreSnapshotFromRecycleBinWithContextFunc       func(ctx aws.Context, input *ec2.RestoreSnapshotFromRecycleBinInput, opts ...request.Option) (*ec2.RestoreSnapshotFromRecycleBinOutput, error)
+	DescribeLockedSnapshotsWithContextFunc             func(ctx aws.Context, input *ec2.DescribeLockedSnapshotsInput, opts ...request.Option) (*ec2.DescribeLockedSnapshotsOutput, error)
+	UnlockSnapshotWithContextFunc                      func(ctx aws.Context, input *ec2.UnlockSnapshotInput, opts ...request.Option) (*ec2.UnlockSnapshotOutput, error)
+	DescribeLaunchTemplatesPagesWithContextFunc        func(ctx aws.Context, input *ec2.DescribeLaunchTemplatesInput, fn func(*ec2.DescribeLaunchTemplatesOutput, bool) bool, opts ...request.Option) error
+	DescribeLaunchTemplateVersionsPagesWithContextFunc func(ctx aws.Context, input *ec2.DescribeLaunchTemplateVersionsInput, fn func(*ec2.DescribeLaunchTemplateVersionsOutput, bool) bool, opts ...request.Option) error
+	DescribeSnapshotAttributeWithContextFunc           func(ctx aws.Context, input *ec2.DescribeSnapshotAttributeInput, opts ...request.Option) (*ec2.DescribeSnapshotAttributeOutput, error)
+	ModifySnapshotAttributeWithContextFunc             func(ctx aws.Context, input *ec2.ModifySnapshotAttributeInput, opts ...request.Option) (*ec2.ModifySnapshotAttributeOutput, error)
 }
 
 func (m *ec2SnapshotAPIMock) DescribeSnapshotsPagesWithContext(ctx aws.Context, input *ec2.DescribeSnapshotsInput, fn func(*ec2.DescribeSnapshotsOutput, bool) bool, opts ...request.Option) error {
@@ -260,6 +272,38 @@ func (m *ec2SnapshotAPIMock) DeleteSnapshotWithContext(ctx aws.Context, input *e
 	return m.DeleteSnapshotWithContextFunc(ctx, input, opts...)
 }
 
+func (m *ec2SnapshotAPIMock) ListSnapshotsInRecycleBinPagesWithContext(ctx aws.Context, input *ec2.ListSnapshotsInRecycleBinInput, fn func(*ec2.ListSnapshotsInRecycleBinOutput, bool) bool, opts ...request.Option) error {
+	return m.ListSnapshotsInRecycleBinPagesWithContextFunc(ctx, input, fn, opts...)
+}
+
+func (m *ec2SnapshotAPIMock) RestoreSnapshotFromRecycleBinWithContext(ctx aws.Context, input *ec2.RestoreSnapshotFromRecycleBinInput, opts ...request.Option) (*ec2.RestoreSnapshotFromRecycleBinOutput, error) {
+	return m.RestoreSnapshotFromRecycleBinWithContextFunc(ctx, input, opts...)
+}
+
+func (m *ec2SnapshotAPIMock) DescribeLockedSnapshotsWithContext(ctx aws.Context, input *ec2.DescribeLockedSnapshotsInput, opts ...request.Option) (*ec2.DescribeLockedSnapshotsOutput, error) {
+	return m.DescribeLockedSnapshotsWithContextFunc(ctx, input, opts...)
+}
+
+func (m *ec2SnapshotAPIMock) UnlockSnapshotWithContext(ctx aws.Context, input *ec2.UnlockSnapshotInput, opts ...request.Option) (*ec2.UnlockSnapshotOutput, error) {
+	return m.UnlockSnapshotWithContextFunc(ctx, input, opts...)
+}
+
+func (m *ec2SnapshotAPIMock) DescribeLaunchTemplatesPagesWithContext(ctx aws.Context, input *ec2.DescribeLaunchTemplatesInput, fn func(*ec2.DescribeLaunchTemplatesOutput, bool) bool, opts ...request.Option) error {
+	return m.DescribeLaunchTemplatesPagesWithContextFunc(ctx, input, fn, opts...)
+}
+
+func (m *ec2SnapshotAPIMock) DescribeLaunchTemplateVersionsPagesWithContext(ctx aws.Context, input *ec2.DescribeLaunchTemplateVersionsInput, fn func(*ec2.DescribeLaunchTemplateVersionsOutput, bool) bool, opts ...request.Option) error {
+	return m.DescribeLaunchTemplateVersionsPagesWithContextFunc(ctx, input, fn, opts...)
+}
+
+func (m *ec2SnapshotAPIMock) DescribeSnapshotAttributeWithContext(ctx aws.Context, input *ec2.DescribeSnapshotAttributeInput, opts ...request.Option) (*ec2.DescribeSnapshotAttributeOutput, error) {
+	return m.DescribeSnapshotAttributeWithContextFunc(ctx, input, opts...)
+}
+
+func (m *ec2SnapshotAPIMock) ModifySnapshotAttributeWithContext(ctx aws.Context, input *ec2.ModifySnapshotAttributeInput, opts ...request.Option) (*ec2.ModifySnapshotAttributeOutput, error) {
+	return m.ModifySnapshotAttributeWithContextFunc(ctx, input, opts...)
+}
+
 func newSnapshot(snapshotId string, startTime time.Time, tagSet []string) *ec2.Snapshot {
 	var tags []*ec2.Tag
 	for i, v := range tagSet {
@@ -277,3 +321,58 @@ func newSnapshot(snapshotId string, startTime time.Time, tagSet []string) *ec2.S
 		Tags:       tags,
 	}
 }
+
+type recycleBinAPIMock struct {
+	ListRulesPagesWithContextFunc func(ctx aws.Context, input *recyclebin.ListRulesInput, fn func(*recyclebin.ListRulesOutput, bool) bool, opts ...request.Option) error
+	GetRuleWithContextFunc        func(ctx aws.Context, input *recyclebin.GetRuleInput, opts ...request.Option) (*recyclebin.GetRuleOutput, error)
+}
+
+func (m *recycleBinAPIMock) ListRulesPagesWithContext(ctx aws.Context, input *recyclebin.ListRulesInput, fn func(*recyclebin.ListRulesOutput, bool) bool, opts ...request.Option) error {
+	return m.ListRulesPagesWithContextFunc(ctx, input, fn, opts...)
+}
+
+func (m *recycleBinAPIMock) GetRuleWithContext(ctx aws.Context, input *recyclebin.GetRuleInput, opts ...request.Option) (*recyclebin.GetRuleOutput, error) {
+	return m.GetRuleWithContextFunc(ctx, input, opts...)
+}
+
+type backupAPIMock struct {
+	ListBackupVaultsPagesWithContextFunc                func(ctx aws.Context, input *backup.ListBackupVaultsInput, fn func(*backup.ListBackupVaultsOutput, bool) bool, opts ...request.Option) error
+	ListRecoveryPointsByBackupVaultPagesWithContextFunc func(ctx aws.Context, input *backup.ListRecoveryPointsByBackupVaultInput, fn func(*backup.ListRecoveryPointsByBackupVaultOutput, bool) bool, opts ...request.Option) error
+}
+
+func (m *backupAPIMock) ListBackupVaultsPagesWithContext(ctx aws.Context, input *backup.ListBackupVaultsInput, fn func(*backup.ListBackupVaultsOutput, bool) bool, opts ...request.Option) error {
+	return m.ListBackupVaultsPagesWithContextFunc(ctx, input, fn, opts...)
+}
+
+func (m *backupAPIMock) ListRecoveryPointsByBackupVaultPagesWithContext(ctx aws.Context, input *backup.ListRecoveryPointsByBackupVaultInput, fn func(*backup.ListRecoveryPointsByBackupVaultOutput, bool) bool, opts ...request.Option) error {
+	return m.ListRecoveryPointsByBackupVaultPagesWithContextFunc(ctx, input, fn, opts...)
+}
+
+type snapshotCopyAPIMock struct {
+	CopySnapshotWithContextFunc               func(ctx aws.Context, input *ec2.CopySnapshotInput, opts ...request.Option) (*ec2.CopySnapshotOutput, error)
+	WaitUntilSnapshotCompletedWithContextFunc func(ctx aws.Context, input *ec2.DescribeSnapshotsInput, opts ...request.WaiterOption) error
+}
+
+func (m *snapshotCopyAPIMock) CopySnapshotWithContext(ctx aws.Context, input *ec2.CopySnapshotInput, opts ...request.Option) (*ec2.CopySnapshotOutput, error) {
+	return m.CopySnapshotWithContextFunc(ctx, input, opts...)
+}
+
+func (m *snapshotCopyAPIMock) WaitUntilSnapshotCompletedWithContext(ctx aws.Context, input *ec2.DescribeSnapshotsInput, opts ...request.WaiterOption) error {
+	return m.WaitUntilSnapshotCompletedWithContextFunc(ctx, input, opts...)
+}
+
+type eventBridgeAPIMock struct {
+	PutEventsWithContextFunc func(ctx aws.Context, input *eventbridge.PutEventsInput, opts ...request.Option) (*eventbridge.PutEventsOutput, error)
+}
+
+func (m *eventBridgeAPIMock) PutEventsWithContext(ctx aws.Context, input *eventbridge.PutEventsInput, opts ...request.Option) (*eventbridge.PutEventsOutput, error) {
+	return m.PutEventsWithContextFunc(ctx, input, opts...)
+}
+
+type snsAPIMock struct {
+	PublishBatchWithContextFunc func(ctx aws.Context, input *sns.PublishBatchInput, opts ...request.Option) (*sns.PublishBatchOutput, error)
+}
+
+func (m *snsAPIMock) PublishBatchWithContext(ctx aws.Context, input *sns.PublishBatchInput, opts ...request.Option) (*sns.PublishBatchOutput, error) {
+	return m.PublishBatchWithContextFunc(ctx, input, opts...)
+}
@@ -0,0 +1,187 @@
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+var (
+	errCopyToAccountWithoutRole = errors.New("copy-to-account requires copy-role-arn")
+	errCopyWithUnshare          = errors.New("copy can not be used with the unshare action")
+)
+
+// copyWaitMaxAttempts waits for a copy up to 2 hours, polling every 15 seconds.
+const copyWaitMaxAttempts = 480
+
+type copyTarget struct {
+	sourceRegion string
+	region       string
+	account      string
+	kmsKeyID     string
+	// copies are made by an earlier run, keyed by the source snapshot id.
+	copies map[string]string
+	svc    SnapshotCopyAPI
+}
+
+func (cfg *BulkDeleteConfig) hasCopy() bool {
+	return cfg.CopyToRegion != "" || cfg.CopyToAccount != ""
+}
+
+func (cfg *BulkDeleteConfig) copyTarget(sess *session.Session) (*copyTarget, error) {
+	if !cfg.hasCopy() {
+		return nil, nil
+	}
+	if cfg.Action == ActionUnshare {
+		return nil, errCopyWithUnshare
+	}
+	if cfg.CopyToAccount != "" && cfg.CopyRoleARN == "" {
+		return nil, errCopyToAccountWithoutRole
+	}
+	t := &copyTarget{
+		sourceRegion: cfg.Region,
+		region:       cfg.CopyToRegion,
+		account:      cfg.CopyToAccount,
+		kmsKeyID:     cfg.CopyKmsKeyID,
+		copies:       cfg.CopySnapshotIDs,
+	}
+	if t.region == "" {
+		t.region = cfg.Region
+	}
+	awsCfg := aws.NewConfig().WithRegion(t.region)
+	if cfg.CopyRoleARN != "" {
+		awsCfg = awsCfg.WithCredentials(stscreds.NewCredentials(sess, cfg.CopyRoleARN))
+	}
+	t.svc = ec2.New(sess, awsCfg)
+	return t, nil
+}
+
+// copySnapshot copies the snapshot to the target and waits for the copy to be
+// completed. It returns the snapshot id of the copy, also if the wait fails,
+// so that the copy is not made again. A copy made by an earlier run is only
+// waited for.
+func (c *BullDelete) copySnapshot(ctx context.Context, snapshot *ec2.Snapshot) (string, error) {
+	t := c.copyTarget
+	if id, ok := t.copies[aws.StringValue(snapshot.SnapshotId)]; ok && id != "" {
+		return id, t.waitForCopy(ctx, id)
+	}
+	if t.account != "" {
+		err := c.withRetry(ctx, func() error {
+			_, err := c.svc.ModifySnapshotAttributeWithContext(ctx, &ec2.ModifySnapshotAttributeInput{
+				Attribute:  aws.String(ec2.SnapshotAttributeNameCreateVolumePermission),
+				SnapshotId: snapshot.SnapshotId,
+				CreateVolumePermission: &ec2.CreateVolumePermissionModifications{
+					Add: []*ec2.CreateVolumePermission{{UserId: aws.String(t.account)}},
+				},
+			})
+			return err
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to share with %s: %w", t.account, err)
+		}
+	}
+	input := &ec2.CopySnapshotInput{
+		SourceRegion:     aws.String(t.sourceRegion),
+		SourceSnapshotId: snapshot.SnapshotId,
+		Description:      aws.String("Copy of " + aws.StringValue(snapshot.SnapshotId)),
+	}
+	if t.kmsKeyID != "" {
+		input.Encrypted = aws.Bool(true)
+		input.KmsKeyId = aws.String(t.kmsKeyID)
+	}
+	if tags := copyTags(snapshot.Tags); len(tags) > 0 {
+		input.TagSpecifications = []*ec2.TagSpecification{{
+			ResourceType: aws.String(ec2.ResourceTypeSnapshot),
+			Tags:         tags,
+		}}
+	}
+	var out *ec2.CopySnapshotOutput
+	err := c.withRetry(ctx, func() error {
+		var err error
+		out, err = t.svc.CopySnapshotWithContext(ctx, input)
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to copy: %w", err)
+	}
+	return aws.StringValue(out.SnapshotId), t.waitForCopy(ctx, aws.StringValue(out.SnapshotId))
+}
+
+func (t *copyTarget) waitForCopy(ctx context.Context, copySnapshotID string) error {
+	err := t.svc.WaitUntilSnapshotCompletedWithContext(ctx, &ec2.DescribeSnapshotsInput{
+		SnapshotIds: []*string{aws.String(copySnapshotID)},
+	}, request.WithWaiterMaxAttempts(copyWaitMaxAttempts))
+	if err != nil {
+		return &copyWaitError{copySnapshotID: copySnapshotID, err: err}
+	}
+	return nil
+}
+
+// waiterExceededMessage is the message of the waiter which ran out of
+// attempts. A copy in the error state fails the waiter with the same code.
+const waiterExceededMessage = "exceeded wait attempts"
+
+// copyWaitError is a copy which was made but not waited for to completion.
+type copyWaitError struct {
+	copySnapshotID string
+	err            error
+}
+
+func (e *copyWaitError) Error() string {
+	return fmt.Sprintf("failed to wait for copy %s: %v", e.copySnapshotID, e.err)
+}
+
+func (e *copyWaitError) Unwrap() error {
+	return e.err
+}
+
+// retryable reports whether the copy may still complete, ie. the wait timed
+// out or was interrupted, so that a retry waits for the same copy.
+func (e *copyWaitError) retryable() bool {
+	if errors.Is(e.err, context.Canceled) || errors.Is(e.err, context.DeadlineExceeded) {
+		return true
+	}
+	var aerr awserr.Error
+	if !errors.As(e.err, &aerr) {
+		return false
+	}
+	switch aerr.Code() {
+	case request.WaiterResourceNotReadyErrorCode:
+		return aerr.Message() == waiterExceededMessage
+	case request.CanceledErrorCode:
+		return true
+	}
+	return false
+}
+
+// copyTags drops the tags with the reserved aws: prefix, which can not be set.
+func copyTags(tags []*ec2.Tag) []*ec2.Tag {
+	var copied []*ec2.Tag
+	for _, v := range tags {
+		if strings.HasPrefix(aws.StringValue(v.Key), "aws:") {
+			continue
+		}
+		copied = append(copied, v)
+	}
+	return copied
+}
+
+func (c *BullDelete) copyBeforeDelete(ctx context.Context, snapshot *ec2.Snapshot, eachFunc func(snapshot *ec2.Snapshot, copySnapshotID string) error) error {
+	copySnapshotID, err := c.copySnapshot(ctx, snapshot)
+	if copySnapshotID != "" && eachFunc != nil {
+		// The copy exists even if the wait failed, so it is reported to
+		// keep it in the result.
+		if err := eachFunc(snapshot, copySnapshotID); err != nil {
+			return err
+		}
+	}
+	return err
+}
@@ -0,0 +1,277 @@
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func TestConfig_copyTarget(t *testing.T) {
+	sess := session.Must(session.NewSession())
+	tests := []struct {
+		name       string
+		cfg        *BulkDeleteConfig
+		wantRegion string
+		wantErr    error
+	}{
+		{
+			name: "no copy",
+			cfg:  &BulkDeleteConfig{Region: "us-east-1"},
+		},
+		{
+			name:       "region",
+			cfg:        &BulkDeleteConfig{Region: "us-east-1", CopyToRegion: "us-west-2"},
+			wantRegion: "us-west-2",
+		},
+		{
+			name:       "account in the same region",
+			cfg:        &BulkDeleteConfig{Region: "us-east-1", CopyToAccount: "123456789012", CopyRoleARN: "arn:aws:iam::123456789012:role/backup"},
+			wantRegion: "us-east-1",
+		},
+		{
+			name:    "account without role",
+			cfg:     &BulkDeleteConfig{Region: "us-east-1", CopyToAccount: "123456789012"},
+			wantErr: errCopyToAccountWithoutRole,
+		},
+		{
+			name:    "unshare",
+			cfg:     &BulkDeleteConfig{Region: "us-east-1", CopyToRegion: "us-west-2", Action: ActionUnshare},
+			wantErr: errCopyWithUnshare,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.cfg.copyTarget(sess)
+			if err != tt.wantErr {
+				t.Fatalf("copyTarget() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got == nil {
+				if tt.wantRegion != "" {
+					t.Errorf("copyTarget() = nil, want region %v", tt.wantRegion)
+				}
+				return
+			}
+			if got.region != tt.wantRegion {
+				t.Errorf("copyTarget() region = %v, want %v", got.region, tt.wantRegion)
+			}
+		})
+	}
+}
+
+func Test_copyTags(t *testing.T) {
+	tags := []*ec2.Tag{
+		{Key: aws.String("Name"), Value: aws.String("foo")},
+		{Key: aws.String("aws:backup:source-resource"), Value: aws.String("vol-1")},
+	}
+	want := []*ec2.Tag{{Key: aws.String("Name"), Value: aws.String("foo")}}
+	if got := copyTags(tags); !reflect.DeepEqual(got, want) {
+		t.Errorf("copyTags() = %v, want %v", got, want)
+	}
+}
+
+func TestBullDelete_deleteSnapshots_copy(t *testing.T) {
+	startTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	var (
+		shared  []string
+		deleted []string
+	)
+	c := &BullDelete{
+		svc: &ec2SnapshotAPIMock{
+			ModifySnapshotAttributeWithContextFunc: func(ctx aws.Context, input *ec2.ModifySnapshotAttributeInput, opts ...request.Option) (*ec2.ModifySnapshotAttributeOutput, error) {
+				shared = append(shared, aws.StringValue(input.CreateVolumePermission.Add[0].UserId))
+				return &ec2.ModifySnapshotAttributeOutput{}, nil
+			},
+			DeleteSnapshotWithContextFunc: func(ctx aws.Context, input *ec2.DeleteSnapshotInput, opts ...request.Option) (*ec2.DeleteSnapshotOutput, error) {
+				deleted = append(deleted, aws.StringValue(input.SnapshotId))
+				return &ec2.DeleteSnapshotOutput{}, nil
+			},
+		},
+		copyTarget: &copyTarget{
+			sourceRegion: "us-east-1",
+			region:       "us-west-2",
+			account:      "123456789012",
+			kmsKeyID:     "alias/backup",
+			svc: &snapshotCopyAPIMock{
+				CopySnapshotWithContextFunc: func(ctx aws.Context, input *ec2.CopySnapshotInput, opts ...request.Option) (*ec2.CopySnapshotOutput, error) {
+					if aws.StringValue(input.SourceRegion) != "us-east-1" || aws.StringValue(input.KmsKeyId) != "alias/backup" || !aws.BoolValue(input.Encrypted) {
+						t.Errorf("CopySnapshot() input = %v", input)
+					}
+					id := aws.StringValue(input.SourceSnapshotId)
+					if id == "snap-denied" {
+						return nil, awserr.New("UnauthorizedOperation", "", nil)
+					}
+					return &ec2.CopySnapshotOutput{SnapshotId: aws.String("copy-of-" + id)}, nil
+				},
+				WaitUntilSnapshotCompletedWithContextFunc: func(ctx aws.Context, input *ec2.DescribeSnapshotsInput, opts ...request.WaiterOption) error {
+					if aws.StringValue(input.SnapshotIds[0]) == "copy-of-snap-error" {
+						return errors.New("snapshot is in the error state")
+					}
+					return nil
+				},
+			},
+		},
+	}
+	copies := make(map[string]string)
+	successful, failed, err := c.deleteSnapshots(context.Background(), []*ec2.Snapshot{
+		newSnapshot("snap-ok", startTime, nil),
+		newSnapshot("snap-denied", startTime, nil),
+		newSnapshot("snap-error", startTime, nil),
+	}, nil, Options{
+		EachCopySnapshotFunc: func(snapshot *ec2.Snapshot, copySnapshotID string) error {
+			copies[aws.StringValue(snapshot.SnapshotId)] = copySnapshotID
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("deleteSnapshots() error = %v", err)
+	}
+	if len(successful) != 1 || len(failed) != 2 {
+		t.Fatalf("deleteSnapshots() successful = %d, failed = %d", len(successful), len(failed))
+	}
+	if failed[0].Kind != FailureKindDenied {
+		t.Errorf("deleteSnapshots() kind = %v, want %v", failed[0].Kind, FailureKindDenied)
+	}
+	if !reflect.DeepEqual(deleted, []string{"snap-ok"}) {
+		t.Errorf("deleteSnapshots() deleted = %v", deleted)
+	}
+	if want := map[string]string{"snap-ok": "copy-of-snap-ok", "snap-error": "copy-of-snap-error"}; !reflect.DeepEqual(copies, want) {
+		t.Errorf("deleteSnapshots() copies = %v, want %v", copies, want)
+	}
+	if len(shared) != 3 || shared[0] != "123456789012" {
+		t.Errorf("deleteSnapshots() shared = %v", shared)
+	}
+}
+
+func TestBullDelete_deleteSnapshots_copyAgain(t *testing.T) {
+	startTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	var copied, waited []string
+	c := &BullDelete{
+		svc: &ec2SnapshotAPIMock{
+			DeleteSnapshotWithContextFunc: func(ctx aws.Context, input *ec2.DeleteSnapshotInput, opts ...request.Option) (*ec2.DeleteSnapshotOutput, error) {
+				return &ec2.DeleteSnapshotOutput{}, nil
+			},
+		},
+		copyTarget: &copyTarget{
+			sourceRegion: "us-east-1",
+			region:       "us-west-2",
+			copies:       map[string]string{"snap-copied": "copy-1"},
+			svc: &snapshotCopyAPIMock{
+				CopySnapshotWithContextFunc: func(ctx aws.Context, input *ec2.CopySnapshotInput, opts ...request.Option) (*ec2.CopySnapshotOutput, error) {
+					copied = append(copied, aws.StringValue(input.SourceSnapshotId))
+					return &ec2.CopySnapshotOutput{SnapshotId: aws.String("copy-2")}, nil
+				},
+				WaitUntilSnapshotCompletedWithContextFunc: func(ctx aws.Context, input *ec2.DescribeSnapshotsInput, opts ...request.WaiterOption) error {
+					waited = append(waited, aws.StringValue(input.SnapshotIds[0]))
+					return nil
+				},
+			},
+		},
+	}
+	copies := make(map[string]string)
+	successful, _, err := c.deleteSnapshots(context.Background(), []*ec2.Snapshot{
+		newSnapshot("snap-copied", startTime, nil),
+		newSnapshot("snap-new", startTime, nil),
+	}, nil, Options{
+		EachCopySnapshotFunc: func(snapshot *ec2.Snapshot, copySnapshotID string) error {
+			copies[aws.StringValue(snapshot.SnapshotId)] = copySnapshotID
+			return nil
+		},
+	})
+	if err != nil || len(successful) != 2 {
+		t.Fatalf("deleteSnapshots() successful = %d, error = %v", len(successful), err)
+	}
+	if !reflect.DeepEqual(copied, []string{"snap-new"}) || !reflect.DeepEqual(waited, []string{"copy-1", "copy-2"}) {
+		t.Errorf("deleteSnapshots() copied = %v, waited = %v", copied, waited)
+	}
+	if want := map[string]string{"snap-copied": "copy-1", "snap-new": "copy-2"}; !reflect.DeepEqual(copies, want) {
+		t.Errorf("deleteSnapshots() copies = %v, want %v", copies, want)
+	}
+}
+
+func TestBullDelete_deleteSnapshots_retryCopyWait(t *testing.T) {
+	startTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	var (
+		copied, waited []string
+		waitErr        error
+	)
+	newBulkDelete := func(copies map[string]string) *BullDelete {
+		return &BullDelete{
+			svc: &ec2SnapshotAPIMock{
+				DeleteSnapshotWithContextFunc: func(ctx aws.Context, input *ec2.DeleteSnapshotInput, opts ...request.Option) (*ec2.DeleteSnapshotOutput, error) {
+					return &ec2.DeleteSnapshotOutput{}, nil
+				},
+			},
+			copyTarget: &copyTarget{
+				sourceRegion: "us-east-1",
+				region:       "us-west-2",
+				copies:       copies,
+				svc: &snapshotCopyAPIMock{
+					CopySnapshotWithContextFunc: func(ctx aws.Context, input *ec2.CopySnapshotInput, opts ...request.Option) (*ec2.CopySnapshotOutput, error) {
+						id := aws.StringValue(input.SourceSnapshotId)
+						copied = append(copied, id)
+						return &ec2.CopySnapshotOutput{SnapshotId: aws.String("copy-of-" + id)}, nil
+					},
+					WaitUntilSnapshotCompletedWithContextFunc: func(ctx aws.Context, input *ec2.DescribeSnapshotsInput, opts ...request.WaiterOption) error {
+						id := aws.StringValue(input.SnapshotIds[0])
+						waited = append(waited, id)
+						if id == "copy-of-snap-broken" {
+							return awserr.New(request.WaiterResourceNotReadyErrorCode, "failed waiting for successful resource state", nil)
+						}
+						return waitErr
+					},
+				},
+			},
+		}
+	}
+	copies := make(map[string]string)
+	opts := Options{
+		EachCopySnapshotFunc: func(snapshot *ec2.Snapshot, copySnapshotID string) error {
+			copies[aws.StringValue(snapshot.SnapshotId)] = copySnapshotID
+			return nil
+		},
+	}
+
+	// The wait of the first run times out, and the copy in the error state
+	// is not worth a retry.
+	waitErr = awserr.New(request.WaiterResourceNotReadyErrorCode, waiterExceededMessage, nil)
+	successful, failed, err := newBulkDelete(nil).deleteSnapshots(context.Background(), []*ec2.Snapshot{
+		newSnapshot("snap-slow", startTime, nil),
+		newSnapshot("snap-broken", startTime, nil),
+	}, nil, opts)
+	if err != nil || len(successful) != 0 || len(failed) != 2 {
+		t.Fatalf("deleteSnapshots() successful = %d, failed = %d, error = %v", len(successful), len(failed), err)
+	}
+	if failed[0].Kind != FailureKindRetryable || failed[1].Kind != FailureKindOther {
+		t.Errorf("deleteSnapshots() kinds = %v, %v", failed[0].Kind, failed[1].Kind)
+	}
+	result := NewResult("run-1", successful, failed)
+	result.SetCopySnapshotIDs(copies)
+	var buf bytes.Buffer
+	if err := result.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	from, err := ReadResult(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The retry waits for the copy of the first run instead of copying again.
+	copied, waited, waitErr = nil, nil, nil
+	snapshots := from.RetryableSnapshots()
+	successful, failed, err = newBulkDelete(from.CopySnapshotIDs()).deleteSnapshots(context.Background(), snapshots, nil, opts)
+	if err != nil || len(successful) != 1 || len(failed) != 0 {
+		t.Fatalf("deleteSnapshots() successful = %d, failed = %d, error = %v", len(successful), len(failed), err)
+	}
+	if len(copied) != 0 || !reflect.DeepEqual(waited, []string{"copy-of-snap-slow"}) {
+		t.Errorf("deleteSnapshots() copied = %v, waited = %v", copied, waited)
+	}
+}
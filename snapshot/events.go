@@ -0,0 +1,307 @@
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+var errEventTarget = errors.New("specify either an SNS topic or an EventBridge bus")
+
+// EventSource is the source of the EventBridge events.
+const EventSource = "aws-snapshot-bulk-delete"
+
+// eventBatchSize is the max entries of both PutEvents and PublishBatch.
+const eventBatchSize = 10
+
+type EventType string
+
+const (
+	EventRunStarted           EventType = "RunStarted"
+	EventPlanCreated          EventType = "PlanCreated"
+	EventSnapshotDeleted      EventType = "SnapshotDeleted"
+	EventSnapshotDeleteFailed EventType = "SnapshotDeleteFailed"
+	EventRunFinished          EventType = "RunFinished"
+)
+
+// EventStatus is the status of RunFinished.
+type EventStatus string
+
+const (
+	// EventStatusCompleted is a run which reached its end, even with failed
+	// snapshots.
+	EventStatusCompleted EventStatus = "completed"
+	// EventStatusAborted is a run which ended early with the error of the
+	// event, eg. AccessDenied.
+	EventStatusAborted EventStatus = "aborted"
+	// EventStatusCancelled is a run which the user declined at the
+	// confirmation.
+	EventStatusCancelled EventStatus = "cancelled"
+)
+
+type Event struct {
+	Type       EventType    `json:"type"`
+	RunID      string       `json:"run_id"`
+	Account    string       `json:"account"`
+	Region     string       `json:"region"`
+	Action     Action       `json:"action"`
+	Time       time.Time    `json:"time"`
+	SnapshotID string       `json:"snapshot_id,omitempty"`
+	VolumeID   string       `json:"volume_id,omitempty"`
+	Kind       FailureKind  `json:"kind,omitempty"`
+	Error      string       `json:"error,omitempty"`
+	Status     EventStatus  `json:"status,omitempty"`
+	Counts     *EventCounts `json:"counts,omitempty"`
+}
+
+type EventCounts struct {
+	Planned    int `json:"planned"`
+	Excluded   int `json:"excluded"`
+	NotFound   int `json:"not_found"`
+	Successful int `json:"successful"`
+	Failed     int `json:"failed"`
+}
+
+type EventPublisherConfig struct {
+	RunID       string
+	SNSTopicARN string
+	// EventBusName is the name or ARN of an EventBridge bus.
+	EventBusName string
+	// OnError is called when events can not be published, which does not
+	// stop the run.
+	OnError func(err error)
+}
+
+type EventPublisher struct {
+	runID   string
+	account string
+	region  string
+	action  Action
+	plan    bool
+	topic   string
+	bus     string
+	sns     SNSAPI
+	events  EventBridgeAPI
+	onError func(err error)
+	now     func() time.Time
+
+	pending []*Event
+	counts  EventCounts
+}
+
+// NewEventPublisher returns a publisher with the credentials and region of cfg.
+func NewEventPublisher(ctx context.Context, cfg *BulkDeleteConfig, pubCfg *EventPublisherConfig) (*EventPublisher, error) {
+	if (pubCfg.SNSTopicARN == "") == (pubCfg.EventBusName == "") {
+		return nil, errEventTarget
+	}
+	sess, err := newAWSSession(cfg.awsConfig())
+	if err != nil {
+		return nil, err
+	}
+	account, err := callerAccount(ctx, sts.New(sess))
+	if err != nil {
+		return nil, err
+	}
+	action := cfg.Action
+	if action == "" {
+		action = ActionDelete
+	}
+	return &EventPublisher{
+		runID:   pubCfg.RunID,
+		account: account,
+		region:  cfg.Region,
+		action:  action,
+		plan:    cfg.Plan,
+		topic:   pubCfg.SNSTopicARN,
+		bus:     pubCfg.EventBusName,
+		sns:     sns.New(sess),
+		events:  eventbridge.New(sess),
+		onError: pubCfg.OnError,
+		now:     time.Now,
+	}, nil
+}
+
+func callerAccount(ctx context.Context, svc CallerIdentityAPI) (string, error) {
+	out, err := svc.GetCallerIdentityWithContext(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get caller identity: %w", err)
+	}
+	return aws.StringValue(out.Account), nil
+}
+
+// Options returns the hooks which publish the events. SnapshotDeleted events
+// are sent in batches, and the rest are sent when they occur. RunFinished is
+// sent by AfterRunFunc, so that a run which ends early is finished too.
+func (p *EventPublisher) Options(ctx context.Context) Options {
+	return Options{
+		BeforeDescribeSnapshotsFunc: func() error {
+			p.publish(ctx, p.newEvent(EventRunStarted))
+			p.flush(ctx)
+			return nil
+		},
+		AfterPlanFunc: func(plan *Plan) error {
+			p.counts.Planned = len(plan.Snapshots)
+			p.counts.Excluded = len(plan.Excluded)
+			p.counts.NotFound = len(plan.NotFound)
+			e := p.newEvent(EventPlanCreated)
+			e.Counts = &EventCounts{Planned: p.counts.Planned, Excluded: p.counts.Excluded, NotFound: p.counts.NotFound}
+			p.publish(ctx, e)
+			if p.plan {
+				// Sent together with RunFinished.
+				return nil
+			}
+			p.flush(ctx)
+			return nil
+		},
+		EachDeleteSnapshotsFunc: func(snapshot *ec2.Snapshot) error {
+			e := p.newEvent(EventSnapshotDeleted)
+			e.SnapshotID = aws.StringValue(snapshot.SnapshotId)
+			e.VolumeID = aws.StringValue(snapshot.VolumeId)
+			p.publish(ctx, e)
+			return nil
+		},
+		AfterDeleteSnapshotsFunc: func(successful []*ec2.Snapshot, failed []*ErrorWithSnapshot) error {
+			for _, v := range failed {
+				e := p.newEvent(EventSnapshotDeleteFailed)
+				e.SnapshotID = aws.StringValue(v.Snapshot.SnapshotId)
+				e.VolumeID = aws.StringValue(v.Snapshot.VolumeId)
+				e.Kind = v.Kind
+				if v.Error != nil {
+					e.Error = v.Error.Error()
+				}
+				p.publish(ctx, e)
+			}
+			p.counts.Successful = len(successful)
+			p.counts.Failed = len(failed)
+			return nil
+		},
+		AfterRunFunc: func(err error) {
+			p.publish(ctx, p.runFinished(err))
+			p.flush(ctx)
+		},
+	}
+}
+
+func (p *EventPublisher) newEvent(t EventType) *Event {
+	return &Event{
+		Type:    t,
+		RunID:   p.runID,
+		Account: p.account,
+		Region:  p.region,
+		Action:  p.action,
+		Time:    p.now(),
+	}
+}
+
+func (p *EventPublisher) runFinished(err error) *Event {
+	e := p.newEvent(EventRunFinished)
+	switch {
+	case err == nil:
+		e.Status = EventStatusCompleted
+	case errors.Is(err, ErrCancelled):
+		e.Status = EventStatusCancelled
+	default:
+		e.Status = EventStatusAborted
+		// eg. an error of the CLI which starts with a newline.
+		e.Error = strings.TrimSpace(err.Error())
+	}
+	counts := p.counts
+	e.Counts = &counts
+	return e
+}
+
+// publish queues the event, and sends the queue once it fills a batch.
+func (p *EventPublisher) publish(ctx context.Context, e *Event) {
+	p.pending = append(p.pending, e)
+	if len(p.pending) >= eventBatchSize {
+		p.flush(ctx)
+	}
+}
+
+func (p *EventPublisher) flush(ctx context.Context) {
+	for len(p.pending) > 0 {
+		n := len(p.pending)
+		if n > eventBatchSize {
+			n = eventBatchSize
+		}
+		batch := p.pending[:n]
+		p.pending = p.pending[n:]
+		if err := p.send(ctx, batch); err != nil && p.onError != nil {
+			p.onError(fmt.Errorf("failed to publish events: %w", err))
+		}
+	}
+}
+
+func (p *EventPublisher) send(ctx context.Context, batch []*Event) error {
+	if p.topic != "" {
+		return p.sendSNS(ctx, batch)
+	}
+	return p.sendEventBridge(ctx, batch)
+}
+
+func (p *EventPublisher) sendSNS(ctx context.Context, batch []*Event) error {
+	input := &sns.PublishBatchInput{TopicArn: aws.String(p.topic)}
+	for i, v := range batch {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		input.PublishBatchRequestEntries = append(input.PublishBatchRequestEntries, &sns.PublishBatchRequestEntry{
+			Id:      aws.String(strconv.Itoa(i)),
+			Message: aws.String(string(b)),
+			// The type lets subscriptions filter the events.
+			MessageAttributes: map[string]*sns.MessageAttributeValue{
+				"type": {DataType: aws.String("String"), StringValue: aws.String(string(v.Type))},
+			},
+		})
+	}
+	out, err := p.sns.PublishBatchWithContext(ctx, input)
+	if err != nil {
+		return err
+	}
+	if len(out.Failed) > 0 {
+		f := out.Failed[0]
+		return fmt.Errorf("%d events failed: %s: %s", len(out.Failed), aws.StringValue(f.Code), aws.StringValue(f.Message))
+	}
+	return nil
+}
+
+func (p *EventPublisher) sendEventBridge(ctx context.Context, batch []*Event) error {
+	input := &eventbridge.PutEventsInput{}
+	for _, v := range batch {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		input.Entries = append(input.Entries, &eventbridge.PutEventsRequestEntry{
+			EventBusName: aws.String(p.bus),
+			Source:       aws.String(EventSource),
+			DetailType:   aws.String(string(v.Type)),
+			Detail:       aws.String(string(b)),
+			Time:         aws.Time(v.Time),
+		})
+	}
+	out, err := p.events.PutEventsWithContext(ctx, input)
+	if err != nil {
+		return err
+	}
+	if n := aws.Int64Value(out.FailedEntryCount); n > 0 {
+		for _, v := range out.Entries {
+			if v.ErrorCode != nil {
+				return fmt.Errorf("%d events failed: %s: %s", n, aws.StringValue(v.ErrorCode), aws.StringValue(v.ErrorMessage))
+			}
+		}
+		return fmt.Errorf("%d events failed", n)
+	}
+	return nil
+}
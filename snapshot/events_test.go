@@ -0,0 +1,235 @@
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+	"github.com/aws/aws-sdk-go/service/sns"
+)
+
+func newTestEventPublisher(plan bool) *EventPublisher {
+	now := time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC)
+	return &EventPublisher{
+		runID:   "run-1",
+		account: "123456789012",
+		region:  "us-east-1",
+		action:  ActionDelete,
+		plan:    plan,
+		now:     func() time.Time { return now },
+	}
+}
+
+func runEventPublisher(p *EventPublisher, deleted int, failed []*ErrorWithSnapshot) {
+	opts := p.Options(context.Background())
+	_ = opts.BeforeDescribeSnapshotsFunc()
+	var snapshots []*ec2.Snapshot
+	for i := 0; i < deleted; i++ {
+		snapshots = append(snapshots, &ec2.Snapshot{
+			SnapshotId: aws.String(fmt.Sprintf("snap-%d", i)),
+			VolumeId:   aws.String("vol-1"),
+		})
+	}
+	_ = opts.AfterPlanFunc(&Plan{Snapshots: snapshots, NotFound: []string{"snap-x"}})
+	if p.plan {
+		opts.AfterRunFunc(nil)
+		return
+	}
+	for _, v := range snapshots {
+		_ = opts.EachDeleteSnapshotsFunc(v)
+	}
+	_ = opts.AfterDeleteSnapshotsFunc(snapshots, failed)
+	opts.AfterRunFunc(nil)
+}
+
+func TestEventPublisher_Options(t *testing.T) {
+	failed := []*ErrorWithSnapshot{
+		{Snapshot: &ec2.Snapshot{SnapshotId: aws.String("snap-f")}, Error: errors.New("in use"), Kind: FailureKindInUse},
+	}
+	tests := []struct {
+		name        string
+		plan        bool
+		deleted     int
+		failed      []*ErrorWithSnapshot
+		wantBatches [][]EventType
+		wantCounts  EventCounts
+	}{
+		{
+			name:    "plan",
+			plan:    true,
+			deleted: 2,
+			wantBatches: [][]EventType{
+				{EventRunStarted},
+				{EventPlanCreated, EventRunFinished},
+			},
+			wantCounts: EventCounts{Planned: 2, NotFound: 1},
+		},
+		{
+			name:    "batches",
+			deleted: 12,
+			failed:  failed,
+			wantBatches: [][]EventType{
+				{EventRunStarted},
+				{EventPlanCreated},
+				{
+					EventSnapshotDeleted, EventSnapshotDeleted, EventSnapshotDeleted, EventSnapshotDeleted, EventSnapshotDeleted,
+					EventSnapshotDeleted, EventSnapshotDeleted, EventSnapshotDeleted, EventSnapshotDeleted, EventSnapshotDeleted,
+				},
+				{EventSnapshotDeleted, EventSnapshotDeleted, EventSnapshotDeleteFailed, EventRunFinished},
+			},
+			wantCounts: EventCounts{Planned: 12, NotFound: 1, Successful: 12, Failed: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				batches [][]EventType
+				last    Event
+			)
+			p := newTestEventPublisher(tt.plan)
+			p.bus = "default"
+			p.onError = func(err error) { t.Error(err) }
+			p.events = &eventBridgeAPIMock{
+				PutEventsWithContextFunc: func(ctx aws.Context, input *eventbridge.PutEventsInput, opts ...request.Option) (*eventbridge.PutEventsOutput, error) {
+					var batch []EventType
+					for _, v := range input.Entries {
+						if aws.StringValue(v.Source) != EventSource || aws.StringValue(v.EventBusName) != "default" {
+							t.Errorf("unexpected entry: %v", v)
+						}
+						if err := json.Unmarshal([]byte(aws.StringValue(v.Detail)), &last); err != nil {
+							t.Fatal(err)
+						}
+						batch = append(batch, EventType(aws.StringValue(v.DetailType)))
+					}
+					batches = append(batches, batch)
+					return &eventbridge.PutEventsOutput{FailedEntryCount: aws.Int64(0)}, nil
+				},
+			}
+			runEventPublisher(p, tt.deleted, tt.failed)
+			if !reflect.DeepEqual(batches, tt.wantBatches) {
+				t.Errorf("Options() batches = %v, want %v", batches, tt.wantBatches)
+			}
+			if last.Type != EventRunFinished || last.Status != EventStatusCompleted || last.RunID != "run-1" || last.Account != "123456789012" {
+				t.Errorf("Options() last = %+v", last)
+			}
+			if !reflect.DeepEqual(*last.Counts, tt.wantCounts) {
+				t.Errorf("Options() counts = %+v, want %+v", *last.Counts, tt.wantCounts)
+			}
+		})
+	}
+}
+
+func TestEventPublisher_Options_aborted(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus EventStatus
+		wantError  string
+	}{
+		{
+			name:       "aborted",
+			err:        errors.New("AccessDenied: not authorized"),
+			wantStatus: EventStatusAborted,
+			wantError:  "AccessDenied: not authorized",
+		},
+		{
+			// The confirmation is declined.
+			name:       "cancelled",
+			err:        fmt.Errorf("\nApply %w.", ErrCancelled),
+			wantStatus: EventStatusCancelled,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var events []Event
+			p := newTestEventPublisher(false)
+			p.bus = "default"
+			p.onError = func(err error) { t.Error(err) }
+			p.events = &eventBridgeAPIMock{
+				PutEventsWithContextFunc: func(ctx aws.Context, input *eventbridge.PutEventsInput, opts ...request.Option) (*eventbridge.PutEventsOutput, error) {
+					for _, v := range input.Entries {
+						var e Event
+						if err := json.Unmarshal([]byte(aws.StringValue(v.Detail)), &e); err != nil {
+							t.Fatal(err)
+						}
+						events = append(events, e)
+					}
+					return &eventbridge.PutEventsOutput{FailedEntryCount: aws.Int64(0)}, nil
+				},
+			}
+			opts := p.Options(context.Background())
+			_ = opts.BeforeDescribeSnapshotsFunc()
+			_ = opts.AfterPlanFunc(&Plan{Snapshots: []*ec2.Snapshot{{SnapshotId: aws.String("snap-1")}}})
+			opts.AfterRunFunc(tt.err)
+			var types []EventType
+			for _, v := range events {
+				types = append(types, v.Type)
+			}
+			if want := []EventType{EventRunStarted, EventPlanCreated, EventRunFinished}; !reflect.DeepEqual(types, want) {
+				t.Fatalf("Options() events = %v, want %v", types, want)
+			}
+			last := events[len(events)-1]
+			if last.Status != tt.wantStatus || last.Error != tt.wantError || last.Counts.Planned != 1 {
+				t.Errorf("Options() last = %+v", last)
+			}
+		})
+	}
+}
+
+func TestEventPublisher_sendSNS(t *testing.T) {
+	tests := []struct {
+		name    string
+		out     *sns.PublishBatchOutput
+		err     error
+		wantErr bool
+	}{
+		{
+			name: "ok",
+			out:  &sns.PublishBatchOutput{},
+		},
+		{
+			name: "failed entries",
+			out: &sns.PublishBatchOutput{Failed: []*sns.BatchResultErrorEntry{
+				{Id: aws.String("0"), Code: aws.String("InternalError"), Message: aws.String("internal error")},
+			}},
+			wantErr: true,
+		},
+		{
+			name:    "error",
+			err:     errors.New("denied"),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var input *sns.PublishBatchInput
+			p := newTestEventPublisher(false)
+			p.topic = "arn:aws:sns:us-east-1:123456789012:snapshots"
+			p.sns = &snsAPIMock{
+				PublishBatchWithContextFunc: func(ctx aws.Context, in *sns.PublishBatchInput, opts ...request.Option) (*sns.PublishBatchOutput, error) {
+					input = in
+					return tt.out, tt.err
+				},
+			}
+			err := p.send(context.Background(), []*Event{p.newEvent(EventRunStarted), p.newEvent(EventRunFinished)})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("send() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if aws.StringValue(input.TopicArn) != p.topic || len(input.PublishBatchRequestEntries) != 2 {
+				t.Fatalf("send() input = %v", input)
+			}
+			entry := input.PublishBatchRequestEntries[1]
+			if aws.StringValue(entry.Id) != "1" || aws.StringValue(entry.MessageAttributes["type"].StringValue) != string(EventRunFinished) {
+				t.Errorf("send() entry = %v", entry)
+			}
+		})
+	}
+}
@@ -0,0 +1,347 @@
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// DecisionStage is the part of the pipeline which made a decision.
+type DecisionStage string
+
+const (
+	// DecisionStageDescribe decides whether DescribeSnapshots returned the
+	// snapshot.
+	DecisionStageDescribe DecisionStage = "describe"
+	// DecisionStageSelector decides whether a selector such as the age or
+	// the tags matches the snapshot.
+	DecisionStageSelector DecisionStage = "selector"
+	// DecisionStageProtection decides whether a protection such as a lock
+	// keeps the snapshot.
+	DecisionStageProtection DecisionStage = "protection"
+	// DecisionStagePlan is the final decision to include the snapshot.
+	DecisionStagePlan DecisionStage = "plan"
+)
+
+// Decision records a selector or protection rule which included or excluded
+// a snapshot.
+type Decision struct {
+	Stage    DecisionStage
+	Rule     string
+	Included bool
+	Detail   string
+}
+
+// Explanation is the trace of the decisions on a snapshot. A snapshot stops
+// at the first decision which excludes it.
+type Explanation struct {
+	SnapshotID string
+	// Snapshot is nil if DescribeSnapshots did not return the snapshot.
+	Snapshot  *ec2.Snapshot
+	Included  bool
+	Decisions []*Decision
+}
+
+// Decisive returns the decision which excluded the snapshot, or the final
+// decision to include it.
+func (e *Explanation) Decisive() *Decision {
+	for _, v := range e.Decisions {
+		if !v.Included {
+			return v
+		}
+	}
+	if len(e.Decisions) == 0 {
+		return nil
+	}
+	return e.Decisions[len(e.Decisions)-1]
+}
+
+// Reason returns the decisive rule and its detail. eg. "tags: Env=prod,dev"
+func (e *Explanation) Reason() string {
+	d := e.Decisive()
+	if d == nil {
+		return ""
+	}
+	if d.Detail == "" {
+		return d.Rule
+	}
+	return d.Rule + ": " + d.Detail
+}
+
+type decisionRecorderKey struct{}
+
+// decisionRecorder collects the decisions of a run. It is held by the
+// context, so that the pipeline records nothing unless Explain asks for it.
+type decisionRecorder struct {
+	ids          []string
+	explanations map[string]*Explanation
+}
+
+func withDecisionRecorder(ctx context.Context) (context.Context, *decisionRecorder) {
+	r := &decisionRecorder{explanations: make(map[string]*Explanation)}
+	return context.WithValue(ctx, decisionRecorderKey{}, r), r
+}
+
+// decisions returns the recorder of ctx, which is nil unless Explain runs.
+func decisions(ctx context.Context) *decisionRecorder {
+	r, _ := ctx.Value(decisionRecorderKey{}).(*decisionRecorder)
+	return r
+}
+
+func (r *decisionRecorder) explanation(id string) *Explanation {
+	e, ok := r.explanations[id]
+	if !ok {
+		e = &Explanation{SnapshotID: id}
+		r.explanations[id] = e
+		r.ids = append(r.ids, id)
+	}
+	return e
+}
+
+func (r *decisionRecorder) record(snapshot *ec2.Snapshot, d *Decision) {
+	if r == nil {
+		return
+	}
+	e := r.explanation(aws.StringValue(snapshot.SnapshotId))
+	e.Decisions = append(e.Decisions, d)
+}
+
+func (r *decisionRecorder) discover(snapshots []*ec2.Snapshot, notFound []string) {
+	if r == nil {
+		return
+	}
+	for _, v := range snapshots {
+		r.explanation(aws.StringValue(v.SnapshotId)).Snapshot = v
+		r.record(v, &Decision{Stage: DecisionStageDescribe, Rule: "describe", Included: true})
+	}
+	for _, id := range notFound {
+		e := r.explanation(id)
+		e.Decisions = append(e.Decisions, &Decision{
+			Stage:  DecisionStageDescribe,
+			Rule:   "snapshot-ids",
+			Detail: "not returned by DescribeSnapshots",
+		})
+	}
+}
+
+func (r *decisionRecorder) recordPlan(plan *Plan, notRecoverable []*ec2.Snapshot) {
+	if r == nil || plan == nil {
+		return
+	}
+	for _, v := range plan.Excluded {
+		r.record(v.Snapshot, &Decision{Stage: DecisionStageProtection, Rule: string(v.Reason), Detail: v.Detail})
+	}
+	uncovered := make(map[string]struct{})
+	for _, v := range notRecoverable {
+		uncovered[aws.StringValue(v.SnapshotId)] = struct{}{}
+		r.record(v, &Decision{
+			Stage:  DecisionStageProtection,
+			Rule:   "recycle-bin",
+			Detail: "not covered by a Recycle Bin retention rule",
+		})
+	}
+	for _, v := range plan.Snapshots {
+		id := aws.StringValue(v.SnapshotId)
+		if _, ok := uncovered[id]; ok {
+			continue
+		}
+		var details []string
+		if lock, ok := plan.Unlocks[id]; ok {
+			details = append(details, "unlock "+lockDetail(lock))
+		}
+		if until, ok := plan.RecoverableUntil[id]; ok {
+			details = append(details, "recoverable until "+until.Format(time.RFC3339))
+		}
+		r.record(v, &Decision{
+			Stage:    DecisionStagePlan,
+			Rule:     string(plan.Action),
+			Included: true,
+			Detail:   strings.Join(details, ", "),
+		})
+		r.explanations[id].Included = true
+	}
+}
+
+// list returns the explanations of the described snapshots ordered by the
+// start time, followed by the snapshot ids which were not found.
+func (r *decisionRecorder) list() []*Explanation {
+	list := make([]*Explanation, 0, len(r.ids))
+	for _, id := range r.ids {
+		list = append(list, r.explanations[id])
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		a, b := list[i].Snapshot, list[j].Snapshot
+		if a == nil || b == nil {
+			return a != nil && b == nil
+		}
+		return aws.TimeValue(a.StartTime).Before(aws.TimeValue(b.StartTime))
+	})
+	return list
+}
+
+// selector is a named filterFunc, so that an explanation tells which
+// selector excluded a snapshot.
+type selector struct {
+	name   string
+	detail string
+	match  func(ctx context.Context, snapshot *ec2.Snapshot) (bool, error)
+}
+
+func newSelector(name, detail string, fn filterFunc) *selector {
+	return &selector{
+		name:   name,
+		detail: detail,
+		match: func(_ context.Context, snapshot *ec2.Snapshot) (bool, error) {
+			return fn(snapshot), nil
+		},
+	}
+}
+
+// filterFunc is only used for the selectors made by newSelector, which never
+// fail.
+func (s *selector) filterFunc() filterFunc {
+	return func(snapshot *ec2.Snapshot) bool {
+		ok, _ := s.match(context.Background(), snapshot)
+		return ok
+	}
+}
+
+func allFilterFunc(selectors []*selector) filterFunc {
+	return func(snapshot *ec2.Snapshot) bool {
+		for _, v := range selectors {
+			if !v.filterFunc()(snapshot) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// selectors returns the selectors of the client in the order they apply.
+func (c *BullDelete) selectors(ctx context.Context, tags map[string]string, age uint) []*selector {
+	var selectors []*selector
+	if age > 0 {
+		expireDate := now(ctx).Add(-time.Duration(age) * 24 * time.Hour)
+		selectors = append(selectors, newSelector("age",
+			fmt.Sprintf("created before %s (%d days)", expireDate.Format(time.RFC3339), age), expiredFilterFunc(expireDate)))
+	}
+	if c.attributeFilters != nil {
+		selectors = append(selectors, c.attributeFilters.selectors()...)
+	}
+	if c.timeRange != nil {
+		selectors = append(selectors, c.timeRange.selectors(now(ctx))...)
+	}
+	if len(tags) > 0 {
+		// The same as the filters of DescribeSnapshots, so that the snapshot
+		// ids and Explain, which describe without them, select alike.
+		selectors = append(selectors, newSelector("tags", tagsDetail(tags), tagsEC2FilterFunc(tags)))
+	}
+	if c.shared != "" {
+		selectors = append(selectors, c.sharedSelector())
+	}
+	return selectors
+}
+
+// tagsDetail returns the tags sorted by key. eg. "Env=dev,prod Team=infra"
+func tagsDetail(tags map[string]string) string {
+	var pairs []string
+	for k, v := range tags {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, " ")
+}
+
+// selectSnapshots applies the selectors in order. A snapshot excluded by a
+// selector is not passed to the following ones, so that the shared selector
+// only describes the permissions of the snapshots which the others selected.
+func (c *BullDelete) selectSnapshots(ctx context.Context, snapshots []*ec2.Snapshot, selectors []*selector) ([]*ec2.Snapshot, error) {
+	r := decisions(ctx)
+	for _, s := range selectors {
+		var matches []*ec2.Snapshot
+		for _, snapshot := range snapshots {
+			ok, err := s.match(ctx, snapshot)
+			if err != nil {
+				return nil, err
+			}
+			r.record(snapshot, &Decision{Stage: DecisionStageSelector, Rule: s.name, Included: ok, Detail: s.detail})
+			if ok {
+				matches = append(matches, snapshot)
+				continue
+			}
+			c.log().Debug("snapshot not selected", snapshotIDAttr(snapshot), slog.String("selector", s.name))
+		}
+		snapshots = matches
+	}
+	return snapshots, nil
+}
+
+// Explain describes the snapshots and builds the plan without running it,
+// and returns the decisions on every snapshot which DescribeSnapshots
+// returned, including the excluded ones.
+func (c *BullDelete) Explain(ctx context.Context) ([]*Explanation, error) {
+	if !c.hasAgeOrTags() {
+		return nil, errNoAgeOrTags
+	}
+	ctx, r := withDecisionRecorder(setNow(ctx))
+	snapshots, _, err := c.describeSnapshots(ctx, c.tags, c.age)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.explainPlan(ctx, r, snapshots); err != nil {
+		return nil, err
+	}
+	return r.list(), nil
+}
+
+// ExplainSnapshot returns the decisions on a single snapshot, which is
+// described by its id without the filters of the selectors, so that a
+// snapshot which they drop is explained too.
+func (c *BullDelete) ExplainSnapshot(ctx context.Context, id string) (*Explanation, error) {
+	if !c.hasAgeOrTags() {
+		return nil, errNoAgeOrTags
+	}
+	ctx, r := withDecisionRecorder(setNow(ctx))
+	snapshots, notFound, err := c.describeSnapshotsByIDs(ctx, []string{id})
+	if err != nil {
+		return nil, err
+	}
+	r.discover(snapshots, notFound)
+	if len(c.snapshotIDs) > 0 && !containsString(c.snapshotIDs, id) {
+		for _, v := range snapshots {
+			r.record(v, &Decision{Stage: DecisionStageSelector, Rule: "snapshot-ids", Detail: "not in the snapshot ids"})
+		}
+		return r.explanation(id), nil
+	}
+	snapshots, err = c.selectSnapshots(ctx, snapshots, c.selectors(ctx, c.tags, c.age))
+	if err != nil {
+		return nil, err
+	}
+	if err := c.explainPlan(ctx, r, snapshots); err != nil {
+		return nil, err
+	}
+	return r.explanation(id), nil
+}
+
+func (c *BullDelete) explainPlan(ctx context.Context, r *decisionRecorder, snapshots []*ec2.Snapshot) error {
+	plan, err := c.buildPlan(ctx, snapshots)
+	var nerr *NotRecoverableError
+	switch {
+	case errors.As(err, &nerr):
+		// The plan is refused as a whole, which is what the explanation
+		// is for.
+		r.recordPlan(plan, nerr.Snapshots)
+	case err != nil:
+		return err
+	default:
+		r.recordPlan(plan, nil)
+	}
+	return nil
+}
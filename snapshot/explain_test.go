@@ -0,0 +1,186 @@
+package snapshot
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func TestBullDelete_Explain(t *testing.T) {
+	old := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	svc := newLockedSnapshotsMock(map[string]string{"snap-locked": ec2.LockStateCompliance}, old.AddDate(10, 0, 0))
+	svc.DescribeSnapshotsPagesWithContextFunc = func(ctx aws.Context, input *ec2.DescribeSnapshotsInput, fn func(*ec2.DescribeSnapshotsOutput, bool) bool, opts ...request.Option) error {
+		// The snapshots which the tags filter drops are described too.
+		if len(input.Filters) > 0 {
+			t.Errorf("DescribeSnapshots() filters = %v, want none", input.Filters)
+		}
+		// The public snapshots of other owners are not described.
+		if !reflect.DeepEqual(aws.StringValueSlice(input.OwnerIds), []string{"self"}) {
+			t.Errorf("DescribeSnapshots() owners = %v, want self", aws.StringValueSlice(input.OwnerIds))
+		}
+		fn(&ec2.DescribeSnapshotsOutput{Snapshots: []*ec2.Snapshot{
+			newSnapshot("snap-untagged", old.Add(3*time.Hour), nil),
+			newSnapshot("snap-new", time.Now(), []string{"Env", "dev"}),
+			newSnapshot("snap-prod", old.Add(time.Hour), []string{"Env", "prod"}),
+			newSnapshot("snap-locked", old.Add(2*time.Hour), []string{"Env", "dev"}),
+			newSnapshot("snap-dev", old, []string{"Env", "dev"}),
+		}}, true)
+		return nil
+	}
+	c := &BullDelete{
+		age:            1,
+		tags:           map[string]string{"Env": "dev"},
+		includeManaged: true,
+		svc:            svc,
+	}
+	explanations, err := c.Explain(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	type result struct {
+		id       string
+		included bool
+		rules    []string
+		reason   string
+	}
+	var got []result
+	for _, e := range explanations {
+		r := result{id: e.SnapshotID, included: e.Included, reason: e.Reason()}
+		if r.id == "snap-new" {
+			// The age selector tells the expire date, which depends on the time of the run.
+			if !strings.HasPrefix(r.reason, "age: created before ") {
+				t.Errorf("Reason() = %v, want the age", r.reason)
+			}
+			r.reason = ""
+		}
+		for _, d := range e.Decisions {
+			r.rules = append(r.rules, string(d.Stage)+":"+d.Rule)
+		}
+		got = append(got, r)
+	}
+	want := []result{
+		{id: "snap-dev", included: true, rules: []string{"describe:describe", "selector:age", "selector:tags", "plan:delete"}, reason: "delete"},
+		{id: "snap-prod", rules: []string{"describe:describe", "selector:age", "selector:tags"}, reason: "tags: Env=dev"},
+		{id: "snap-locked", rules: []string{"describe:describe", "selector:age", "selector:tags", "protection:locked"}, reason: "locked: compliance until 2033-01-01T00:00:00Z"},
+		{id: "snap-untagged", rules: []string{"describe:describe", "selector:age", "selector:tags"}, reason: "tags: Env=dev"},
+		{id: "snap-new", rules: []string{"describe:describe", "selector:age"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Explain() got = %+v, want %+v", got, want)
+	}
+}
+
+func TestBullDelete_Explain_notFound(t *testing.T) {
+	svc := newLockedSnapshotsMock(nil, time.Time{})
+	svc.DescribeSnapshotsPagesWithContextFunc = func(ctx aws.Context, input *ec2.DescribeSnapshotsInput, fn func(*ec2.DescribeSnapshotsOutput, bool) bool, opts ...request.Option) error {
+		fn(&ec2.DescribeSnapshotsOutput{Snapshots: []*ec2.Snapshot{newSnapshot("snap-1", time.Now(), nil)}}, true)
+		return nil
+	}
+	c := &BullDelete{
+		snapshotIDs:    []string{"snap-1", "snap-gone"},
+		includeManaged: true,
+		svc:            svc,
+	}
+	explanations, err := c.Explain(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(explanations) != 2 {
+		t.Fatalf("Explain() got %d explanations, want 2", len(explanations))
+	}
+	if e := explanations[0]; e.SnapshotID != "snap-1" || !e.Included {
+		t.Errorf("Explain() got = %+v, want snap-1 included", e)
+	}
+	if e := explanations[1]; e.SnapshotID != "snap-gone" || e.Included || e.Snapshot != nil || e.Reason() != "snapshot-ids: not returned by DescribeSnapshots" {
+		t.Errorf("Explain() got = %+v, want snap-gone not found", e)
+	}
+}
+
+func TestBullDelete_ExplainSnapshot(t *testing.T) {
+	old := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	snapshots := map[string]*ec2.Snapshot{
+		"snap-dev":     newSnapshot("snap-dev", old, []string{"Env", "dev", "Team", "infra"}),
+		"snap-no-team": newSnapshot("snap-no-team", old, []string{"Env", "dev"}),
+	}
+	tests := []struct {
+		name        string
+		id          string
+		snapshotIDs []string
+		wantRules   []string
+		wantReason  string
+	}{
+		{
+			name:       "included",
+			id:         "snap-dev",
+			wantRules:  []string{"describe:describe", "selector:tags", "plan:delete"},
+			wantReason: "delete",
+		},
+		{
+			// DescribeSnapshots filters every tag, not one of them.
+			name:       "missing tag",
+			id:         "snap-no-team",
+			wantRules:  []string{"describe:describe", "selector:tags"},
+			wantReason: "tags: Env=dev Team=infra",
+		},
+		{
+			name:        "not in the snapshot ids",
+			id:          "snap-dev",
+			snapshotIDs: []string{"snap-other"},
+			wantRules:   []string{"describe:describe", "selector:snapshot-ids"},
+			wantReason:  "snapshot-ids: not in the snapshot ids",
+		},
+		{
+			name:       "not found",
+			id:         "snap-gone",
+			wantRules:  []string{"describe:snapshot-ids"},
+			wantReason: "snapshot-ids: not returned by DescribeSnapshots",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newLockedSnapshotsMock(nil, time.Time{})
+			svc.DescribeSnapshotsPagesWithContextFunc = func(ctx aws.Context, input *ec2.DescribeSnapshotsInput, fn func(*ec2.DescribeSnapshotsOutput, bool) bool, opts ...request.Option) error {
+				if len(input.Filters) != 1 || aws.StringValue(input.Filters[0].Name) != "snapshot-id" {
+					t.Errorf("DescribeSnapshots() filters = %v, want only the snapshot id", input.Filters)
+				}
+				var out []*ec2.Snapshot
+				for _, id := range aws.StringValueSlice(input.Filters[0].Values) {
+					if v, ok := snapshots[id]; ok {
+						out = append(out, v)
+					}
+				}
+				fn(&ec2.DescribeSnapshotsOutput{Snapshots: out}, true)
+				return nil
+			}
+			c := &BullDelete{
+				tags:           map[string]string{"Env": "dev", "Team": "infra"},
+				snapshotIDs:    tt.snapshotIDs,
+				includeManaged: true,
+				svc:            svc,
+			}
+			e, err := c.ExplainSnapshot(context.Background(), tt.id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var rules []string
+			for _, d := range e.Decisions {
+				rules = append(rules, string(d.Stage)+":"+d.Rule)
+			}
+			if e.SnapshotID != tt.id || !reflect.DeepEqual(rules, tt.wantRules) || e.Reason() != tt.wantReason {
+				t.Errorf("ExplainSnapshot() got = %v %v %q, want %v %q", e.SnapshotID, rules, e.Reason(), tt.wantRules, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestBullDelete_Explain_noAgeOrTags(t *testing.T) {
+	if _, err := (&BullDelete{}).Explain(context.Background()); err != errNoAgeOrTags {
+		t.Errorf("Explain() error = %v, want %v", err, errNoAgeOrTags)
+	}
+}
@@ -0,0 +1,113 @@
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+type FailureKind string
+
+const (
+	FailureKindRetryable FailureKind = "retryable"
+	FailureKindInUse     FailureKind = "in-use"
+	FailureKindNotFound  FailureKind = "not-found"
+	FailureKindDenied    FailureKind = "denied"
+	FailureKindOther     FailureKind = "other"
+)
+
+var FailureKinds = []FailureKind{
+	FailureKindRetryable,
+	FailureKindInUse,
+	FailureKindNotFound,
+	FailureKindDenied,
+	FailureKindOther,
+}
+
+const (
+	errCodeSnapshotInUse         = "InvalidSnapshot.InUse"
+	errCodeSnapshotNotFound      = "InvalidSnapshot.NotFound"
+	errCodeUnauthorizedOperation = "UnauthorizedOperation"
+	errCodeAccessDenied          = "AccessDenied"
+)
+
+// isAccessDenied reports whether err is a missing IAM permission.
+func isAccessDenied(err error) bool {
+	var aerr awserr.Error
+	if !errors.As(err, &aerr) {
+		return false
+	}
+	switch aerr.Code() {
+	case errCodeUnauthorizedOperation, errCodeAccessDenied:
+		return true
+	}
+	return false
+}
+
+func ClassifyError(err error) FailureKind {
+	if err == nil {
+		return ""
+	}
+	var werr *copyWaitError
+	if errors.As(err, &werr) && werr.retryable() {
+		return FailureKindRetryable
+	}
+	var aerr awserr.Error
+	if errors.As(err, &aerr) {
+		switch aerr.Code() {
+		case errCodeSnapshotInUse:
+			return FailureKindInUse
+		case errCodeSnapshotNotFound:
+			return FailureKindNotFound
+		case errCodeUnauthorizedOperation, errCodeAccessDenied:
+			return FailureKindDenied
+		}
+		// The SDK helpers take any error which is not an awserr.Error as
+		// retryable, so they are given the unwrapped error.
+		if request.IsErrorThrottle(aerr) || request.IsErrorRetryable(aerr) {
+			return FailureKindRetryable
+		}
+	}
+	return FailureKindOther
+}
+
+// Retryable reports whether a failure of this kind is worth another attempt.
+// A not-found snapshot is already gone, so it is never retried.
+func (k FailureKind) Retryable() bool {
+	return k == FailureKindRetryable
+}
+
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+}
+
+var defaultRetryPolicies = map[FailureKind]RetryPolicy{
+	FailureKindRetryable: {MaxAttempts: 5, BaseDelay: time.Second},
+	FailureKindInUse:     {MaxAttempts: 1},
+	FailureKindNotFound:  {MaxAttempts: 1},
+	FailureKindDenied:    {MaxAttempts: 1},
+	FailureKindOther:     {MaxAttempts: 1},
+}
+
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	// eg. 1s, 2s, 4s, 8s ...
+	return p.BaseDelay << (attempt - 1)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
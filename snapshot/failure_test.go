@@ -0,0 +1,140 @@
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+func TestClassifyError(t *testing.T) {
+	type args struct {
+		err error
+	}
+	tests := []struct {
+		name string
+		args args
+		want FailureKind
+	}{
+		{
+			name: "in use",
+			args: args{
+				err: awserr.New("InvalidSnapshot.InUse", "in use by ami-0123", nil),
+			},
+			want: FailureKindInUse,
+		},
+		{
+			name: "not found",
+			args: args{
+				err: awserr.New("InvalidSnapshot.NotFound", "does not exist", nil),
+			},
+			want: FailureKindNotFound,
+		},
+		{
+			name: "unauthorized",
+			args: args{
+				err: awserr.New("UnauthorizedOperation", "not authorized", nil),
+			},
+			want: FailureKindDenied,
+		},
+		{
+			name: "access denied",
+			args: args{
+				err: awserr.New("AccessDenied", "access denied", nil),
+			},
+			want: FailureKindDenied,
+		},
+		{
+			name: "throttling",
+			args: args{
+				err: awserr.New("RequestLimitExceeded", "rate exceeded", nil),
+			},
+			want: FailureKindRetryable,
+		},
+		{
+			name: "other aws error",
+			args: args{
+				err: awserr.New("IncorrectState", "incorrect state", nil),
+			},
+			want: FailureKindOther,
+		},
+		{
+			name: "wrapped throttling",
+			args: args{
+				err: fmt.Errorf("failed to copy: %w", awserr.New("RequestLimitExceeded", "rate exceeded", nil)),
+			},
+			want: FailureKindRetryable,
+		},
+		{
+			name: "wrapped other aws error",
+			args: args{
+				err: fmt.Errorf("failed to copy: %w", awserr.New("InvalidParameterValue", "invalid value", nil)),
+			},
+			want: FailureKindOther,
+		},
+		{
+			name: "copy wait timed out",
+			args: args{
+				err: &copyWaitError{copySnapshotID: "snap-copy", err: awserr.New("ResourceNotReady", "exceeded wait attempts", nil)},
+			},
+			want: FailureKindRetryable,
+		},
+		{
+			name: "copy wait interrupted",
+			args: args{
+				err: &copyWaitError{copySnapshotID: "snap-copy", err: awserr.New("RequestCanceled", "waiter context canceled", context.Canceled)},
+			},
+			want: FailureKindRetryable,
+		},
+		{
+			name: "copy in the error state",
+			args: args{
+				err: &copyWaitError{copySnapshotID: "snap-copy", err: awserr.New("ResourceNotReady", "failed waiting for successful resource state", nil)},
+			},
+			want: FailureKindOther,
+		},
+		{
+			name: "non aws error",
+			args: args{
+				err: errors.New("foo"),
+			},
+			want: FailureKindOther,
+		},
+		{
+			name: "nil",
+			args: args{
+				err: nil,
+			},
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClassifyError(tt.args.err); got != tt.want {
+				t.Errorf("ClassifyError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFailureKind_Retryable(t *testing.T) {
+	for _, kind := range FailureKinds {
+		want := kind == FailureKindRetryable
+		if got := kind.Retryable(); got != want {
+			t.Errorf("%s.Retryable() = %v, want %v", kind, got, want)
+		}
+	}
+}
+
+func TestRetryPolicy_backoff(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 5, BaseDelay: time.Second}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second}
+	for i, v := range want {
+		if got := p.backoff(i + 1); got != v {
+			t.Errorf("backoff(%d) = %v, want %v", i+1, got, v)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package snapshot
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+var errMinSizeGreaterThanMaxSize = errors.New("min-size must not be greater than max-size")
+
+type attributeFilters struct {
+	minSize     int64
+	maxSize     int64
+	encrypted   *bool
+	kmsKeyID    string
+	storageTier string
+	state       string
+	volumeID    string
+	description *regexp.Regexp
+}
+
+func (cfg *BulkDeleteConfig) attributeFilters() (*attributeFilters, error) {
+	f := &attributeFilters{
+		minSize:     cfg.MinSize,
+		maxSize:     cfg.MaxSize,
+		kmsKeyID:    cfg.KmsKeyID,
+		storageTier: cfg.StorageTier,
+		state:       cfg.State,
+		volumeID:    cfg.VolumeID,
+	}
+	if f.maxSize > 0 && f.minSize > f.maxSize {
+		return nil, errMinSizeGreaterThanMaxSize
+	}
+	if cfg.Encrypted != "" {
+		encrypted, err := strconv.ParseBool(cfg.Encrypted)
+		if err != nil {
+			return nil, fmt.Errorf("invalid encrypted: %s", cfg.Encrypted)
+		}
+		f.encrypted = &encrypted
+	}
+	switch f.storageTier {
+	case "", ec2.StorageTierStandard, ec2.StorageTierArchive:
+	default:
+		return nil, fmt.Errorf("invalid storage tier: %s", f.storageTier)
+	}
+	switch f.state {
+	case "", ec2.SnapshotStateCompleted, ec2.SnapshotStateError, ec2.SnapshotStatePending:
+	default:
+		return nil, fmt.Errorf("invalid state: %s", f.state)
+	}
+	if cfg.DescriptionRegex != "" {
+		re, err := regexp.Compile(cfg.DescriptionRegex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid description regex: %w", err)
+		}
+		f.description = re
+	}
+	return f, nil
+}
+
+func (f *attributeFilters) empty() bool {
+	return f.minSize == 0 && f.maxSize == 0 && f.encrypted == nil && f.kmsKeyID == "" &&
+		f.storageTier == "" && f.state == "" && f.volumeID == "" && f.description == nil
+}
+
+// ec2Filters returns the filters which DescribeSnapshots supports. Sizes, the
+// KMS key and the description regex are only filtered by filterFunc.
+func (f *attributeFilters) ec2Filters() []*ec2.Filter {
+	var filters []*ec2.Filter
+	add := func(name, value string) {
+		filters = append(filters, &ec2.Filter{
+			Name:   aws.String(name),
+			Values: []*string{aws.String(value)},
+		})
+	}
+	if f.encrypted != nil {
+		add("encrypted", strconv.FormatBool(*f.encrypted))
+	}
+	if f.storageTier != "" {
+		add("storage-tier", f.storageTier)
+	}
+	if f.state != "" {
+		add("status", f.state)
+	}
+	if f.volumeID != "" {
+		add("volume-id", f.volumeID)
+	}
+	return filters
+}
+
+func (f *attributeFilters) filterFunc() filterFunc {
+	return allFilterFunc(f.selectors())
+}
+
+// selectors returns a selector per attribute, so that an explanation tells
+// which attribute excluded a snapshot.
+func (f *attributeFilters) selectors() []*selector {
+	var selectors []*selector
+	if f.minSize > 0 {
+		selectors = append(selectors, newSelector("min-size", fmt.Sprintf("at least %d GiB", f.minSize), func(snapshot *ec2.Snapshot) bool {
+			return aws.Int64Value(snapshot.VolumeSize) >= f.minSize
+		}))
+	}
+	if f.maxSize > 0 {
+		selectors = append(selectors, newSelector("max-size", fmt.Sprintf("at most %d GiB", f.maxSize), func(snapshot *ec2.Snapshot) bool {
+			return aws.Int64Value(snapshot.VolumeSize) <= f.maxSize
+		}))
+	}
+	if f.encrypted != nil {
+		selectors = append(selectors, newSelector("encrypted", strconv.FormatBool(*f.encrypted), func(snapshot *ec2.Snapshot) bool {
+			return aws.BoolValue(snapshot.Encrypted) == *f.encrypted
+		}))
+	}
+	if f.kmsKeyID != "" {
+		selectors = append(selectors, newSelector("kms-key-id", f.kmsKeyID, func(snapshot *ec2.Snapshot) bool {
+			return matchKmsKeyID(aws.StringValue(snapshot.KmsKeyId), f.kmsKeyID)
+		}))
+	}
+	if f.storageTier != "" {
+		selectors = append(selectors, newSelector("storage-tier", f.storageTier, func(snapshot *ec2.Snapshot) bool {
+			return aws.StringValue(snapshot.StorageTier) == f.storageTier
+		}))
+	}
+	if f.state != "" {
+		selectors = append(selectors, newSelector("state", f.state, func(snapshot *ec2.Snapshot) bool {
+			return aws.StringValue(snapshot.State) == f.state
+		}))
+	}
+	if f.volumeID != "" {
+		selectors = append(selectors, newSelector("volume-id", f.volumeID, func(snapshot *ec2.Snapshot) bool {
+			return aws.StringValue(snapshot.VolumeId) == f.volumeID
+		}))
+	}
+	if f.description != nil {
+		selectors = append(selectors, newSelector("description-regex", f.description.String(), func(snapshot *ec2.Snapshot) bool {
+			return f.description.MatchString(aws.StringValue(snapshot.Description))
+		}))
+	}
+	return selectors
+}
+
+// matchKmsKeyID matches the key ARN of a snapshot with a key ARN or a key id.
+func matchKmsKeyID(arn, keyID string) bool {
+	if arn == keyID {
+		return true
+	}
+	// eg. arn:aws:kms:us-east-1:123456789012:key/1234abcd-12ab-34cd-56ef-1234567890ab
+	return len(arn) > len(keyID) && arn[len(arn)-len(keyID)-1:] == "/"+keyID
+}
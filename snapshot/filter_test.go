@@ -0,0 +1,91 @@
+package snapshot
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func TestConfig_attributeFilters(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     *BulkDeleteConfig
+		wantErr bool
+	}{
+		{name: "empty", cfg: &BulkDeleteConfig{}},
+		{name: "valid", cfg: &BulkDeleteConfig{MinSize: 8, MaxSize: 100, Encrypted: "false", StorageTier: "archive", State: "error", DescriptionRegex: "^Created by CreateImage"}},
+		{name: "min-size greater than max-size", cfg: &BulkDeleteConfig{MinSize: 100, MaxSize: 8}, wantErr: true},
+		{name: "invalid encrypted", cfg: &BulkDeleteConfig{Encrypted: "yes please"}, wantErr: true},
+		{name: "invalid storage tier", cfg: &BulkDeleteConfig{StorageTier: "glacier"}, wantErr: true},
+		{name: "invalid state", cfg: &BulkDeleteConfig{State: "deleted"}, wantErr: true},
+		{name: "invalid description regex", cfg: &BulkDeleteConfig{DescriptionRegex: "("}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.cfg.attributeFilters()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("attributeFilters() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_attributeFilters_ec2Filters(t *testing.T) {
+	f, err := (&BulkDeleteConfig{MinSize: 8, Encrypted: "true", State: "error", VolumeID: "vol-1"}).attributeFilters()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*ec2.Filter{
+		{Name: aws.String("encrypted"), Values: aws.StringSlice([]string{"true"})},
+		{Name: aws.String("status"), Values: aws.StringSlice([]string{"error"})},
+		{Name: aws.String("volume-id"), Values: aws.StringSlice([]string{"vol-1"})},
+	}
+	if got := f.ec2Filters(); !reflect.DeepEqual(got, want) {
+		t.Errorf("ec2Filters() = %v, want %v", got, want)
+	}
+}
+
+func Test_attributeFilters_filterFunc(t *testing.T) {
+	startTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := newSnapshot("snap-1", startTime, nil)
+	s.VolumeSize = aws.Int64(50)
+	s.Encrypted = aws.Bool(true)
+	s.KmsKeyId = aws.String("arn:aws:kms:us-east-1:123456789012:key/1234abcd")
+	s.StorageTier = aws.String(ec2.StorageTierStandard)
+	s.State = aws.String(ec2.SnapshotStateError)
+	s.VolumeId = aws.String("vol-1")
+	s.Description = aws.String("Created by CreateImage(i-1) for ami-1")
+	tests := []struct {
+		name string
+		cfg  *BulkDeleteConfig
+		want bool
+	}{
+		{name: "size in range", cfg: &BulkDeleteConfig{MinSize: 50, MaxSize: 50}, want: true},
+		{name: "smaller than min-size", cfg: &BulkDeleteConfig{MinSize: 51}, want: false},
+		{name: "larger than max-size", cfg: &BulkDeleteConfig{MaxSize: 49}, want: false},
+		{name: "encrypted", cfg: &BulkDeleteConfig{Encrypted: "true"}, want: true},
+		{name: "not encrypted", cfg: &BulkDeleteConfig{Encrypted: "false"}, want: false},
+		{name: "kms key id", cfg: &BulkDeleteConfig{KmsKeyID: "1234abcd"}, want: true},
+		{name: "kms key arn", cfg: &BulkDeleteConfig{KmsKeyID: "arn:aws:kms:us-east-1:123456789012:key/1234abcd"}, want: true},
+		{name: "other kms key", cfg: &BulkDeleteConfig{KmsKeyID: "34abcd"}, want: false},
+		{name: "storage tier", cfg: &BulkDeleteConfig{StorageTier: "archive"}, want: false},
+		{name: "state", cfg: &BulkDeleteConfig{State: "error"}, want: true},
+		{name: "volume id", cfg: &BulkDeleteConfig{VolumeID: "vol-2"}, want: false},
+		{name: "description regex", cfg: &BulkDeleteConfig{DescriptionRegex: "^Created by CreateImage"}, want: true},
+		{name: "description regex does not match", cfg: &BulkDeleteConfig{DescriptionRegex: "^Copied"}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := tt.cfg.attributeFilters()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := f.filterFunc()(s); got != tt.want {
+				t.Errorf("filterFunc() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
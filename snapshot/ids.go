@@ -0,0 +1,80 @@
+package snapshot
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+const snapshotIDsChunkSize = 200
+
+// ReadSnapshotIDs reads snapshot ids separated by newlines, spaces or commas.
+// Duplicated ids are read once.
+func ReadSnapshotIDs(r io.Reader) ([]string, error) {
+	var ids []string
+	seen := make(map[string]struct{})
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.FieldsFunc(scanner.Text(), func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		})
+		for _, id := range fields {
+			if !strings.HasPrefix(id, "snap-") {
+				return nil, fmt.Errorf("invalid snapshot id: %s", id)
+			}
+			if _, ok := seen[id]; ok {
+				continue
+			}
+			seen[id] = struct{}{}
+			ids = append(ids, id)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// describeSnapshotsByIDs uses the snapshot-id filter instead of SnapshotIds,
+// because DescribeSnapshots fails as a whole if one of SnapshotIds is not found.
+// Tags are not filtered here, so that a snapshot without the tags is not
+// reported as not found.
+func (c *BullDelete) describeSnapshotsByIDs(ctx context.Context, ids []string) ([]*ec2.Snapshot, []string, error) {
+	var snapshots []*ec2.Snapshot
+	for start := 0; start < len(ids); start += snapshotIDsChunkSize {
+		end := start + snapshotIDsChunkSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		input := &ec2.DescribeSnapshotsInput{
+			Filters: []*ec2.Filter{{
+				Name:   aws.String("snapshot-id"),
+				Values: aws.StringSlice(ids[start:end]),
+			}},
+			OwnerIds: c.ownerIDs(),
+		}
+		err := c.svc.DescribeSnapshotsPagesWithContext(ctx, input, func(out *ec2.DescribeSnapshotsOutput, lastPage bool) bool {
+			snapshots = append(snapshots, out.Snapshots...)
+			return !lastPage
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	found := make(map[string]struct{})
+	for _, v := range snapshots {
+		found[aws.StringValue(v.SnapshotId)] = struct{}{}
+	}
+	var notFound []string
+	for _, id := range ids {
+		if _, ok := found[id]; !ok {
+			notFound = append(notFound, id)
+		}
+	}
+	return snapshots, notFound, nil
+}
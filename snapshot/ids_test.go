@@ -0,0 +1,160 @@
+package snapshot
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func TestReadSnapshotIDs(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:  "lines, spaces and commas",
+			input: "snap-1\nsnap-2 snap-3,snap-4\n\n",
+			want:  []string{"snap-1", "snap-2", "snap-3", "snap-4"},
+		},
+		{
+			name:  "duplicated",
+			input: "snap-1\nsnap-1\n",
+			want:  []string{"snap-1"},
+		},
+		{
+			name:    "invalid",
+			input:   "vol-1\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadSnapshotIDs(strings.NewReader(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ReadSnapshotIDs() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadSnapshotIDs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBullDelete_describeSnapshots_snapshotIDs(t *testing.T) {
+	now := time.Date(2023, 1, 10, 0, 0, 0, 0, time.UTC)
+	var ids []string
+	existing := make(map[string]*ec2.Snapshot)
+	for i := 0; i < snapshotIDsChunkSize+10; i++ {
+		id := fmt.Sprintf("snap-%d", i)
+		ids = append(ids, id)
+		if i%100 == 0 {
+			continue
+		}
+		tags := []string{"Env", "dev"}
+		if i%2 == 0 {
+			tags = []string{"Env", "prod"}
+		}
+		existing[id] = newSnapshot(id, now.Add(-48*time.Hour), tags)
+	}
+	var chunks []int
+	c := &BullDelete{
+		tags:        map[string]string{"Env": "prod"},
+		snapshotIDs: ids,
+		svc: &ec2SnapshotAPIMock{
+			DescribeSnapshotsPagesWithContextFunc: func(ctx aws.Context, input *ec2.DescribeSnapshotsInput, fn func(*ec2.DescribeSnapshotsOutput, bool) bool, opts ...request.Option) error {
+				if len(input.Filters) != 1 || aws.StringValue(input.Filters[0].Name) != "snapshot-id" {
+					t.Errorf("describeSnapshots() filters = %v", input.Filters)
+				}
+				chunks = append(chunks, len(input.Filters[0].Values))
+				out := &ec2.DescribeSnapshotsOutput{}
+				for _, id := range input.Filters[0].Values {
+					if s, ok := existing[aws.StringValue(id)]; ok {
+						out.Snapshots = append(out.Snapshots, s)
+					}
+				}
+				fn(out, true)
+				return nil
+			},
+		},
+	}
+	snapshots, notFound, err := c.describeSnapshots(mockNow(context.Background(), now), c.tags, 1)
+	if err != nil {
+		t.Fatalf("describeSnapshots() error = %v", err)
+	}
+	if want := []int{snapshotIDsChunkSize, 10}; !reflect.DeepEqual(chunks, want) {
+		t.Errorf("describeSnapshots() chunks = %v, want %v", chunks, want)
+	}
+	if want := []string{"snap-0", "snap-100", "snap-200"}; !reflect.DeepEqual(notFound, want) {
+		t.Errorf("describeSnapshots() notFound = %v, want %v", notFound, want)
+	}
+	for _, v := range snapshots {
+		if tagValue(v, "Env") != "prod" {
+			t.Errorf("describeSnapshots() = %s, want only Env=prod", aws.StringValue(v.SnapshotId))
+		}
+	}
+	if got, want := len(snapshots), 102; got != want {
+		t.Errorf("describeSnapshots() = %d snapshots, want %d", got, want)
+	}
+}
+
+func TestBullDelete_describeSnapshots_snapshotIDsTags(t *testing.T) {
+	now := time.Date(2023, 1, 10, 0, 0, 0, 0, time.UTC)
+	existing := []*ec2.Snapshot{
+		newSnapshot("snap-both", now.Add(-48*time.Hour), []string{"Env", "prod", "Team", "infra"}),
+		newSnapshot("snap-env", now.Add(-47*time.Hour), []string{"Env", "prod"}),
+		newSnapshot("snap-team", now.Add(-46*time.Hour), []string{"Team", "infra"}),
+		newSnapshot("snap-dev", now.Add(-45*time.Hour), []string{"Env", "dev", "Team", "infra"}),
+	}
+	tests := []struct {
+		name string
+		tags map[string]string
+		want []string
+	}{
+		{
+			// Every tag must match, like the filters of DescribeSnapshots.
+			name: "two keys",
+			tags: map[string]string{"Env": "prod", "Team": "infra"},
+			want: []string{"snap-both"},
+		},
+		{
+			name: "multiple values",
+			tags: map[string]string{"Env": "prod,dev"},
+			want: []string{"snap-both", "snap-env", "snap-dev"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &BullDelete{
+				tags:        tt.tags,
+				snapshotIDs: []string{"snap-both", "snap-env", "snap-team", "snap-dev"},
+				svc: &ec2SnapshotAPIMock{
+					DescribeSnapshotsPagesWithContextFunc: func(ctx aws.Context, input *ec2.DescribeSnapshotsInput, fn func(*ec2.DescribeSnapshotsOutput, bool) bool, opts ...request.Option) error {
+						fn(&ec2.DescribeSnapshotsOutput{Snapshots: existing}, true)
+						return nil
+					},
+				},
+			}
+			snapshots, _, err := c.describeSnapshots(mockNow(context.Background(), now), c.tags, 1)
+			if err != nil {
+				t.Fatalf("describeSnapshots() error = %v", err)
+			}
+			var got []string
+			for _, v := range snapshots {
+				got = append(got, aws.StringValue(v.SnapshotId))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("describeSnapshots() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package snapshot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+type LaunchTemplateVersions string
+
+const (
+	// LaunchTemplateVersionsLatest scans the $Latest and $Default versions.
+	LaunchTemplateVersionsLatest LaunchTemplateVersions = "latest"
+	LaunchTemplateVersionsAll    LaunchTemplateVersions = "all"
+)
+
+func (v LaunchTemplateVersions) validate() error {
+	switch v {
+	case "", LaunchTemplateVersionsLatest, LaunchTemplateVersionsAll:
+		return nil
+	}
+	return fmt.Errorf("invalid launch template versions: %s", v)
+}
+
+// launchTemplateReferences returns the launch template versions referencing
+// each snapshot, keyed by snapshot id.
+func (c *BullDelete) launchTemplateReferences(ctx context.Context) (map[string][]string, error) {
+	refs := make(map[string][]string)
+	fn := func(out *ec2.DescribeLaunchTemplateVersionsOutput, lastPage bool) bool {
+		for _, v := range out.LaunchTemplateVersions {
+			if v.LaunchTemplateData == nil {
+				continue
+			}
+			// eg. lt-0123456789abcdef0 (web):3
+			ref := fmt.Sprintf("%s (%s):%d", aws.StringValue(v.LaunchTemplateId),
+				aws.StringValue(v.LaunchTemplateName), aws.Int64Value(v.VersionNumber))
+			for _, m := range v.LaunchTemplateData.BlockDeviceMappings {
+				if m.Ebs == nil || m.Ebs.SnapshotId == nil {
+					continue
+				}
+				id := aws.StringValue(m.Ebs.SnapshotId)
+				if !containsString(refs[id], ref) {
+					refs[id] = append(refs[id], ref)
+				}
+			}
+		}
+		return !lastPage
+	}
+	if c.launchTemplateVersions != LaunchTemplateVersionsAll {
+		// Without a launch template id, only $Latest and $Default can be
+		// described across all launch templates.
+		err := c.svc.DescribeLaunchTemplateVersionsPagesWithContext(ctx, &ec2.DescribeLaunchTemplateVersionsInput{
+			Versions: aws.StringSlice([]string{"$Latest", "$Default"}),
+		}, fn)
+		if err != nil {
+			return nil, err
+		}
+		return refs, nil
+	}
+	var ids []*string
+	err := c.svc.DescribeLaunchTemplatesPagesWithContext(ctx, &ec2.DescribeLaunchTemplatesInput{},
+		func(out *ec2.DescribeLaunchTemplatesOutput, lastPage bool) bool {
+			for _, v := range out.LaunchTemplates {
+				ids = append(ids, v.LaunchTemplateId)
+			}
+			return !lastPage
+		})
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		err := c.svc.DescribeLaunchTemplateVersionsPagesWithContext(ctx, &ec2.DescribeLaunchTemplateVersionsInput{
+			LaunchTemplateId: id,
+		}, fn)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return refs, nil
+}
+
+func (c *BullDelete) excludeLaunchTemplateReferenced(ctx context.Context, plan *Plan, snapshots []*ec2.Snapshot) ([]*ec2.Snapshot, error) {
+	if len(snapshots) == 0 {
+		return snapshots, nil
+	}
+	refs, err := c.launchTemplateReferences(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe launch template versions: %w", err)
+	}
+	var unreferenced []*ec2.Snapshot
+	for _, v := range snapshots {
+		ref, ok := refs[aws.StringValue(v.SnapshotId)]
+		if !ok {
+			unreferenced = append(unreferenced, v)
+			continue
+		}
+		plan.Excluded = append(plan.Excluded, &ExcludedSnapshot{
+			Snapshot: v,
+			Reason:   ExclusionReasonLaunchTemplate,
+			Detail:   strings.Join(ref, ", "),
+		})
+	}
+	return unreferenced, nil
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
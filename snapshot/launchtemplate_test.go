@@ -0,0 +1,119 @@
+package snapshot
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func newLaunchTemplateVersion(id string, version int64, snapshotIDs ...string) *ec2.LaunchTemplateVersion {
+	var mappings []*ec2.LaunchTemplateBlockDeviceMapping
+	for _, v := range snapshotIDs {
+		mappings = append(mappings, &ec2.LaunchTemplateBlockDeviceMapping{
+			Ebs: &ec2.LaunchTemplateEbsBlockDevice{SnapshotId: aws.String(v)},
+		})
+	}
+	mappings = append(mappings, &ec2.LaunchTemplateBlockDeviceMapping{VirtualName: aws.String("ephemeral0")})
+	return &ec2.LaunchTemplateVersion{
+		LaunchTemplateId:   aws.String(id),
+		LaunchTemplateName: aws.String("web"),
+		VersionNumber:      aws.Int64(version),
+		LaunchTemplateData: &ec2.ResponseLaunchTemplateData{BlockDeviceMappings: mappings},
+	}
+}
+
+func TestBullDelete_launchTemplateReferences(t *testing.T) {
+	versions := map[string][]*ec2.LaunchTemplateVersion{
+		"$Latest": {newLaunchTemplateVersion("lt-1", 2, "snap-2")},
+		"lt-1": {
+			newLaunchTemplateVersion("lt-1", 1, "snap-1"),
+			newLaunchTemplateVersion("lt-1", 2, "snap-2"),
+		},
+	}
+	svc := &ec2SnapshotAPIMock{
+		DescribeLaunchTemplatesPagesWithContextFunc: func(ctx aws.Context, input *ec2.DescribeLaunchTemplatesInput, fn func(*ec2.DescribeLaunchTemplatesOutput, bool) bool, opts ...request.Option) error {
+			fn(&ec2.DescribeLaunchTemplatesOutput{
+				LaunchTemplates: []*ec2.LaunchTemplate{{LaunchTemplateId: aws.String("lt-1")}},
+			}, true)
+			return nil
+		},
+		DescribeLaunchTemplateVersionsPagesWithContextFunc: func(ctx aws.Context, input *ec2.DescribeLaunchTemplateVersionsInput, fn func(*ec2.DescribeLaunchTemplateVersionsOutput, bool) bool, opts ...request.Option) error {
+			key := aws.StringValue(input.LaunchTemplateId)
+			if key == "" {
+				key = aws.StringValue(input.Versions[0])
+			}
+			fn(&ec2.DescribeLaunchTemplateVersionsOutput{LaunchTemplateVersions: versions[key]}, true)
+			return nil
+		},
+	}
+	tests := []struct {
+		name     string
+		versions LaunchTemplateVersions
+		want     map[string][]string
+	}{
+		{
+			name: "latest and default by default",
+			want: map[string][]string{
+				"snap-2": {"lt-1 (web):2"},
+			},
+		},
+		{
+			name:     "all",
+			versions: LaunchTemplateVersionsAll,
+			want: map[string][]string{
+				"snap-1": {"lt-1 (web):1"},
+				"snap-2": {"lt-1 (web):2"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &BullDelete{launchTemplateVersions: tt.versions, svc: svc}
+			got, err := c.launchTemplateReferences(context.Background())
+			if err != nil {
+				t.Fatalf("launchTemplateReferences() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("launchTemplateReferences() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBullDelete_excludeLaunchTemplateReferenced(t *testing.T) {
+	startTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := &BullDelete{
+		svc: &ec2SnapshotAPIMock{
+			DescribeLaunchTemplateVersionsPagesWithContextFunc: func(ctx aws.Context, input *ec2.DescribeLaunchTemplateVersionsInput, fn func(*ec2.DescribeLaunchTemplateVersionsOutput, bool) bool, opts ...request.Option) error {
+				fn(&ec2.DescribeLaunchTemplateVersionsOutput{
+					LaunchTemplateVersions: []*ec2.LaunchTemplateVersion{newLaunchTemplateVersion("lt-1", 3, "snap-2")},
+				}, true)
+				return nil
+			},
+		},
+	}
+	plan := &Plan{}
+	got, err := c.excludeLaunchTemplateReferenced(context.Background(), plan, []*ec2.Snapshot{
+		newSnapshot("snap-1", startTime, nil),
+		newSnapshot("snap-2", startTime, nil),
+	})
+	if err != nil {
+		t.Fatalf("excludeLaunchTemplateReferenced() error = %v", err)
+	}
+	if len(got) != 1 || aws.StringValue(got[0].SnapshotId) != "snap-1" {
+		t.Errorf("excludeLaunchTemplateReferenced() = %v", got)
+	}
+	want := []*ExcludedSnapshot{{
+		Snapshot: newSnapshot("snap-2", startTime, nil),
+		Reason:   ExclusionReasonLaunchTemplate,
+		Detail:   "lt-1 (web):3",
+	}}
+	if !reflect.DeepEqual(plan.Excluded, want) {
+		t.Errorf("excludeLaunchTemplateReferenced() excluded = %v, want %v", plan.Excluded, want)
+	}
+}
@@ -0,0 +1,68 @@
+package snapshot
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+const lockedSnapshotsChunkSize = 200
+
+func (c *BullDelete) lockedSnapshots(ctx context.Context, snapshots []*ec2.Snapshot) (map[string]*ec2.LockedSnapshotsInfo, error) {
+	locks := make(map[string]*ec2.LockedSnapshotsInfo)
+	for start := 0; start < len(snapshots); start += lockedSnapshotsChunkSize {
+		end := start + lockedSnapshotsChunkSize
+		if end > len(snapshots) {
+			end = len(snapshots)
+		}
+		var ids []*string
+		for _, v := range snapshots[start:end] {
+			ids = append(ids, v.SnapshotId)
+		}
+		input := &ec2.DescribeLockedSnapshotsInput{SnapshotIds: ids}
+		for {
+			out, err := c.svc.DescribeLockedSnapshotsWithContext(ctx, input)
+			if err != nil {
+				return nil, err
+			}
+			for _, v := range out.Snapshots {
+				locks[aws.StringValue(v.SnapshotId)] = v
+			}
+			if aws.StringValue(out.NextToken) == "" {
+				break
+			}
+			input.NextToken = out.NextToken
+		}
+	}
+	return locks, nil
+}
+
+// isLocked reports whether the lock prevents the snapshot from being deleted.
+// An expired lock does not.
+func isLocked(lock *ec2.LockedSnapshotsInfo) bool {
+	switch aws.StringValue(lock.LockState) {
+	case ec2.LockStateCompliance, ec2.LockStateComplianceCooloff, ec2.LockStateGovernance:
+		return true
+	}
+	return false
+}
+
+func lockDetail(lock *ec2.LockedSnapshotsInfo) string {
+	if lock.LockExpiresOn == nil {
+		return aws.StringValue(lock.LockState)
+	}
+	return fmt.Sprintf("%s until %s", aws.StringValue(lock.LockState), lock.LockExpiresOn.Format(time.RFC3339))
+}
+
+func (c *BullDelete) unlockSnapshot(ctx context.Context, snapshot *ec2.Snapshot) error {
+	_, err := c.svc.UnlockSnapshotWithContext(ctx, &ec2.UnlockSnapshotInput{
+		SnapshotId: snapshot.SnapshotId,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to unlock: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package snapshot
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func newLockedSnapshotsMock(states map[string]string, expiresOn time.Time) *ec2SnapshotAPIMock {
+	return &ec2SnapshotAPIMock{
+		DescribeLockedSnapshotsWithContextFunc: func(ctx aws.Context, input *ec2.DescribeLockedSnapshotsInput, opts ...request.Option) (*ec2.DescribeLockedSnapshotsOutput, error) {
+			out := &ec2.DescribeLockedSnapshotsOutput{}
+			for _, id := range input.SnapshotIds {
+				if state, ok := states[aws.StringValue(id)]; ok {
+					out.Snapshots = append(out.Snapshots, &ec2.LockedSnapshotsInfo{
+						SnapshotId:    id,
+						LockState:     aws.String(state),
+						LockExpiresOn: aws.Time(expiresOn),
+					})
+				}
+			}
+			return out, nil
+		},
+		DescribeLaunchTemplateVersionsPagesWithContextFunc: func(ctx aws.Context, input *ec2.DescribeLaunchTemplateVersionsInput, fn func(*ec2.DescribeLaunchTemplateVersionsOutput, bool) bool, opts ...request.Option) error {
+			return nil
+		},
+	}
+}
+
+func TestBullDelete_buildPlan_locked(t *testing.T) {
+	startTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	expiresOn := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	states := map[string]string{
+		"snap-compliance": ec2.LockStateCompliance,
+		"snap-cooloff":    ec2.LockStateComplianceCooloff,
+		"snap-governance": ec2.LockStateGovernance,
+		"snap-expired":    ec2.LockStateExpired,
+	}
+	snapshots := []*ec2.Snapshot{
+		newSnapshot("snap-unlocked", startTime, nil),
+		newSnapshot("snap-compliance", startTime, nil),
+		newSnapshot("snap-cooloff", startTime, nil),
+		newSnapshot("snap-governance", startTime, nil),
+		newSnapshot("snap-expired", startTime, nil),
+	}
+	tests := []struct {
+		name             string
+		unlockGovernance bool
+		wantSnapshots    []string
+		wantExcluded     []string
+		wantUnlocks      []string
+	}{
+		{
+			name:          "exclude locked",
+			wantSnapshots: []string{"snap-unlocked", "snap-expired"},
+			wantExcluded:  []string{"snap-compliance", "snap-cooloff", "snap-governance"},
+		},
+		{
+			name:             "unlock governance",
+			unlockGovernance: true,
+			wantSnapshots:    []string{"snap-unlocked", "snap-governance", "snap-expired"},
+			wantExcluded:     []string{"snap-compliance", "snap-cooloff"},
+			wantUnlocks:      []string{"snap-governance"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &BullDelete{
+				unlockGovernance: tt.unlockGovernance,
+				svc:              newLockedSnapshotsMock(states, expiresOn),
+			}
+			plan, err := c.buildPlan(context.Background(), snapshots)
+			if err != nil {
+				t.Fatalf("buildPlan() error = %v", err)
+			}
+			var gotSnapshots, gotExcluded, gotUnlocks []string
+			for _, v := range plan.Snapshots {
+				gotSnapshots = append(gotSnapshots, aws.StringValue(v.SnapshotId))
+			}
+			for _, v := range plan.Excluded {
+				gotExcluded = append(gotExcluded, aws.StringValue(v.Snapshot.SnapshotId))
+				if v.Reason != ExclusionReasonLocked {
+					t.Errorf("buildPlan() reason = %v, want %v", v.Reason, ExclusionReasonLocked)
+				}
+			}
+			for _, v := range snapshots {
+				if _, ok := plan.Unlocks[aws.StringValue(v.SnapshotId)]; ok {
+					gotUnlocks = append(gotUnlocks, aws.StringValue(v.SnapshotId))
+				}
+			}
+			if !reflect.DeepEqual(gotSnapshots, tt.wantSnapshots) {
+				t.Errorf("buildPlan() snapshots = %v, want %v", gotSnapshots, tt.wantSnapshots)
+			}
+			if !reflect.DeepEqual(gotExcluded, tt.wantExcluded) {
+				t.Errorf("buildPlan() excluded = %v, want %v", gotExcluded, tt.wantExcluded)
+			}
+			if !reflect.DeepEqual(gotUnlocks, tt.wantUnlocks) {
+				t.Errorf("buildPlan() unlocks = %v, want %v", gotUnlocks, tt.wantUnlocks)
+			}
+		})
+	}
+}
+
+func TestBullDelete_buildPlan_lockedDenied(t *testing.T) {
+	startTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	svc := newLockedSnapshotsMock(nil, startTime)
+	svc.DescribeLockedSnapshotsWithContextFunc = func(ctx aws.Context, input *ec2.DescribeLockedSnapshotsInput, opts ...request.Option) (*ec2.DescribeLockedSnapshotsOutput, error) {
+		return nil, awserr.New("UnauthorizedOperation", "You are not authorized to perform this operation.", nil)
+	}
+	c := &BullDelete{svc: svc}
+	snapshots := []*ec2.Snapshot{newSnapshot("snap-1", startTime, nil), newSnapshot("snap-2", startTime, nil)}
+	plan, err := c.buildPlan(context.Background(), snapshots)
+	if err != nil {
+		t.Fatalf("buildPlan() error = %v", err)
+	}
+	if !reflect.DeepEqual(plan.Snapshots, snapshots) || len(plan.Warnings) != 1 {
+		t.Errorf("buildPlan() = %+v", plan)
+	}
+
+	svc.DescribeLockedSnapshotsWithContextFunc = func(ctx aws.Context, input *ec2.DescribeLockedSnapshotsInput, opts ...request.Option) (*ec2.DescribeLockedSnapshotsOutput, error) {
+		return nil, awserr.New("RequestLimitExceeded", "Request limit exceeded.", nil)
+	}
+	if _, err := c.buildPlan(context.Background(), snapshots); err == nil {
+		t.Error("buildPlan() error = nil, want the error of DescribeLockedSnapshots")
+	}
+}
+
+func Test_lockDetail(t *testing.T) {
+	lock := &ec2.LockedSnapshotsInfo{
+		LockState:     aws.String(ec2.LockStateGovernance),
+		LockExpiresOn: aws.Time(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
+	}
+	if got, want := lockDetail(lock), "governance until 2024-01-01T00:00:00Z"; got != want {
+		t.Errorf("lockDetail() = %v, want %v", got, want)
+	}
+}
+
+func TestBullDelete_deleteSnapshots_unlock(t *testing.T) {
+	startTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	var deleted []string
+	c := &BullDelete{
+		svc: &ec2SnapshotAPIMock{
+			UnlockSnapshotWithContextFunc: func(ctx aws.Context, input *ec2.UnlockSnapshotInput, opts ...request.Option) (*ec2.UnlockSnapshotOutput, error) {
+				if aws.StringValue(input.SnapshotId) == "snap-denied" {
+					return nil, awserr.New("UnauthorizedOperation", "", nil)
+				}
+				return &ec2.UnlockSnapshotOutput{}, nil
+			},
+			DeleteSnapshotWithContextFunc: func(ctx aws.Context, input *ec2.DeleteSnapshotInput, opts ...request.Option) (*ec2.DeleteSnapshotOutput, error) {
+				deleted = append(deleted, aws.StringValue(input.SnapshotId))
+				return &ec2.DeleteSnapshotOutput{}, nil
+			},
+		},
+	}
+	unlocks := map[string]*ec2.LockedSnapshotsInfo{
+		"snap-governance": {LockState: aws.String(ec2.LockStateGovernance)},
+		"snap-denied":     {LockState: aws.String(ec2.LockStateGovernance)},
+	}
+	snapshots := []*ec2.Snapshot{
+		newSnapshot("snap-governance", startTime, nil),
+		newSnapshot("snap-denied", startTime, nil),
+	}
+	successful, failed, err := c.deleteSnapshots(context.Background(), snapshots, unlocks, Options{})
+	if err != nil {
+		t.Fatalf("deleteSnapshots() error = %v", err)
+	}
+	if len(successful) != 1 || len(failed) != 1 {
+		t.Fatalf("deleteSnapshots() successful = %d, failed = %d", len(successful), len(failed))
+	}
+	if failed[0].Kind != FailureKindDenied {
+		t.Errorf("deleteSnapshots() kind = %v, want %v", failed[0].Kind, FailureKindDenied)
+	}
+	if !reflect.DeepEqual(deleted, []string{"snap-governance"}) {
+		t.Errorf("deleteSnapshots() deleted = %v", deleted)
+	}
+}
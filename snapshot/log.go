@@ -0,0 +1,46 @@
+package snapshot
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// discardHandler drops every record, so that BullDelete logs nothing unless
+// BulkDeleteConfig.Logger is set.
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+var discardLogger = slog.New(discardHandler{})
+
+func (c *BullDelete) log() *slog.Logger {
+	if c.logger == nil {
+		return discardLogger
+	}
+	return c.logger
+}
+
+func snapshotIDAttr(snapshot *ec2.Snapshot) slog.Attr {
+	return slog.String("snapshot_id", aws.StringValue(snapshot.SnapshotId))
+}
+
+// logPlan logs the decision on each described snapshot which made it to
+// buildPlan.
+func (c *BullDelete) logPlan(plan *Plan) {
+	for _, v := range plan.Snapshots {
+		c.log().Debug("snapshot included", snapshotIDAttr(v), slog.String("action", string(plan.Action)))
+	}
+	for _, v := range plan.Excluded {
+		c.log().Info("snapshot excluded", snapshotIDAttr(v.Snapshot),
+			slog.String("reason", string(v.Reason)), slog.String("detail", v.Detail))
+	}
+	for _, v := range plan.NotFound {
+		c.log().Warn("snapshot not found", slog.String("snapshot_id", v))
+	}
+}
@@ -0,0 +1,99 @@
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// logRecords returns the records of a JSON logger without the time.
+func logRecords(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var records []map[string]interface{}
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var r map[string]interface{}
+		if err := dec.Decode(&r); err != nil {
+			t.Fatal(err)
+		}
+		delete(r, slog.TimeKey)
+		delete(r, "duration")
+		records = append(records, r)
+	}
+	return records
+}
+
+func TestBullDelete_log(t *testing.T) {
+	now := time.Date(2023, 1, 10, 0, 0, 0, 0, time.UTC)
+	var buf bytes.Buffer
+	calls := 0
+	c := &BullDelete{
+		age:    1,
+		tags:   map[string]string{"Env": "dev"},
+		logger: slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		svc: &ec2SnapshotAPIMock{
+			DescribeSnapshotsPagesWithContextFunc: func(ctx aws.Context, input *ec2.DescribeSnapshotsInput, fn func(*ec2.DescribeSnapshotsOutput, bool) bool, opts ...request.Option) error {
+				fn(&ec2.DescribeSnapshotsOutput{Snapshots: []*ec2.Snapshot{
+					newSnapshot("snap-new", now, []string{"Env", "dev"}),
+					newSnapshot("snap-prod", now.Add(-48*time.Hour), []string{"Env", "prod"}),
+					newSnapshot("snap-dev", now.Add(-48*time.Hour), []string{"Env", "dev"}),
+				}}, true)
+				return nil
+			},
+			DeleteSnapshotWithContextFunc: func(ctx aws.Context, input *ec2.DeleteSnapshotInput, opts ...request.Option) (*ec2.DeleteSnapshotOutput, error) {
+				calls++
+				if calls == 1 {
+					return nil, awserr.New("RequestLimitExceeded", "", nil)
+				}
+				return &ec2.DeleteSnapshotOutput{}, nil
+			},
+		},
+		retryPolicies: map[FailureKind]RetryPolicy{
+			FailureKindRetryable: {MaxAttempts: 2},
+		},
+		includeManaged: true,
+	}
+	snapshots, _, err := c.describeSnapshots(mockNow(context.Background(), now), c.tags, c.age)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.logPlan(&Plan{
+		Action:    ActionDelete,
+		Snapshots: snapshots,
+		Excluded: []*ExcludedSnapshot{
+			{Snapshot: newSnapshot("snap-locked", now, nil), Reason: ExclusionReasonLocked, Detail: "compliance"},
+		},
+		NotFound: []string{"snap-gone"},
+	})
+	if _, _, err := c.deleteSnapshots(context.Background(), snapshots, nil, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	want := []map[string]interface{}{
+		{"level": "DEBUG", "msg": "snapshot not selected", "snapshot_id": "snap-new", "selector": "age"},
+		{"level": "DEBUG", "msg": "snapshot not selected", "snapshot_id": "snap-prod", "selector": "tags"},
+		{"level": "DEBUG", "msg": "snapshot included", "snapshot_id": "snap-dev", "action": "delete"},
+		{"level": "INFO", "msg": "snapshot excluded", "snapshot_id": "snap-locked", "reason": "locked", "detail": "compliance"},
+		{"level": "WARN", "msg": "snapshot not found", "snapshot_id": "snap-gone"},
+		{"level": "WARN", "msg": "retrying", "kind": "retryable", "attempt": float64(1), "max_attempts": float64(2), "delay": float64(0),
+			"error": "RequestLimitExceeded: "},
+		{"level": "DEBUG", "msg": "snapshot done", "snapshot_id": "snap-dev", "action": "delete"},
+	}
+	if got := logRecords(t, &buf); !reflect.DeepEqual(got, want) {
+		t.Errorf("log got = %v, want %v", got, want)
+	}
+}
+
+func TestBullDelete_log_default(t *testing.T) {
+	c := &BullDelete{}
+	if c.log().Enabled(context.Background(), slog.LevelError) {
+		t.Errorf("log() is enabled without a logger")
+	}
+}
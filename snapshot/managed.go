@@ -0,0 +1,118 @@
+package snapshot
+
+import (
+	"context"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/backup"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+const (
+	tagKeyDLMPolicyID          = "aws:dlm:lifecycle-policy-id"
+	tagKeyBackupSourceResource = "aws:backup:source-resource"
+
+	backupDescription = "created by the AWS Backup service"
+	recoveryPointARN  = ":recovery-point:"
+)
+
+type manager string
+
+const (
+	managerDLM    manager = "dlm"
+	managerBackup manager = "backup"
+)
+
+// managedBy returns the service which owns the lifecycle of the snapshot,
+// and the DLM policy id if it is DLM.
+func managedBy(snapshot *ec2.Snapshot) (manager, string) {
+	if v := tagValue(snapshot, tagKeyDLMPolicyID); v != "" {
+		return managerDLM, v
+	}
+	if tagValue(snapshot, tagKeyBackupSourceResource) != "" {
+		return managerBackup, ""
+	}
+	description := aws.StringValue(snapshot.Description)
+	if strings.Contains(description, backupDescription) || strings.Contains(description, recoveryPointARN) {
+		return managerBackup, ""
+	}
+	return "", ""
+}
+
+func (c *BullDelete) excludeManaged(ctx context.Context, plan *Plan, snapshots []*ec2.Snapshot) []*ec2.Snapshot {
+	var (
+		unmanaged []*ec2.Snapshot
+		backedUp  []*ec2.Snapshot
+	)
+	for _, v := range snapshots {
+		m, policyID := managedBy(v)
+		switch m {
+		case managerDLM:
+			plan.Excluded = append(plan.Excluded, &ExcludedSnapshot{
+				Snapshot: v,
+				Reason:   ExclusionReasonManaged,
+				Detail:   "DLM policy " + policyID,
+			})
+		case managerBackup:
+			backedUp = append(backedUp, v)
+		default:
+			unmanaged = append(unmanaged, v)
+		}
+	}
+	if len(backedUp) == 0 {
+		return unmanaged
+	}
+	// The vault name is only for display, so the snapshots are excluded
+	// even if the caller is not allowed to list the recovery points.
+	vaults, err := c.backupVaults(ctx)
+	for _, v := range backedUp {
+		detail := "AWS Backup"
+		if err != nil {
+			detail += " (vault unknown: " + err.Error() + ")"
+		} else if vault, ok := vaults[aws.StringValue(v.SnapshotId)]; ok {
+			detail += " vault " + vault
+		}
+		plan.Excluded = append(plan.Excluded, &ExcludedSnapshot{
+			Snapshot: v,
+			Reason:   ExclusionReasonManaged,
+			Detail:   detail,
+		})
+	}
+	return unmanaged
+}
+
+// backupVaults returns the backup vault names keyed by snapshot id.
+func (c *BullDelete) backupVaults(ctx context.Context) (map[string]string, error) {
+	var names []*string
+	err := c.backup.ListBackupVaultsPagesWithContext(ctx, &backup.ListBackupVaultsInput{},
+		func(out *backup.ListBackupVaultsOutput, lastPage bool) bool {
+			for _, v := range out.BackupVaultList {
+				names = append(names, v.BackupVaultName)
+			}
+			return !lastPage
+		})
+	if err != nil {
+		return nil, err
+	}
+	vaults := make(map[string]string)
+	for _, name := range names {
+		err := c.backup.ListRecoveryPointsByBackupVaultPagesWithContext(ctx, &backup.ListRecoveryPointsByBackupVaultInput{
+			BackupVaultName: name,
+			ByResourceType:  aws.String("EBS"),
+		}, func(out *backup.ListRecoveryPointsByBackupVaultOutput, lastPage bool) bool {
+			for _, v := range out.RecoveryPoints {
+				// eg. arn:aws:ec2:us-east-1::snapshot/snap-0123456789abcdef0
+				_, id, ok := strings.Cut(aws.StringValue(v.RecoveryPointArn), ":snapshot/")
+				if ok {
+					vaults[id] = aws.StringValue(name)
+				}
+			}
+			return !lastPage
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return vaults, nil
+}
@@ -0,0 +1,129 @@
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/backup"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func Test_managedBy(t *testing.T) {
+	startTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	withDescription := func(s *ec2.Snapshot, description string) *ec2.Snapshot {
+		s.Description = aws.String(description)
+		return s
+	}
+	tests := []struct {
+		name         string
+		snapshot     *ec2.Snapshot
+		wantManager  manager
+		wantPolicyID string
+	}{
+		{
+			name:         "dlm",
+			snapshot:     newSnapshot("snap-1", startTime, []string{"aws:dlm:lifecycle-policy-id", "policy-0123"}),
+			wantManager:  managerDLM,
+			wantPolicyID: "policy-0123",
+		},
+		{
+			name:        "backup tag",
+			snapshot:    newSnapshot("snap-1", startTime, []string{"aws:backup:source-resource", "vol-0123:arn:aws:backup:us-east-1:123456789012:backup-plan:xyz"}),
+			wantManager: managerBackup,
+		},
+		{
+			name:        "backup description",
+			snapshot:    withDescription(newSnapshot("snap-1", startTime, nil), "This snapshot is created by the AWS Backup service."),
+			wantManager: managerBackup,
+		},
+		{
+			name:        "recovery point description",
+			snapshot:    withDescription(newSnapshot("snap-1", startTime, nil), "arn:aws:backup:us-east-1:123456789012:recovery-point:abc"),
+			wantManager: managerBackup,
+		},
+		{
+			name:     "unmanaged",
+			snapshot: newSnapshot("snap-1", startTime, []string{"Name", "foo"}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotManager, gotPolicyID := managedBy(tt.snapshot)
+			if gotManager != tt.wantManager || gotPolicyID != tt.wantPolicyID {
+				t.Errorf("managedBy() = %v, %v, want %v, %v", gotManager, gotPolicyID, tt.wantManager, tt.wantPolicyID)
+			}
+		})
+	}
+}
+
+func TestBullDelete_excludeManaged(t *testing.T) {
+	startTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	snapshots := []*ec2.Snapshot{
+		newSnapshot("snap-1", startTime, nil),
+		newSnapshot("snap-2", startTime, []string{"aws:dlm:lifecycle-policy-id", "policy-0123"}),
+		newSnapshot("snap-3", startTime, []string{"aws:backup:source-resource", "vol-0123"}),
+	}
+	tests := []struct {
+		name       string
+		vaultsErr  error
+		wantDetail map[string]string
+	}{
+		{
+			name: "names the vault",
+			wantDetail: map[string]string{
+				"snap-2": "DLM policy policy-0123",
+				"snap-3": "AWS Backup vault Default",
+			},
+		},
+		{
+			name:      "excludes without the vault",
+			vaultsErr: errors.New("denied"),
+			wantDetail: map[string]string{
+				"snap-2": "DLM policy policy-0123",
+				"snap-3": "AWS Backup (vault unknown: denied)",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &BullDelete{
+				backup: &backupAPIMock{
+					ListBackupVaultsPagesWithContextFunc: func(ctx aws.Context, input *backup.ListBackupVaultsInput, fn func(*backup.ListBackupVaultsOutput, bool) bool, opts ...request.Option) error {
+						if tt.vaultsErr != nil {
+							return tt.vaultsErr
+						}
+						fn(&backup.ListBackupVaultsOutput{
+							BackupVaultList: []*backup.VaultListMember{{BackupVaultName: aws.String("Default")}},
+						}, true)
+						return nil
+					},
+					ListRecoveryPointsByBackupVaultPagesWithContextFunc: func(ctx aws.Context, input *backup.ListRecoveryPointsByBackupVaultInput, fn func(*backup.ListRecoveryPointsByBackupVaultOutput, bool) bool, opts ...request.Option) error {
+						fn(&backup.ListRecoveryPointsByBackupVaultOutput{
+							RecoveryPoints: []*backup.RecoveryPointByBackupVault{
+								{RecoveryPointArn: aws.String("arn:aws:ec2:us-east-1::snapshot/snap-3")},
+							},
+						}, true)
+						return nil
+					},
+				},
+			}
+			plan := &Plan{}
+			got := c.excludeManaged(context.Background(), plan, snapshots)
+			if len(got) != 1 || aws.StringValue(got[0].SnapshotId) != "snap-1" {
+				t.Errorf("excludeManaged() = %v", got)
+			}
+			gotDetail := make(map[string]string)
+			for _, v := range plan.Excluded {
+				gotDetail[aws.StringValue(v.Snapshot.SnapshotId)] = v.Detail
+			}
+			if !reflect.DeepEqual(gotDetail, tt.wantDetail) {
+				t.Errorf("excludeManaged() excluded = %v, want %v", gotDetail, tt.wantDetail)
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+const metricsNamespace = "aws_snapshot_bulk_delete"
+
+// MetricsJob is the job of the metrics pushed to a Pushgateway.
+const MetricsJob = "aws-snapshot-bulk-delete"
+
+type apiCallMetrics struct {
+	count   int
+	errors  int
+	seconds float64
+}
+
+// Metrics collects the metrics of a run through Options, and writes them in
+// the Prometheus text format.
+type Metrics struct {
+	region       string
+	action       Action
+	discovered   int
+	planned      int
+	excluded     int
+	deleted      int
+	unshared     int
+	failed       map[FailureKind]int
+	reclaimedGiB int64
+	apiCalls     map[string]*apiCallMetrics
+	startedAt    time.Time
+	finishedAt   time.Time
+	success      bool
+}
+
+// NewMetrics returns the metrics of a run of the action. Unsharing keeps the
+// snapshots, so it is counted apart from deleting and reclaims nothing.
+func NewMetrics(region string, action Action) *Metrics {
+	return &Metrics{
+		region:   region,
+		action:   action,
+		failed:   make(map[FailureKind]int),
+		apiCalls: make(map[string]*apiCallMetrics),
+	}
+}
+
+func (m *Metrics) Options() Options {
+	return Options{
+		BeforeDescribeSnapshotsFunc: func() error {
+			m.startedAt = time.Now()
+			return nil
+		},
+		AfterPlanFunc: func(plan *Plan) error {
+			m.planned = len(plan.Snapshots)
+			m.excluded = len(plan.Excluded)
+			m.discovered = m.planned + m.excluded
+			return nil
+		},
+		AfterDeleteSnapshotsFunc: func(successful []*ec2.Snapshot, failed []*ErrorWithSnapshot) error {
+			if m.action == ActionUnshare {
+				m.unshared = len(successful)
+			} else {
+				m.deleted = len(successful)
+				for _, v := range successful {
+					m.reclaimedGiB += aws.Int64Value(v.VolumeSize)
+				}
+			}
+			for _, v := range failed {
+				m.failed[v.Kind]++
+			}
+			return nil
+		},
+		AfterAPICallFunc: func(call *APICall) {
+			// eg. ec2.DeleteSnapshot
+			op := call.Service + "." + call.Operation
+			c, ok := m.apiCalls[op]
+			if !ok {
+				c = &apiCallMetrics{}
+				m.apiCalls[op] = c
+			}
+			c.count++
+			c.seconds += call.Latency.Seconds()
+			if call.Err != nil {
+				c.errors++
+			}
+		},
+	}
+}
+
+// Finish records the end of the run, which succeeded if err is nil and no
+// snapshot failed.
+func (m *Metrics) Finish(err error) {
+	m.finishedAt = time.Now()
+	m.success = err == nil
+	for _, v := range m.failed {
+		if v > 0 {
+			m.success = false
+		}
+	}
+}
+
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	region := fmt.Sprintf("region=%q", m.region)
+	gauge := func(name, help string, samples ...string) {
+		fmt.Fprintf(&buf, "# HELP %s_%s %s\n# TYPE %s_%s gauge\n", metricsNamespace, name, help, metricsNamespace, name)
+		for _, v := range samples {
+			fmt.Fprintf(&buf, "%s_%s%s\n", metricsNamespace, name, v)
+		}
+	}
+	sample := func(labels string, v interface{}) string {
+		return fmt.Sprintf("{%s} %v", labels, v)
+	}
+	gauge("snapshots_discovered", "Snapshots matching the selectors.", sample(region, m.discovered))
+	gauge("snapshots_planned", "Snapshots planned to delete.", sample(region, m.planned))
+	gauge("snapshots_excluded", "Snapshots excluded from the plan by protections.", sample(region, m.excluded))
+	gauge("snapshots_deleted", "Snapshots deleted.", sample(region, m.deleted))
+	gauge("snapshots_unshared", "Snapshots unshared.", sample(region, m.unshared))
+	var failed []string
+	for _, k := range FailureKinds {
+		failed = append(failed, sample(fmt.Sprintf("%s,kind=%q", region, k), m.failed[k]))
+	}
+	gauge("snapshots_failed", "Snapshots failed to delete by failure kind.", failed...)
+	gauge("reclaimed_gibibytes", "Volume size of the deleted snapshots in GiB.", sample(region, m.reclaimedGiB))
+	var duration float64
+	if !m.startedAt.IsZero() {
+		duration = m.finishedAt.Sub(m.startedAt).Seconds()
+	}
+	gauge("run_duration_seconds", "Duration of the run.", sample(region, duration))
+	gauge("last_run_timestamp_seconds", "Time the run finished.", sample(region, m.finishedAt.Unix()))
+	success := 0
+	if m.success {
+		success = 1
+	}
+	gauge("last_run_success", "Whether the run finished without errors and failures.", sample(region, success))
+
+	var ops []string
+	for k := range m.apiCalls {
+		ops = append(ops, k)
+	}
+	sort.Strings(ops)
+	name := metricsNamespace + "_api_call_duration_seconds"
+	fmt.Fprintf(&buf, "# HELP %s Latency of AWS API calls including retries.\n# TYPE %s summary\n", name, name)
+	for _, op := range ops {
+		labels := fmt.Sprintf("%s,operation=%q", region, op)
+		fmt.Fprintf(&buf, "%s_sum{%s} %v\n%s_count{%s} %d\n", name, labels, m.apiCalls[op].seconds, name, labels, m.apiCalls[op].count)
+	}
+	var apiErrors []string
+	for _, op := range ops {
+		apiErrors = append(apiErrors, sample(fmt.Sprintf("%s,operation=%q", region, op), m.apiCalls[op].errors))
+	}
+	gauge("api_call_errors", "AWS API calls which failed.", apiErrors...)
+	return buf.WriteTo(w)
+}
+
+// WriteTextfile writes the metrics for the textfile collector of
+// node_exporter. The file is replaced atomically so that the collector never
+// reads a partial file.
+func (m *Metrics) WriteTextfile(name string) error {
+	f, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := m.WriteTo(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Chmod(0644); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), name)
+}
+
+// Push replaces the metrics of the region on the Pushgateway.
+func (m *Metrics) Push(ctx context.Context, client *http.Client, gateway string) error {
+	// eg. http://pushgateway:9091/metrics/job/aws-snapshot-bulk-delete/region/us-east-1
+	u := strings.TrimSuffix(gateway, "/") + "/metrics/job/" + url.PathEscape(MetricsJob) + "/region/" + url.PathEscape(m.region)
+	var buf bytes.Buffer
+	if _, err := m.WriteTo(&buf); err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u, &buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	return fmt.Errorf("unexpected status: %s", resp.Status)
+}
@@ -0,0 +1,174 @@
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client/metadata"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func newTestMetrics() *Metrics {
+	m := NewMetrics("us-east-1", ActionDelete)
+	opts := m.Options()
+	_ = opts.BeforeDescribeSnapshotsFunc()
+	_ = opts.AfterPlanFunc(&Plan{
+		Snapshots: []*ec2.Snapshot{{VolumeSize: aws.Int64(8)}, {VolumeSize: aws.Int64(100)}, {VolumeSize: aws.Int64(1)}},
+		Excluded:  []*ExcludedSnapshot{{Snapshot: &ec2.Snapshot{}, Reason: ExclusionReasonLocked}},
+	})
+	opts.AfterAPICallFunc(&APICall{Service: "ec2", Operation: "DeleteSnapshot", Latency: 250 * time.Millisecond})
+	opts.AfterAPICallFunc(&APICall{Service: "ec2", Operation: "DeleteSnapshot", Latency: 500 * time.Millisecond, Err: errors.New("in use")})
+	opts.AfterAPICallFunc(&APICall{Service: "ec2", Operation: "DescribeSnapshots", Latency: time.Second})
+	_ = opts.AfterDeleteSnapshotsFunc(
+		[]*ec2.Snapshot{{VolumeSize: aws.Int64(8)}, {VolumeSize: aws.Int64(100)}},
+		[]*ErrorWithSnapshot{{Snapshot: &ec2.Snapshot{VolumeSize: aws.Int64(1)}, Kind: FailureKindInUse}},
+	)
+	m.Finish(nil)
+	m.startedAt = time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC)
+	m.finishedAt = m.startedAt.Add(90 * time.Second)
+	return m
+}
+
+const wantMetrics = `# HELP aws_snapshot_bulk_delete_snapshots_discovered Snapshots matching the selectors.
+# TYPE aws_snapshot_bulk_delete_snapshots_discovered gauge
+aws_snapshot_bulk_delete_snapshots_discovered{region="us-east-1"} 4
+# HELP aws_snapshot_bulk_delete_snapshots_planned Snapshots planned to delete.
+# TYPE aws_snapshot_bulk_delete_snapshots_planned gauge
+aws_snapshot_bulk_delete_snapshots_planned{region="us-east-1"} 3
+# HELP aws_snapshot_bulk_delete_snapshots_excluded Snapshots excluded from the plan by protections.
+# TYPE aws_snapshot_bulk_delete_snapshots_excluded gauge
+aws_snapshot_bulk_delete_snapshots_excluded{region="us-east-1"} 1
+# HELP aws_snapshot_bulk_delete_snapshots_deleted Snapshots deleted.
+# TYPE aws_snapshot_bulk_delete_snapshots_deleted gauge
+aws_snapshot_bulk_delete_snapshots_deleted{region="us-east-1"} 2
+# HELP aws_snapshot_bulk_delete_snapshots_unshared Snapshots unshared.
+# TYPE aws_snapshot_bulk_delete_snapshots_unshared gauge
+aws_snapshot_bulk_delete_snapshots_unshared{region="us-east-1"} 0
+# HELP aws_snapshot_bulk_delete_snapshots_failed Snapshots failed to delete by failure kind.
+# TYPE aws_snapshot_bulk_delete_snapshots_failed gauge
+aws_snapshot_bulk_delete_snapshots_failed{region="us-east-1",kind="retryable"} 0
+aws_snapshot_bulk_delete_snapshots_failed{region="us-east-1",kind="in-use"} 1
+aws_snapshot_bulk_delete_snapshots_failed{region="us-east-1",kind="not-found"} 0
+aws_snapshot_bulk_delete_snapshots_failed{region="us-east-1",kind="denied"} 0
+aws_snapshot_bulk_delete_snapshots_failed{region="us-east-1",kind="other"} 0
+# HELP aws_snapshot_bulk_delete_reclaimed_gibibytes Volume size of the deleted snapshots in GiB.
+# TYPE aws_snapshot_bulk_delete_reclaimed_gibibytes gauge
+aws_snapshot_bulk_delete_reclaimed_gibibytes{region="us-east-1"} 108
+# HELP aws_snapshot_bulk_delete_run_duration_seconds Duration of the run.
+# TYPE aws_snapshot_bulk_delete_run_duration_seconds gauge
+aws_snapshot_bulk_delete_run_duration_seconds{region="us-east-1"} 90
+# HELP aws_snapshot_bulk_delete_last_run_timestamp_seconds Time the run finished.
+# TYPE aws_snapshot_bulk_delete_last_run_timestamp_seconds gauge
+aws_snapshot_bulk_delete_last_run_timestamp_seconds{region="us-east-1"} 1672671935
+# HELP aws_snapshot_bulk_delete_last_run_success Whether the run finished without errors and failures.
+# TYPE aws_snapshot_bulk_delete_last_run_success gauge
+aws_snapshot_bulk_delete_last_run_success{region="us-east-1"} 0
+# HELP aws_snapshot_bulk_delete_api_call_duration_seconds Latency of AWS API calls including retries.
+# TYPE aws_snapshot_bulk_delete_api_call_duration_seconds summary
+aws_snapshot_bulk_delete_api_call_duration_seconds_sum{region="us-east-1",operation="ec2.DeleteSnapshot"} 0.75
+aws_snapshot_bulk_delete_api_call_duration_seconds_count{region="us-east-1",operation="ec2.DeleteSnapshot"} 2
+aws_snapshot_bulk_delete_api_call_duration_seconds_sum{region="us-east-1",operation="ec2.DescribeSnapshots"} 1
+aws_snapshot_bulk_delete_api_call_duration_seconds_count{region="us-east-1",operation="ec2.DescribeSnapshots"} 1
+# HELP aws_snapshot_bulk_delete_api_call_errors AWS API calls which failed.
+# TYPE aws_snapshot_bulk_delete_api_call_errors gauge
+aws_snapshot_bulk_delete_api_call_errors{region="us-east-1",operation="ec2.DeleteSnapshot"} 1
+aws_snapshot_bulk_delete_api_call_errors{region="us-east-1",operation="ec2.DescribeSnapshots"} 0
+`
+
+func TestMetrics_WriteTo(t *testing.T) {
+	var buf bytes.Buffer
+	if _, err := newTestMetrics().WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != wantMetrics {
+		t.Errorf("WriteTo() got = %v, want %v", got, wantMetrics)
+	}
+}
+
+func TestMetrics_unshare(t *testing.T) {
+	m := NewMetrics("us-east-1", ActionUnshare)
+	_ = m.Options().AfterDeleteSnapshotsFunc([]*ec2.Snapshot{{VolumeSize: aws.Int64(8)}, {VolumeSize: aws.Int64(100)}}, nil)
+	if m.unshared != 2 || m.deleted != 0 || m.reclaimedGiB != 0 {
+		t.Errorf("Options() unshared = %d, deleted = %d, reclaimed = %d", m.unshared, m.deleted, m.reclaimedGiB)
+	}
+}
+
+func TestMetrics_WriteTextfile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "snapshots.prom")
+	if err := newTestMetrics().WriteTextfile(name); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != wantMetrics {
+		t.Errorf("WriteTextfile() got = %v, want %v", string(b), wantMetrics)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(name))
+	if len(entries) != 1 {
+		t.Errorf("WriteTextfile() left %d files", len(entries))
+	}
+}
+
+func TestMetrics_Push(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{
+			name:   "ok",
+			status: http.StatusOK,
+		},
+		{
+			name:    "error",
+			status:  http.StatusBadRequest,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPut || r.URL.Path != "/metrics/job/aws-snapshot-bulk-delete/region/us-east-1" {
+					t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+				}
+				b, _ := io.ReadAll(r.Body)
+				if string(b) != wantMetrics {
+					t.Errorf("unexpected body: %s", b)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+			err := newTestMetrics().Push(context.Background(), srv.Client(), srv.URL+"/")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Push() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBullDelete_afterAPICall(t *testing.T) {
+	var got *APICall
+	c := &BullDelete{afterAPICallFunc: func(call *APICall) { got = call }}
+	errInUse := errors.New("in use")
+	c.afterAPICall(&request.Request{
+		ClientInfo: metadata.ClientInfo{ServiceName: "ec2"},
+		Operation:  &request.Operation{Name: "DeleteSnapshot"},
+		Time:       time.Now().Add(-time.Second),
+		Error:      errInUse,
+	})
+	if got == nil || got.Service != "ec2" || got.Operation != "DeleteSnapshot" || got.Err != errInUse || got.Latency < time.Second {
+		t.Errorf("afterAPICall() got = %+v", got)
+	}
+}
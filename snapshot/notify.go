@@ -0,0 +1,358 @@
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+var errInvalidTemplatePayload = errors.New("template did not produce valid JSON")
+
+type NotifyEvent string
+
+const (
+	// NotifyOnPlan fires after the snapshots are described, even with --plan.
+	NotifyOnPlan NotifyEvent = "plan"
+	// NotifyOnSuccess fires after a run without failures.
+	NotifyOnSuccess NotifyEvent = "success"
+	// NotifyOnFailure fires after a run with at least one failure, or which
+	// ended with an error, eg. AccessDenied on DescribeSnapshots.
+	NotifyOnFailure NotifyEvent = "failure"
+)
+
+func (e NotifyEvent) validate() error {
+	switch e {
+	case NotifyOnPlan, NotifyOnSuccess, NotifyOnFailure:
+		return nil
+	}
+	return fmt.Errorf("invalid notify event: %s", e)
+}
+
+type WebhookFormat string
+
+const (
+	WebhookFormatJSON  WebhookFormat = "json"
+	WebhookFormatSlack WebhookFormat = "slack"
+	WebhookFormatTeams WebhookFormat = "teams"
+)
+
+func (f WebhookFormat) validate() error {
+	switch f {
+	case "", WebhookFormatJSON, WebhookFormatSlack, WebhookFormatTeams:
+		return nil
+	}
+	return fmt.Errorf("invalid webhook format: %s", f)
+}
+
+type Webhook struct {
+	URL    string
+	Format WebhookFormat
+	// Template is a text/template executed with the Notification, which
+	// replaces the format. It must produce JSON.
+	Template string
+
+	tmpl *template.Template
+}
+
+// ParseWebhook parses a webhook URL with an optional format prefix.
+// eg. https://example.com/hook, slack=https://hooks.slack.com/services/...
+func ParseWebhook(s string) (*Webhook, error) {
+	s = strings.TrimSpace(s)
+	w := &Webhook{URL: s}
+	if format, url, ok := strings.Cut(s, "="); ok && !strings.Contains(format, "/") {
+		w.Format = WebhookFormat(format)
+		w.URL = url
+	}
+	if err := w.Format.validate(); err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(w.URL, "https://") && !strings.HasPrefix(w.URL, "http://") {
+		return nil, fmt.Errorf("invalid webhook url: %s", w.URL)
+	}
+	return w, nil
+}
+
+// Notification is the payload of the json format and the data of a template.
+type Notification struct {
+	Event        NotifyEvent            `json:"event"`
+	RunID        string                 `json:"run_id"`
+	Region       string                 `json:"region"`
+	Action       Action                 `json:"action"`
+	Planned      int                    `json:"planned"`
+	Successful   int                    `json:"successful"`
+	Failed       int                    `json:"failed"`
+	FailedByKind map[FailureKind]int    `json:"failed_by_kind,omitempty"`
+	SnapshotIDs  []string               `json:"snapshot_ids,omitempty"`
+	Failures     []*NotificationFailure `json:"failures,omitempty"`
+	// Error is the error which ended the run.
+	Error string `json:"error,omitempty"`
+}
+
+type NotificationFailure struct {
+	SnapshotID string      `json:"snapshot_id"`
+	Kind       FailureKind `json:"kind"`
+	Error      string      `json:"error"`
+}
+
+// Text returns a one-line summary of the notification.
+func (n *Notification) Text() string {
+	verb := "delete"
+	if n.Action == ActionUnshare {
+		verb = "unshare"
+	}
+	prefix := fmt.Sprintf("Run %s in %s: ", n.RunID, n.Region)
+	if n.Event == NotifyOnPlan {
+		return prefix + fmt.Sprintf("%d snapshots planned to %s.", n.Planned, verb)
+	}
+	text := prefix + fmt.Sprintf("%d snapshots %sd", n.Successful, verb)
+	if n.Error != "" {
+		if n.Successful == 0 && n.Failed == 0 {
+			return prefix + "failed: " + n.Error
+		}
+		return text + fmt.Sprintf(", %d failed, then the run failed: %s", n.Failed, n.Error)
+	}
+	if n.Failed == 0 {
+		return text + "."
+	}
+	var kinds []string
+	for k, v := range n.FailedByKind {
+		kinds = append(kinds, fmt.Sprintf("%s: %d", k, v))
+	}
+	sort.Strings(kinds)
+	return text + fmt.Sprintf(", %d failed (%s).", n.Failed, strings.Join(kinds, ", "))
+}
+
+type NotifierConfig struct {
+	RunID    string
+	Region   string
+	Action   Action
+	Webhooks []*Webhook
+	// On is the events to notify. It defaults to success and failure.
+	On     []NotifyEvent
+	Retry  RetryPolicy
+	Client *http.Client
+	// OnError is called when a notification can not be sent, which does not
+	// stop the run.
+	OnError func(err error)
+}
+
+type Notifier struct {
+	cfg *NotifierConfig
+	on  map[NotifyEvent]bool
+
+	// result is the notification of the deletion, sent once the run ends.
+	result *Notification
+}
+
+var defaultNotifyRetryPolicy = RetryPolicy{MaxAttempts: 3, BaseDelay: time.Second}
+
+func NewNotifier(cfg *NotifierConfig) (*Notifier, error) {
+	on := make(map[NotifyEvent]bool)
+	events := cfg.On
+	if len(events) == 0 {
+		events = []NotifyEvent{NotifyOnSuccess, NotifyOnFailure}
+	}
+	for _, v := range events {
+		if err := v.validate(); err != nil {
+			return nil, err
+		}
+		on[v] = true
+	}
+	for _, w := range cfg.Webhooks {
+		if err := w.Format.validate(); err != nil {
+			return nil, err
+		}
+		if w.Template == "" {
+			continue
+		}
+		tmpl, err := template.New(w.URL).Funcs(template.FuncMap{"json": marshalJSON}).Parse(w.Template)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse template: %w", err)
+		}
+		w.tmpl = tmpl
+	}
+	if cfg.Retry.MaxAttempts == 0 {
+		cfg.Retry = defaultNotifyRetryPolicy
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Notifier{cfg: cfg, on: on}, nil
+}
+
+// Options returns the hooks which send the notifications. Success and failure
+// are sent by AfterRunFunc, so that a run which ends with an error is notified
+// too.
+func (n *Notifier) Options(ctx context.Context) Options {
+	return Options{
+		AfterDescribeSnapshotsFunc: func(snapshots []*ec2.Snapshot) error {
+			if !n.on[NotifyOnPlan] {
+				return nil
+			}
+			msg := n.newNotification(NotifyOnPlan)
+			msg.Planned = len(snapshots)
+			for _, v := range snapshots {
+				msg.SnapshotIDs = append(msg.SnapshotIDs, aws.StringValue(v.SnapshotId))
+			}
+			n.notify(ctx, msg)
+			return nil
+		},
+		AfterDeleteSnapshotsFunc: func(successful []*ec2.Snapshot, failed []*ErrorWithSnapshot) error {
+			event := NotifyOnSuccess
+			if len(failed) > 0 {
+				event = NotifyOnFailure
+			}
+			msg := n.newNotification(event)
+			msg.Planned = len(successful) + len(failed)
+			msg.Successful = len(successful)
+			msg.Failed = len(failed)
+			for _, v := range successful {
+				msg.SnapshotIDs = append(msg.SnapshotIDs, aws.StringValue(v.SnapshotId))
+			}
+			for _, v := range failed {
+				if msg.FailedByKind == nil {
+					msg.FailedByKind = make(map[FailureKind]int)
+				}
+				msg.FailedByKind[v.Kind]++
+				f := &NotificationFailure{SnapshotID: aws.StringValue(v.Snapshot.SnapshotId), Kind: v.Kind}
+				if v.Error != nil {
+					f.Error = v.Error.Error()
+				}
+				msg.Failures = append(msg.Failures, f)
+			}
+			n.result = msg
+			return nil
+		},
+		AfterRunFunc: func(err error) {
+			// A declined confirmation deletes nothing and did not fail.
+			if errors.Is(err, ErrCancelled) {
+				return
+			}
+			msg := n.result
+			if err != nil {
+				if msg == nil {
+					msg = n.newNotification(NotifyOnFailure)
+				}
+				msg.Event = NotifyOnFailure
+				// eg. an error of the CLI which starts with a newline.
+				msg.Error = strings.TrimSpace(err.Error())
+			}
+			// A plan without an error deletes nothing to notify.
+			if msg == nil || !n.on[msg.Event] {
+				return
+			}
+			n.notify(ctx, msg)
+		},
+	}
+}
+
+func (n *Notifier) newNotification(event NotifyEvent) *Notification {
+	action := n.cfg.Action
+	if action == "" {
+		action = ActionDelete
+	}
+	return &Notification{
+		Event:  event,
+		RunID:  n.cfg.RunID,
+		Region: n.cfg.Region,
+		Action: action,
+	}
+}
+
+func (n *Notifier) notify(ctx context.Context, msg *Notification) {
+	for _, w := range n.cfg.Webhooks {
+		err := n.send(ctx, w, msg)
+		if err != nil && n.cfg.OnError != nil {
+			n.cfg.OnError(fmt.Errorf("failed to notify %s: %w", w.URL, err))
+		}
+	}
+}
+
+func (n *Notifier) send(ctx context.Context, w *Webhook, msg *Notification) error {
+	payload, err := w.payload(msg)
+	if err != nil {
+		return err
+	}
+	for attempt := 1; ; attempt++ {
+		retryable, err := n.post(ctx, w.URL, payload)
+		if err == nil {
+			return nil
+		}
+		if !retryable || attempt >= n.cfg.Retry.MaxAttempts {
+			return err
+		}
+		if sleepErr := sleep(ctx, n.cfg.Retry.backoff(attempt)); sleepErr != nil {
+			return err
+		}
+	}
+}
+
+// post sends the payload and reports whether a failure is worth another
+// attempt, which are network errors, 429 and 5xx.
+func (n *Notifier) post(ctx context.Context, url string, payload []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := n.cfg.Client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retryable, fmt.Errorf("unexpected status: %s", resp.Status)
+}
+
+func (w *Webhook) payload(msg *Notification) ([]byte, error) {
+	if w.tmpl != nil {
+		var buf bytes.Buffer
+		if err := w.tmpl.Execute(&buf, msg); err != nil {
+			return nil, err
+		}
+		if !json.Valid(buf.Bytes()) {
+			return nil, errInvalidTemplatePayload
+		}
+		return buf.Bytes(), nil
+	}
+	switch w.Format {
+	case WebhookFormatSlack:
+		return json.Marshal(map[string]string{"text": msg.Text()})
+	case WebhookFormatTeams:
+		color := "2EB886"
+		if msg.Failed > 0 || msg.Error != "" {
+			color = "D00000"
+		}
+		return json.Marshal(map[string]string{
+			"@type":      "MessageCard",
+			"@context":   "https://schema.org/extensions",
+			"summary":    msg.Text(),
+			"themeColor": color,
+			"title":      "aws-snapshot-bulk-delete " + string(msg.Event),
+			"text":       msg.Text(),
+		})
+	}
+	return json.Marshal(msg)
+}
+
+// marshalJSON is the json function of templates, which quotes strings.
+// eg. {"text": {{ json .Text }}}
+func marshalJSON(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
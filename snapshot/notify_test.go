@@ -0,0 +1,338 @@
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func TestParseWebhook(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    *Webhook
+		wantErr bool
+	}{
+		{
+			name: "json",
+			s:    "https://example.com/hook?a=b",
+			want: &Webhook{URL: "https://example.com/hook?a=b"},
+		},
+		{
+			name: "slack",
+			s:    "slack=https://hooks.slack.com/services/T/B/X",
+			want: &Webhook{URL: "https://hooks.slack.com/services/T/B/X", Format: WebhookFormatSlack},
+		},
+		{
+			name: "teams",
+			s:    " teams=https://example.webhook.office.com/x ",
+			want: &Webhook{URL: "https://example.webhook.office.com/x", Format: WebhookFormatTeams},
+		},
+		{
+			name:    "unknown format",
+			s:       "discord=https://example.com/hook",
+			wantErr: true,
+		},
+		{
+			name:    "not url",
+			s:       "example.com/hook",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseWebhook(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseWebhook() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseWebhook() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNotification_Text(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  *Notification
+		want string
+	}{
+		{
+			name: "plan",
+			msg:  &Notification{Event: NotifyOnPlan, RunID: "run-1", Region: "us-east-1", Action: ActionDelete, Planned: 3},
+			want: "Run run-1 in us-east-1: 3 snapshots planned to delete.",
+		},
+		{
+			name: "success",
+			msg:  &Notification{Event: NotifyOnSuccess, RunID: "run-1", Region: "us-east-1", Action: ActionUnshare, Successful: 2},
+			want: "Run run-1 in us-east-1: 2 snapshots unshared.",
+		},
+		{
+			name: "failure",
+			msg: &Notification{Event: NotifyOnFailure, RunID: "run-1", Region: "us-east-1", Action: ActionDelete, Successful: 1, Failed: 3,
+				FailedByKind: map[FailureKind]int{FailureKindInUse: 2, FailureKindDenied: 1}},
+			want: "Run run-1 in us-east-1: 1 snapshots deleted, 3 failed (denied: 1, in-use: 2).",
+		},
+		{
+			name: "error",
+			msg:  &Notification{Event: NotifyOnFailure, RunID: "run-1", Region: "us-east-1", Action: ActionDelete, Error: "AccessDenied"},
+			want: "Run run-1 in us-east-1: failed: AccessDenied",
+		},
+		{
+			name: "error after deletion",
+			msg:  &Notification{Event: NotifyOnFailure, RunID: "run-1", Region: "us-east-1", Action: ActionDelete, Successful: 2, Error: "failed to write result"},
+			want: "Run run-1 in us-east-1: 2 snapshots deleted, 0 failed, then the run failed: failed to write result",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.msg.Text(); got != tt.want {
+				t.Errorf("Text() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebhook_payload(t *testing.T) {
+	msg := &Notification{Event: NotifyOnSuccess, RunID: "run-1", Region: "us-east-1", Action: ActionDelete, Successful: 1}
+	tests := []struct {
+		name    string
+		webhook *Webhook
+		want    map[string]interface{}
+		wantErr bool
+	}{
+		{
+			name:    "json",
+			webhook: &Webhook{},
+			want: map[string]interface{}{
+				"event": "success", "run_id": "run-1", "region": "us-east-1", "action": "delete",
+				"planned": float64(0), "successful": float64(1), "failed": float64(0),
+			},
+		},
+		{
+			name:    "slack",
+			webhook: &Webhook{Format: WebhookFormatSlack},
+			want:    map[string]interface{}{"text": "Run run-1 in us-east-1: 1 snapshots deleted."},
+		},
+		{
+			name:    "teams",
+			webhook: &Webhook{Format: WebhookFormatTeams},
+			want: map[string]interface{}{
+				"@type":      "MessageCard",
+				"@context":   "https://schema.org/extensions",
+				"summary":    "Run run-1 in us-east-1: 1 snapshots deleted.",
+				"themeColor": "2EB886",
+				"title":      "aws-snapshot-bulk-delete success",
+				"text":       "Run run-1 in us-east-1: 1 snapshots deleted.",
+			},
+		},
+		{
+			name:    "template",
+			webhook: &Webhook{Template: `{"content": {{ json .Text }}, "run": "{{ .RunID }}"}`},
+			want:    map[string]interface{}{"content": "Run run-1 in us-east-1: 1 snapshots deleted.", "run": "run-1"},
+		},
+		{
+			name:    "template without json",
+			webhook: &Webhook{Template: `{{ .Text }}`},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewNotifier(&NotifierConfig{Webhooks: []*Webhook{tt.webhook}}); err != nil {
+				t.Fatal(err)
+			}
+			b, err := tt.webhook.payload(msg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("payload() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			var got map[string]interface{}
+			if err := json.Unmarshal(b, &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("payload() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNotifier_Options(t *testing.T) {
+	snapshots := []*ec2.Snapshot{
+		{SnapshotId: aws.String("snap-1")},
+		{SnapshotId: aws.String("snap-2")},
+	}
+	failed := []*ErrorWithSnapshot{
+		{Snapshot: snapshots[1], Error: errors.New("in use"), Kind: FailureKindInUse},
+	}
+	tests := []struct {
+		name       string
+		on         []NotifyEvent
+		plan       bool
+		failed     []*ErrorWithSnapshot
+		runErr     error
+		wantEvents []NotifyEvent
+		wantError  string
+	}{
+		{
+			name:       "default without failures",
+			failed:     nil,
+			wantEvents: []NotifyEvent{NotifyOnSuccess},
+		},
+		{
+			name:       "default with failures",
+			failed:     failed,
+			wantEvents: []NotifyEvent{NotifyOnFailure},
+		},
+		{
+			name:       "plan",
+			on:         []NotifyEvent{NotifyOnPlan},
+			failed:     failed,
+			wantEvents: []NotifyEvent{NotifyOnPlan},
+		},
+		{
+			name:       "failure only without failures",
+			on:         []NotifyEvent{NotifyOnFailure},
+			failed:     nil,
+			wantEvents: nil,
+		},
+		{
+			name:       "plan without error",
+			plan:       true,
+			wantEvents: nil,
+		},
+		{
+			name:       "error before deletion",
+			on:         []NotifyEvent{NotifyOnFailure},
+			plan:       true,
+			runErr:     errors.New("AccessDenied"),
+			wantEvents: []NotifyEvent{NotifyOnFailure},
+			wantError:  "AccessDenied",
+		},
+		{
+			name:       "error after deletion",
+			runErr:     errors.New("\nfailed to write the result"),
+			wantEvents: []NotifyEvent{NotifyOnFailure},
+			wantError:  "failed to write the result",
+		},
+		{
+			// A declined confirmation is not a failure.
+			name:       "cancelled",
+			plan:       true,
+			runErr:     fmt.Errorf("\nApply %w.", ErrCancelled),
+			wantEvents: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				mu       sync.Mutex
+				got      []NotifyEvent
+				gotError string
+			)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var msg Notification
+				b, _ := io.ReadAll(r.Body)
+				if err := json.Unmarshal(b, &msg); err != nil {
+					t.Error(err)
+				}
+				mu.Lock()
+				got = append(got, msg.Event)
+				gotError = msg.Error
+				mu.Unlock()
+			}))
+			defer srv.Close()
+			n, err := NewNotifier(&NotifierConfig{
+				RunID:    "run-1",
+				Region:   "us-east-1",
+				Webhooks: []*Webhook{{URL: srv.URL}},
+				On:       tt.on,
+				OnError:  func(err error) { t.Error(err) },
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			opts := n.Options(context.Background())
+			_ = opts.AfterDescribeSnapshotsFunc(snapshots)
+			if !tt.plan {
+				_ = opts.AfterDeleteSnapshotsFunc(snapshots[:1], tt.failed)
+			}
+			opts.AfterRunFunc(tt.runErr)
+			if !reflect.DeepEqual(got, tt.wantEvents) {
+				t.Errorf("Options() got = %v, want %v", got, tt.wantEvents)
+			}
+			if gotError != tt.wantError {
+				t.Errorf("Options() error = %q, want %q", gotError, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestNotifier_send(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int
+		wantAttempts int
+		wantErr      bool
+	}{
+		{
+			name:         "ok",
+			statuses:     []int{http.StatusOK},
+			wantAttempts: 1,
+		},
+		{
+			name:         "retry server error",
+			statuses:     []int{http.StatusBadGateway, http.StatusTooManyRequests, http.StatusNoContent},
+			wantAttempts: 3,
+		},
+		{
+			name:         "give up",
+			statuses:     []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError},
+			wantAttempts: 3,
+			wantErr:      true,
+		},
+		{
+			name:         "client error",
+			statuses:     []int{http.StatusBadRequest},
+			wantAttempts: 1,
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts int
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.statuses[attempts])
+				attempts++
+			}))
+			defer srv.Close()
+			n, err := NewNotifier(&NotifierConfig{Retry: RetryPolicy{MaxAttempts: 3}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = n.send(context.Background(), &Webhook{URL: srv.URL}, &Notification{Event: NotifyOnSuccess})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("send() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("send() attempts = %v, want %v", attempts, tt.wantAttempts)
+			}
+		})
+	}
+}
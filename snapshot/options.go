@@ -0,0 +1,94 @@
+package snapshot
+
+import "github.com/aws/aws-sdk-go/service/ec2"
+
+// ChainOptions returns Options which calls the hooks of each Options in order.
+// A hook stops at the first error.
+func ChainOptions(opts ...Options) Options {
+	return Options{
+		BeforeDescribeSnapshotsFunc: func() error {
+			for _, o := range opts {
+				if o.BeforeDescribeSnapshotsFunc != nil {
+					if err := o.BeforeDescribeSnapshotsFunc(); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+		AfterDescribeSnapshotsFunc: func(snapshots []*ec2.Snapshot) error {
+			for _, o := range opts {
+				if o.AfterDescribeSnapshotsFunc != nil {
+					if err := o.AfterDescribeSnapshotsFunc(snapshots); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+		AfterPlanFunc: func(plan *Plan) error {
+			for _, o := range opts {
+				if o.AfterPlanFunc != nil {
+					if err := o.AfterPlanFunc(plan); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+		BeforeDeleteSnapshotsFunc: func(snapshots []*ec2.Snapshot) error {
+			for _, o := range opts {
+				if o.BeforeDeleteSnapshotsFunc != nil {
+					if err := o.BeforeDeleteSnapshotsFunc(snapshots); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+		EachDeleteSnapshotsFunc: func(snapshot *ec2.Snapshot) error {
+			for _, o := range opts {
+				if o.EachDeleteSnapshotsFunc != nil {
+					if err := o.EachDeleteSnapshotsFunc(snapshot); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+		EachCopySnapshotFunc: func(snapshot *ec2.Snapshot, copySnapshotID string) error {
+			for _, o := range opts {
+				if o.EachCopySnapshotFunc != nil {
+					if err := o.EachCopySnapshotFunc(snapshot, copySnapshotID); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+		AfterDeleteSnapshotsFunc: func(successful []*ec2.Snapshot, failed []*ErrorWithSnapshot) error {
+			for _, o := range opts {
+				if o.AfterDeleteSnapshotsFunc != nil {
+					if err := o.AfterDeleteSnapshotsFunc(successful, failed); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+		AfterAPICallFunc: func(call *APICall) {
+			for _, o := range opts {
+				if o.AfterAPICallFunc != nil {
+					o.AfterAPICallFunc(call)
+				}
+			}
+		},
+		AfterRunFunc: func(err error) {
+			for _, o := range opts {
+				if o.AfterRunFunc != nil {
+					o.AfterRunFunc(err)
+				}
+			}
+		},
+	}
+}
@@ -0,0 +1,55 @@
+package snapshot
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func TestChainOptions(t *testing.T) {
+	errHook := errors.New("hook")
+	tests := []struct {
+		name    string
+		errs    []error
+		want    []int
+		wantErr error
+	}{
+		{
+			name: "all",
+			errs: []error{nil, nil},
+			want: []int{0, 1},
+		},
+		{
+			name:    "stop at error",
+			errs:    []error{errHook, nil},
+			want:    []int{0},
+			wantErr: errHook,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				got  []int
+				opts []Options
+			)
+			for i, err := range tt.errs {
+				i, err := i, err
+				opts = append(opts, Options{
+					BeforeDeleteSnapshotsFunc: func(snapshots []*ec2.Snapshot) error {
+						got = append(got, i)
+						return err
+					},
+				}, Options{})
+			}
+			err := ChainOptions(opts...).BeforeDeleteSnapshotsFunc(nil)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("ChainOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ChainOptions() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package snapshot
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+type Plan struct {
+	Action    Action
+	Snapshots []*ec2.Snapshot
+	Excluded  []*ExcludedSnapshot
+	// NotFound holds the SnapshotIDs which were not found.
+	NotFound []string
+	// RecoverableUntil is keyed by snapshot id, and only set with RequireRecycleBin.
+	RecoverableUntil map[string]time.Time
+	// Unlocks holds the governance locks to remove before deleting, keyed by
+	// snapshot id. It is only set with UnlockGovernance.
+	Unlocks map[string]*ec2.LockedSnapshotsInfo
+	// Warnings holds the checks which the plan had to skip, eg. for a
+	// missing permission.
+	Warnings []string
+}
+
+type ExclusionReason string
+
+const (
+	ExclusionReasonLocked  ExclusionReason = "locked"
+	ExclusionReasonManaged ExclusionReason = "managed"
+
+	ExclusionReasonLaunchTemplate ExclusionReason = "launch-template"
+)
+
+type ExcludedSnapshot struct {
+	Snapshot *ec2.Snapshot
+	Reason   ExclusionReason
+	Detail   string
+}
+
+type NotRecoverableError struct {
+	Snapshots []*ec2.Snapshot
+}
+
+func (e *NotRecoverableError) Error() string {
+	ids := make([]string, 0, len(e.Snapshots))
+	for _, v := range e.Snapshots {
+		ids = append(ids, aws.StringValue(v.SnapshotId))
+	}
+	return fmt.Sprintf("%d snapshots are not covered by a Recycle Bin retention rule: %s",
+		len(ids), strings.Join(ids, ", "))
+}
+
+// InterruptedError is returned when the context is done before every snapshot
+// of the plan is processed. Remaining holds the snapshots which were not
+// processed, including the one in flight, so that a later run can continue
+// with their ids.
+type InterruptedError struct {
+	Remaining []*ec2.Snapshot
+	Err       error
+}
+
+func (e *InterruptedError) Error() string {
+	return fmt.Sprintf("interrupted with %d snapshots remaining: %v", len(e.Remaining), e.Err)
+}
+
+func (e *InterruptedError) Unwrap() error {
+	return e.Err
+}
+
+func (c *BullDelete) buildPlan(ctx context.Context, snapshots []*ec2.Snapshot) (*Plan, error) {
+	if c.action == ActionUnshare {
+		// Unsharing keeps the snapshots, so the protections against deletion
+		// do not apply.
+		return &Plan{Action: ActionUnshare, Snapshots: snapshots}, nil
+	}
+	plan := &Plan{Action: ActionDelete}
+	if !c.includeManaged {
+		snapshots = c.excludeManaged(ctx, plan, snapshots)
+	}
+	snapshots, err := c.excludeLaunchTemplateReferenced(ctx, plan, snapshots)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.excludeLocked(ctx, plan, snapshots); err != nil {
+		return nil, err
+	}
+	if c.requireRecycleBin {
+		rules, err := c.retentionRules(ctx)
+		if err != nil {
+			return nil, err
+		}
+		until, uncovered := recoverableUntil(now(ctx), plan.Snapshots, rules)
+		if len(uncovered) > 0 {
+			return plan, &NotRecoverableError{Snapshots: uncovered}
+		}
+		plan.RecoverableUntil = until
+	}
+	return plan, nil
+}
+
+func (c *BullDelete) excludeLocked(ctx context.Context, plan *Plan, snapshots []*ec2.Snapshot) error {
+	if len(snapshots) == 0 {
+		return nil
+	}
+	locks, err := c.lockedSnapshots(ctx, snapshots)
+	if isAccessDenied(err) {
+		// Without ec2:DescribeLockedSnapshots the plan can not tell locked
+		// snapshots apart, but their deletion still fails one by one.
+		warning := "locked snapshots are not excluded, ec2:DescribeLockedSnapshots is denied"
+		c.log().Warn(warning, slog.Any("error", err))
+		plan.Warnings = append(plan.Warnings, warning)
+		plan.Snapshots = append(plan.Snapshots, snapshots...)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to describe locked snapshots: %w", err)
+	}
+	if c.unlockGovernance {
+		plan.Unlocks = make(map[string]*ec2.LockedSnapshotsInfo)
+	}
+	for _, v := range snapshots {
+		lock, ok := locks[aws.StringValue(v.SnapshotId)]
+		if !ok || !isLocked(lock) {
+			plan.Snapshots = append(plan.Snapshots, v)
+			continue
+		}
+		if c.unlockGovernance && aws.StringValue(lock.LockState) == ec2.LockStateGovernance {
+			plan.Snapshots = append(plan.Snapshots, v)
+			plan.Unlocks[aws.StringValue(v.SnapshotId)] = lock
+			continue
+		}
+		plan.Excluded = append(plan.Excluded, &ExcludedSnapshot{
+			Snapshot: v,
+			Reason:   ExclusionReasonLocked,
+			Detail:   lockDetail(lock),
+		})
+	}
+	return nil
+}
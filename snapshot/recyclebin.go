@@ -0,0 +1,94 @@
+package snapshot
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/recyclebin"
+)
+
+type retentionRule struct {
+	identifier   string
+	resourceTags []*recyclebin.ResourceTag
+	retention    time.Duration
+}
+
+// covers reports whether the rule retains the snapshot. A region-level rule
+// has no resource tags and retains every snapshot in the region.
+func (r *retentionRule) covers(snapshot *ec2.Snapshot) bool {
+	if len(r.resourceTags) == 0 {
+		return true
+	}
+	for _, rt := range r.resourceTags {
+		for _, tag := range snapshot.Tags {
+			if aws.StringValue(tag.Key) != aws.StringValue(rt.ResourceTagKey) {
+				continue
+			}
+			if rt.ResourceTagValue == nil || aws.StringValue(rt.ResourceTagValue) == aws.StringValue(tag.Value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *BullDelete) retentionRules(ctx context.Context) ([]*retentionRule, error) {
+	var ids []*string
+	err := c.rbin.ListRulesPagesWithContext(ctx, &recyclebin.ListRulesInput{
+		ResourceType: aws.String(recyclebin.ResourceTypeEbsSnapshot),
+	}, func(out *recyclebin.ListRulesOutput, lastPage bool) bool {
+		for _, v := range out.Rules {
+			ids = append(ids, v.Identifier)
+		}
+		return !lastPage
+	})
+	if err != nil {
+		return nil, err
+	}
+	var rules []*retentionRule
+	for _, id := range ids {
+		out, err := c.rbin.GetRuleWithContext(ctx, &recyclebin.GetRuleInput{
+			Identifier: id,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if aws.StringValue(out.Status) != recyclebin.RuleStatusAvailable || out.RetentionPeriod == nil {
+			continue
+		}
+		rules = append(rules, &retentionRule{
+			identifier:   aws.StringValue(out.Identifier),
+			resourceTags: out.ResourceTags,
+			retention:    retentionDuration(out.RetentionPeriod),
+		})
+	}
+	return rules, nil
+}
+
+func retentionDuration(p *recyclebin.RetentionPeriod) time.Duration {
+	// DAYS is the only unit of retention periods.
+	return time.Duration(aws.Int64Value(p.RetentionPeriodValue)) * 24 * time.Hour
+}
+
+// recoverableUntil estimates how long each snapshot stays in the Recycle Bin
+// when it is deleted at now, and returns the snapshots no rule covers.
+func recoverableUntil(now time.Time, snapshots []*ec2.Snapshot, rules []*retentionRule) (map[string]time.Time, []*ec2.Snapshot) {
+	until := make(map[string]time.Time)
+	var uncovered []*ec2.Snapshot
+	for _, snapshot := range snapshots {
+		var retention time.Duration
+		for _, rule := range rules {
+			if rule.covers(snapshot) && rule.retention > retention {
+				retention = rule.retention
+			}
+		}
+		if retention == 0 {
+			uncovered = append(uncovered, snapshot)
+			continue
+		}
+		until[aws.StringValue(snapshot.SnapshotId)] = now.Add(retention)
+	}
+	return until, uncovered
+}
@@ -0,0 +1,156 @@
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/recyclebin"
+)
+
+func Test_retentionRule_covers(t *testing.T) {
+	startTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	type args struct {
+		resourceTags []*recyclebin.ResourceTag
+		snapshot     *ec2.Snapshot
+	}
+	tests := []struct {
+		name string
+		args args
+		want bool
+	}{
+		{
+			name: "region-level",
+			args: args{
+				resourceTags: nil,
+				snapshot:     newSnapshot("snap-1", startTime, nil),
+			},
+			want: true,
+		},
+		{
+			name: "tag-level matches key and value",
+			args: args{
+				resourceTags: []*recyclebin.ResourceTag{
+					{ResourceTagKey: aws.String("Env"), ResourceTagValue: aws.String("prod")},
+				},
+				snapshot: newSnapshot("snap-1", startTime, []string{"Env", "prod"}),
+			},
+			want: true,
+		},
+		{
+			name: "tag-level matches key only",
+			args: args{
+				resourceTags: []*recyclebin.ResourceTag{
+					{ResourceTagKey: aws.String("Env")},
+				},
+				snapshot: newSnapshot("snap-1", startTime, []string{"Env", "dev"}),
+			},
+			want: true,
+		},
+		{
+			name: "tag-level does not match value",
+			args: args{
+				resourceTags: []*recyclebin.ResourceTag{
+					{ResourceTagKey: aws.String("Env"), ResourceTagValue: aws.String("prod")},
+				},
+				snapshot: newSnapshot("snap-1", startTime, []string{"Env", "dev"}),
+			},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &retentionRule{resourceTags: tt.args.resourceTags, retention: time.Hour}
+			if got := r.covers(tt.args.snapshot); got != tt.want {
+				t.Errorf("covers() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_recoverableUntil(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	snapshots := []*ec2.Snapshot{
+		newSnapshot("snap-1", now, []string{"Env", "prod"}),
+		newSnapshot("snap-2", now, []string{"Env", "dev"}),
+	}
+	rules := []*retentionRule{
+		{
+			resourceTags: []*recyclebin.ResourceTag{{ResourceTagKey: aws.String("Env"), ResourceTagValue: aws.String("prod")}},
+			retention:    7 * 24 * time.Hour,
+		},
+		{
+			resourceTags: []*recyclebin.ResourceTag{{ResourceTagKey: aws.String("Env")}},
+			retention:    24 * time.Hour,
+		},
+	}
+	until, uncovered := recoverableUntil(now, snapshots, rules)
+	want := map[string]time.Time{
+		"snap-1": now.Add(7 * 24 * time.Hour),
+		"snap-2": now.Add(24 * time.Hour),
+	}
+	if !reflect.DeepEqual(until, want) {
+		t.Errorf("recoverableUntil() = %v, want %v", until, want)
+	}
+	if len(uncovered) != 0 {
+		t.Errorf("recoverableUntil() uncovered = %v", uncovered)
+	}
+	_, uncovered = recoverableUntil(now, snapshots, rules[:1])
+	if len(uncovered) != 1 || aws.StringValue(uncovered[0].SnapshotId) != "snap-2" {
+		t.Errorf("recoverableUntil() uncovered = %v", uncovered)
+	}
+}
+
+func TestBullDelete_buildPlan_requireRecycleBin(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := &BullDelete{
+		requireRecycleBin: true,
+		svc: &ec2SnapshotAPIMock{
+			DescribeLockedSnapshotsWithContextFunc: func(ctx aws.Context, input *ec2.DescribeLockedSnapshotsInput, opts ...request.Option) (*ec2.DescribeLockedSnapshotsOutput, error) {
+				return &ec2.DescribeLockedSnapshotsOutput{}, nil
+			},
+			DescribeLaunchTemplateVersionsPagesWithContextFunc: func(ctx aws.Context, input *ec2.DescribeLaunchTemplateVersionsInput, fn func(*ec2.DescribeLaunchTemplateVersionsOutput, bool) bool, opts ...request.Option) error {
+				return nil
+			},
+		},
+		rbin: &recycleBinAPIMock{
+			ListRulesPagesWithContextFunc: func(ctx aws.Context, input *recyclebin.ListRulesInput, fn func(*recyclebin.ListRulesOutput, bool) bool, opts ...request.Option) error {
+				fn(&recyclebin.ListRulesOutput{
+					Rules: []*recyclebin.RuleSummary{{Identifier: aws.String("rule-1")}},
+				}, true)
+				return nil
+			},
+			GetRuleWithContextFunc: func(ctx aws.Context, input *recyclebin.GetRuleInput, opts ...request.Option) (*recyclebin.GetRuleOutput, error) {
+				return &recyclebin.GetRuleOutput{
+					Identifier:   input.Identifier,
+					Status:       aws.String(recyclebin.RuleStatusAvailable),
+					ResourceTags: []*recyclebin.ResourceTag{{ResourceTagKey: aws.String("Env")}},
+					RetentionPeriod: &recyclebin.RetentionPeriod{
+						RetentionPeriodUnit:  aws.String(recyclebin.RetentionPeriodUnitDays),
+						RetentionPeriodValue: aws.Int64(7),
+					},
+				}, nil
+			},
+		},
+	}
+	ctx := mockNow(context.Background(), now)
+
+	plan, err := c.buildPlan(ctx, []*ec2.Snapshot{newSnapshot("snap-1", now, []string{"Env", "prod"})})
+	if err != nil {
+		t.Fatalf("buildPlan() error = %v", err)
+	}
+	if got := plan.RecoverableUntil["snap-1"]; !got.Equal(now.Add(7 * 24 * time.Hour)) {
+		t.Errorf("buildPlan() RecoverableUntil = %v", got)
+	}
+
+	_, err = c.buildPlan(ctx, []*ec2.Snapshot{newSnapshot("snap-2", now, nil)})
+	var notRecoverable *NotRecoverableError
+	if !errors.As(err, &notRecoverable) || len(notRecoverable.Snapshots) != 1 {
+		t.Errorf("buildPlan() error = %v, want NotRecoverableError", err)
+	}
+}
@@ -0,0 +1,133 @@
+package snapshot
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+var errTagsWithoutResult = errors.New("tags require a previous result, the Recycle Bin does not return tags")
+
+// NewRestore returns a Restore for snapshots in the Recycle Bin.
+// Only the AWS settings of cfg are used.
+func NewRestore(cfg *BulkDeleteConfig) (*Restore, error) {
+	client, err := newEC2SnapshotAPI(cfg.awsConfig())
+	if err != nil {
+		return nil, err
+	}
+	return &Restore{svc: client}, nil
+}
+
+type Restore struct {
+	svc EC2SnapshotAPI
+}
+
+type RestoreSelector struct {
+	SnapshotIDs []string
+	From        *Result
+	Tags        []string
+}
+
+func (r *Restore) List(ctx context.Context) ([]*ec2.SnapshotRecycleBinInfo, error) {
+	var snapshots []*ec2.SnapshotRecycleBinInfo
+	err := r.svc.ListSnapshotsInRecycleBinPagesWithContext(ctx, &ec2.ListSnapshotsInRecycleBinInput{},
+		func(out *ec2.ListSnapshotsInRecycleBinOutput, lastPage bool) bool {
+			snapshots = append(snapshots, out.Snapshots...)
+			return !lastPage
+		})
+	if err != nil {
+		return nil, err
+	}
+	return snapshots, nil
+}
+
+// Plan returns the snapshots in the Recycle Bin matching sel, and the selected
+// snapshot ids which are not in the Recycle Bin.
+func (r *Restore) Plan(ctx context.Context, sel RestoreSelector) ([]*ec2.SnapshotRecycleBinInfo, []string, error) {
+	ids, err := sel.snapshotIDs()
+	if err != nil {
+		return nil, nil, err
+	}
+	snapshots, err := r.List(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	inRecycleBin := make(map[string]*ec2.SnapshotRecycleBinInfo)
+	for _, v := range snapshots {
+		inRecycleBin[aws.StringValue(v.SnapshotId)] = v
+	}
+	var (
+		restorable []*ec2.SnapshotRecycleBinInfo
+		missing    []string
+	)
+	for _, id := range ids {
+		if v, ok := inRecycleBin[id]; ok {
+			restorable = append(restorable, v)
+		} else {
+			missing = append(missing, id)
+		}
+	}
+	return restorable, missing, nil
+}
+
+func (sel RestoreSelector) snapshotIDs() ([]string, error) {
+	tags, err := tagsMap(sel.Tags)
+	if err != nil {
+		return nil, err
+	}
+	if len(tags) > 0 && sel.From == nil {
+		return nil, errTagsWithoutResult
+	}
+	seen := make(map[string]struct{})
+	var ids []string
+	add := func(id string) {
+		if _, ok := seen[id]; ok || id == "" {
+			return
+		}
+		seen[id] = struct{}{}
+		ids = append(ids, id)
+	}
+	for _, id := range sel.SnapshotIDs {
+		add(id)
+	}
+	if sel.From != nil {
+		match := tagsFilterFunc(tags)
+		for _, v := range sel.From.Successful {
+			if len(tags) > 0 && (v.Snapshot == nil || !match(v.Snapshot)) {
+				continue
+			}
+			add(v.SnapshotID)
+		}
+	}
+	return ids, nil
+}
+
+func (r *Restore) RestoreSnapshots(ctx context.Context, snapshotIDs []string,
+	eachFunc func(snapshotID string) error) ([]string, []*ErrorWithSnapshot, error) {
+	var (
+		successful []string
+		failed     []*ErrorWithSnapshot
+	)
+	for _, id := range snapshotIDs {
+		_, err := r.svc.RestoreSnapshotFromRecycleBinWithContext(ctx, &ec2.RestoreSnapshotFromRecycleBinInput{
+			SnapshotId: aws.String(id),
+		})
+		if err != nil {
+			failed = append(failed, &ErrorWithSnapshot{
+				Error:    err,
+				Kind:     ClassifyError(err),
+				Snapshot: &ec2.Snapshot{SnapshotId: aws.String(id)},
+			})
+			continue
+		}
+		successful = append(successful, id)
+		if eachFunc != nil {
+			if err := eachFunc(id); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	return successful, failed, nil
+}
@@ -0,0 +1,118 @@
+package snapshot
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func newRecycleBinMock(ids ...string) *ec2SnapshotAPIMock {
+	return &ec2SnapshotAPIMock{
+		ListSnapshotsInRecycleBinPagesWithContextFunc: func(ctx aws.Context, input *ec2.ListSnapshotsInRecycleBinInput, fn func(*ec2.ListSnapshotsInRecycleBinOutput, bool) bool, opts ...request.Option) error {
+			out := &ec2.ListSnapshotsInRecycleBinOutput{}
+			for _, id := range ids {
+				out.Snapshots = append(out.Snapshots, &ec2.SnapshotRecycleBinInfo{SnapshotId: aws.String(id)})
+			}
+			fn(out, true)
+			return nil
+		},
+	}
+}
+
+func TestRestore_Plan(t *testing.T) {
+	startTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	from := NewResult("run-1", []*ec2.Snapshot{
+		newSnapshot("snap-1", startTime, []string{"Name", "foo"}),
+		newSnapshot("snap-2", startTime, []string{"Name", "bar"}),
+		newSnapshot("snap-3", startTime, []string{"Name", "foo"}),
+	}, nil)
+	type args struct {
+		sel RestoreSelector
+	}
+	tests := []struct {
+		name        string
+		args        args
+		wantIDs     []string
+		wantMissing []string
+		wantErr     bool
+	}{
+		{
+			name: "snapshot ids",
+			args: args{
+				sel: RestoreSelector{SnapshotIDs: []string{"snap-1", "snap-9"}},
+			},
+			wantIDs:     []string{"snap-1"},
+			wantMissing: []string{"snap-9"},
+		},
+		{
+			name: "from result",
+			args: args{
+				sel: RestoreSelector{From: from},
+			},
+			wantIDs:     []string{"snap-1", "snap-2"},
+			wantMissing: []string{"snap-3"},
+		},
+		{
+			name: "from result with tags",
+			args: args{
+				sel: RestoreSelector{From: from, Tags: []string{"Name=bar"}},
+			},
+			wantIDs:     []string{"snap-2"},
+			wantMissing: nil,
+		},
+		{
+			name: "tags without result",
+			args: args{
+				sel: RestoreSelector{Tags: []string{"Name=foo"}},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Restore{svc: newRecycleBinMock("snap-1", "snap-2")}
+			got, missing, err := r.Plan(context.Background(), tt.args.sel)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Plan() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			var ids []string
+			for _, v := range got {
+				ids = append(ids, aws.StringValue(v.SnapshotId))
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("Plan() got = %v, want %v", ids, tt.wantIDs)
+			}
+			if !reflect.DeepEqual(missing, tt.wantMissing) {
+				t.Errorf("Plan() missing = %v, want %v", missing, tt.wantMissing)
+			}
+		})
+	}
+}
+
+func TestRestore_RestoreSnapshots(t *testing.T) {
+	mock := newRecycleBinMock()
+	mock.RestoreSnapshotFromRecycleBinWithContextFunc = func(ctx aws.Context, input *ec2.RestoreSnapshotFromRecycleBinInput, opts ...request.Option) (*ec2.RestoreSnapshotFromRecycleBinOutput, error) {
+		if aws.StringValue(input.SnapshotId) == "snap-2" {
+			return nil, awserr.New("UnauthorizedOperation", "", nil)
+		}
+		return &ec2.RestoreSnapshotFromRecycleBinOutput{SnapshotId: input.SnapshotId}, nil
+	}
+	r := &Restore{svc: mock}
+	successful, failed, err := r.RestoreSnapshots(context.Background(), []string{"snap-1", "snap-2"}, nil)
+	if err != nil {
+		t.Fatalf("RestoreSnapshots() error = %v", err)
+	}
+	if !reflect.DeepEqual(successful, []string{"snap-1"}) {
+		t.Errorf("RestoreSnapshots() successful = %v", successful)
+	}
+	if len(failed) != 1 || failed[0].Kind != FailureKindDenied {
+		t.Errorf("RestoreSnapshots() failed = %v", failed)
+	}
+}
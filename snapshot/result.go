@@ -0,0 +1,118 @@
+package snapshot
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+type Result struct {
+	RunID      string         `json:"run_id"`
+	RetryOf    string         `json:"retry_of,omitempty"`
+	Action     Action         `json:"action,omitempty"`
+	Shared     Shared         `json:"shared,omitempty"`
+	Region     string         `json:"region"`
+	StartedAt  time.Time      `json:"started_at"`
+	FinishedAt time.Time      `json:"finished_at"`
+	Successful []*ResultEntry `json:"successful"`
+	Failed     []*ResultEntry `json:"failed"`
+	Savings    *Savings       `json:"savings,omitempty"`
+}
+
+type ResultEntry struct {
+	SnapshotID     string        `json:"snapshot_id"`
+	CopySnapshotID string        `json:"copy_snapshot_id,omitempty"`
+	Kind           FailureKind   `json:"kind,omitempty"`
+	Error          string        `json:"error,omitempty"`
+	Snapshot       *ec2.Snapshot `json:"snapshot"`
+}
+
+func NewRunID() string {
+	// eg. 20230102T150405Z-1a2b3c4d
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return time.Now().UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(b)
+}
+
+func NewResult(runID string, successful []*ec2.Snapshot, failed []*ErrorWithSnapshot) *Result {
+	r := &Result{
+		RunID:      runID,
+		Successful: []*ResultEntry{},
+		Failed:     []*ResultEntry{},
+	}
+	for _, v := range successful {
+		r.Successful = append(r.Successful, &ResultEntry{
+			SnapshotID: aws.StringValue(v.SnapshotId),
+			Snapshot:   v,
+		})
+	}
+	for _, v := range failed {
+		entry := &ResultEntry{
+			SnapshotID: aws.StringValue(v.Snapshot.SnapshotId),
+			Kind:       v.Kind,
+			Snapshot:   v.Snapshot,
+		}
+		if v.Error != nil {
+			entry.Error = v.Error.Error()
+		}
+		r.Failed = append(r.Failed, entry)
+	}
+	return r
+}
+
+// SetCopySnapshotIDs sets the ids of the copies, keyed by the source snapshot id.
+func (r *Result) SetCopySnapshotIDs(copies map[string]string) {
+	for _, entries := range [][]*ResultEntry{r.Successful, r.Failed} {
+		for _, v := range entries {
+			v.CopySnapshotID = copies[v.SnapshotID]
+		}
+	}
+}
+
+// CopySnapshotIDs returns the ids of the copies, keyed by the source snapshot
+// id, so that a retry waits for them instead of copying again.
+func (r *Result) CopySnapshotIDs() map[string]string {
+	copies := make(map[string]string)
+	for _, entries := range [][]*ResultEntry{r.Successful, r.Failed} {
+		for _, v := range entries {
+			if v.CopySnapshotID != "" {
+				copies[v.SnapshotID] = v.CopySnapshotID
+			}
+		}
+	}
+	return copies
+}
+
+func ReadResult(r io.Reader) (*Result, error) {
+	var result Result
+	if err := json.NewDecoder(r).Decode(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (r *Result) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+func (r *Result) RetryableSnapshots() []*ec2.Snapshot {
+	var snapshots []*ec2.Snapshot
+	for _, v := range r.Failed {
+		if !v.Kind.Retryable() {
+			continue
+		}
+		snapshot := v.Snapshot
+		if snapshot == nil {
+			snapshot = &ec2.Snapshot{SnapshotId: aws.String(v.SnapshotID)}
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots
+}
@@ -0,0 +1,110 @@
+package snapshot
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func TestNewResult(t *testing.T) {
+	startTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	successful := []*ec2.Snapshot{
+		newSnapshot("snap-1", startTime, nil),
+	}
+	failed := []*ErrorWithSnapshot{
+		{
+			Error:    errors.New("foo"),
+			Kind:     FailureKindInUse,
+			Snapshot: newSnapshot("snap-2", startTime, nil),
+		},
+	}
+	want := &Result{
+		RunID: "run-1",
+		Successful: []*ResultEntry{
+			{SnapshotID: "snap-1", Snapshot: successful[0]},
+		},
+		Failed: []*ResultEntry{
+			{SnapshotID: "snap-2", Kind: FailureKindInUse, Error: "foo", Snapshot: failed[0].Snapshot},
+		},
+	}
+	if got := NewResult("run-1", successful, failed); !reflect.DeepEqual(got, want) {
+		t.Errorf("NewResult() = %v, want %v", got, want)
+	}
+}
+
+func TestReadResult(t *testing.T) {
+	startTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	want := NewResult("run-1", nil, []*ErrorWithSnapshot{
+		{
+			Error:    errors.New("foo"),
+			Kind:     FailureKindRetryable,
+			Snapshot: newSnapshot("snap-1", startTime, []string{"Name", "foo"}),
+		},
+	})
+	want.RetryOf = "run-0"
+	want.Region = "us-east-1"
+	var buf bytes.Buffer
+	if err := want.Write(&buf); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	got, err := ReadResult(&buf)
+	if err != nil {
+		t.Fatalf("ReadResult() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadResult() = %v, want %v", got, want)
+	}
+}
+
+func TestResult_RetryableSnapshots(t *testing.T) {
+	r := &Result{
+		Failed: []*ResultEntry{
+			{SnapshotID: "snap-1", Kind: FailureKindRetryable, Snapshot: &ec2.Snapshot{SnapshotId: aws.String("snap-1")}},
+			{SnapshotID: "snap-2", Kind: FailureKindInUse},
+			{SnapshotID: "snap-3", Kind: FailureKindNotFound},
+			{SnapshotID: "snap-4", Kind: FailureKindRetryable},
+		},
+	}
+	var got []string
+	for _, v := range r.RetryableSnapshots() {
+		got = append(got, aws.StringValue(v.SnapshotId))
+	}
+	want := []string{"snap-1", "snap-4"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("RetryableSnapshots() = %v, want %v", got, want)
+	}
+}
+
+func TestResult_SetCopySnapshotIDs(t *testing.T) {
+	startTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := NewResult("run-1",
+		[]*ec2.Snapshot{newSnapshot("snap-1", startTime, nil)},
+		[]*ErrorWithSnapshot{{Snapshot: newSnapshot("snap-2", startTime, nil), Error: errors.New("in use")}})
+	r.SetCopySnapshotIDs(map[string]string{"snap-1": "snap-copy-1", "snap-2": "snap-copy-2"})
+	if got := r.Successful[0].CopySnapshotID; got != "snap-copy-1" {
+		t.Errorf("SetCopySnapshotIDs() successful = %v, want %v", got, "snap-copy-1")
+	}
+	if got := r.Failed[0].CopySnapshotID; got != "snap-copy-2" {
+		t.Errorf("SetCopySnapshotIDs() failed = %v, want %v", got, "snap-copy-2")
+	}
+}
+
+func TestResult_CopySnapshotIDs(t *testing.T) {
+	startTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := NewResult("run-1",
+		[]*ec2.Snapshot{newSnapshot("snap-1", startTime, nil)},
+		[]*ErrorWithSnapshot{
+			{Snapshot: newSnapshot("snap-2", startTime, nil), Error: errors.New("failed to wait for copy")},
+			{Snapshot: newSnapshot("snap-3", startTime, nil), Error: errors.New("failed to copy")},
+		})
+	r.SetCopySnapshotIDs(map[string]string{"snap-1": "snap-copy-1", "snap-2": "snap-copy-2"})
+	want := map[string]string{"snap-1": "snap-copy-1", "snap-2": "snap-copy-2"}
+	if got := r.CopySnapshotIDs(); !reflect.DeepEqual(got, want) {
+		t.Errorf("CopySnapshotIDs() = %v, want %v", got, want)
+	}
+}
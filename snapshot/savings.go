@@ -0,0 +1,88 @@
+package snapshot
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+//go:embed prices.json
+var defaultPricesJSON []byte
+
+// Price is the monthly storage price in USD per GiB.
+type Price struct {
+	Standard float64 `json:"standard"`
+	Archive  float64 `json:"archive"`
+}
+
+type Prices map[string]Price
+
+func DefaultPrices() Prices {
+	var prices Prices
+	if err := json.Unmarshal(defaultPricesJSON, &prices); err != nil {
+		panic(err)
+	}
+	return prices
+}
+
+func ReadPrices(r io.Reader) (Prices, error) {
+	var prices Prices
+	if err := json.NewDecoder(r).Decode(&prices); err != nil {
+		return nil, err
+	}
+	return prices, nil
+}
+
+type Savings struct {
+	Total    float64            `json:"total"`
+	ByRegion map[string]float64 `json:"by_region"`
+	TagKey   string             `json:"tag_key,omitempty"`
+	ByTag    map[string]float64 `json:"by_tag,omitempty"`
+}
+
+func EstimateSavings(region string, snapshots []*ec2.Snapshot, prices Prices, tagKey string) (*Savings, error) {
+	price, ok := prices[region]
+	if !ok {
+		return nil, fmt.Errorf("no price for region: %s", region)
+	}
+	s := &Savings{
+		ByRegion: map[string]float64{region: 0},
+		TagKey:   tagKey,
+	}
+	if tagKey != "" {
+		s.ByTag = make(map[string]float64)
+	}
+	for _, snapshot := range snapshots {
+		perGiB := price.Standard
+		if aws.StringValue(snapshot.StorageTier) == ec2.StorageTierArchive {
+			perGiB = price.Archive
+		}
+		cost := snapshotSizeGiB(snapshot) * perGiB
+		s.Total += cost
+		s.ByRegion[region] += cost
+		if tagKey != "" {
+			s.ByTag[tagValue(snapshot, tagKey)] += cost
+		}
+	}
+	return s, nil
+}
+
+// snapshotSizeGiB uses VolumeSize because aws-sdk-go v1 does not model
+// FullSnapshotSizeInBytes on ec2.Snapshot. That overestimates incremental
+// snapshots, which only store changed blocks.
+func snapshotSizeGiB(snapshot *ec2.Snapshot) float64 {
+	return float64(aws.Int64Value(snapshot.VolumeSize))
+}
+
+func tagValue(snapshot *ec2.Snapshot, key string) string {
+	for _, tag := range snapshot.Tags {
+		if aws.StringValue(tag.Key) == key {
+			return aws.StringValue(tag.Value)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,108 @@
+package snapshot
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func TestDefaultPrices(t *testing.T) {
+	prices := DefaultPrices()
+	price, ok := prices["us-east-1"]
+	if !ok {
+		t.Fatalf("DefaultPrices() has no us-east-1")
+	}
+	if price.Standard <= price.Archive {
+		t.Errorf("DefaultPrices() standard = %v, archive = %v", price.Standard, price.Archive)
+	}
+}
+
+func TestReadPrices(t *testing.T) {
+	got, err := ReadPrices(strings.NewReader(`{"us-east-1": {"standard": 0.05, "archive": 0.0125}}`))
+	if err != nil {
+		t.Fatalf("ReadPrices() error = %v", err)
+	}
+	want := Prices{"us-east-1": {Standard: 0.05, Archive: 0.0125}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadPrices() = %v, want %v", got, want)
+	}
+}
+
+func TestEstimateSavings(t *testing.T) {
+	startTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	newSizedSnapshot := func(id string, size int64, tier string, tagSet []string) *ec2.Snapshot {
+		s := newSnapshot(id, startTime, tagSet)
+		s.VolumeSize = aws.Int64(size)
+		s.StorageTier = aws.String(tier)
+		return s
+	}
+	prices := Prices{"us-east-1": {Standard: 0.05, Archive: 0.01}}
+	type args struct {
+		region    string
+		snapshots []*ec2.Snapshot
+		tagKey    string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    *Savings
+		wantErr bool
+	}{
+		{
+			name: "standard and archive",
+			args: args{
+				region: "us-east-1",
+				snapshots: []*ec2.Snapshot{
+					newSizedSnapshot("snap-1", 100, ec2.StorageTierStandard, nil),
+					newSizedSnapshot("snap-2", 100, ec2.StorageTierArchive, nil),
+				},
+			},
+			want: &Savings{
+				Total:    6,
+				ByRegion: map[string]float64{"us-east-1": 6},
+			},
+		},
+		{
+			name: "by tag",
+			args: args{
+				region: "us-east-1",
+				snapshots: []*ec2.Snapshot{
+					newSizedSnapshot("snap-1", 100, ec2.StorageTierStandard, []string{"Team", "foo"}),
+					newSizedSnapshot("snap-2", 20, ec2.StorageTierStandard, []string{"Team", "bar"}),
+					newSizedSnapshot("snap-3", 40, ec2.StorageTierStandard, nil),
+				},
+				tagKey: "Team",
+			},
+			want: &Savings{
+				Total:    8,
+				ByRegion: map[string]float64{"us-east-1": 8},
+				TagKey:   "Team",
+				ByTag:    map[string]float64{"foo": 5, "bar": 1, "": 2},
+			},
+		},
+		{
+			name: "unknown region",
+			args: args{
+				region: "deadbeef",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := EstimateSavings(tt.args.region, tt.args.snapshots, prices, tt.args.tagKey)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("EstimateSavings() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("EstimateSavings() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
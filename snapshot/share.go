@@ -0,0 +1,105 @@
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+var errUnshareWithoutShared = errors.New("unshare action requires shared")
+
+type Shared string
+
+const (
+	// SharedPublic selects snapshots which everyone can create volumes from.
+	SharedPublic Shared = "public"
+	// SharedExternal selects snapshots shared with specific accounts.
+	SharedExternal Shared = "external"
+	SharedAny      Shared = "any"
+)
+
+func (s Shared) validate() error {
+	switch s {
+	case "", SharedPublic, SharedExternal, SharedAny:
+		return nil
+	}
+	return fmt.Errorf("invalid shared: %s", s)
+}
+
+// matches returns the permissions which the shared selects.
+func (s Shared) matches(permissions []*ec2.CreateVolumePermission) []*ec2.CreateVolumePermission {
+	var matches []*ec2.CreateVolumePermission
+	for _, p := range permissions {
+		public := aws.StringValue(p.Group) == ec2.PermissionGroupAll
+		switch {
+		case s == SharedAny, s == SharedPublic && public, s == SharedExternal && !public:
+			matches = append(matches, p)
+		}
+	}
+	return matches
+}
+
+type Action string
+
+const (
+	ActionDelete  Action = "delete"
+	ActionUnshare Action = "unshare"
+)
+
+func (a Action) validate() error {
+	switch a {
+	case "", ActionDelete, ActionUnshare:
+		return nil
+	}
+	return fmt.Errorf("invalid action: %s", a)
+}
+
+func (c *BullDelete) sharedPermissions(ctx context.Context, snapshot *ec2.Snapshot) ([]*ec2.CreateVolumePermission, error) {
+	out, err := c.svc.DescribeSnapshotAttributeWithContext(ctx, &ec2.DescribeSnapshotAttributeInput{
+		Attribute:  aws.String(ec2.SnapshotAttributeNameCreateVolumePermission),
+		SnapshotId: snapshot.SnapshotId,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c.shared.matches(out.CreateVolumePermissions), nil
+}
+
+func (c *BullDelete) sharedSelector() *selector {
+	return &selector{
+		name:   "shared",
+		detail: string(c.shared),
+		match: func(ctx context.Context, snapshot *ec2.Snapshot) (bool, error) {
+			permissions, err := c.sharedPermissions(ctx, snapshot)
+			if err != nil {
+				return false, fmt.Errorf("failed to describe permissions of %s: %w", aws.StringValue(snapshot.SnapshotId), err)
+			}
+			return len(permissions) > 0, nil
+		},
+	}
+}
+
+func (c *BullDelete) filterShared(ctx context.Context, snapshots []*ec2.Snapshot) ([]*ec2.Snapshot, error) {
+	return c.selectSnapshots(ctx, snapshots, []*selector{c.sharedSelector()})
+}
+
+// unshareSnapshot removes the permissions which the shared selects, so that
+// a snapshot shared with both everyone and an account keeps the account with
+// SharedPublic.
+func (c *BullDelete) unshareSnapshot(ctx context.Context, snapshot *ec2.Snapshot) error {
+	permissions, err := c.sharedPermissions(ctx, snapshot)
+	if err != nil || len(permissions) == 0 {
+		return err
+	}
+	_, err = c.svc.ModifySnapshotAttributeWithContext(ctx, &ec2.ModifySnapshotAttributeInput{
+		Attribute:  aws.String(ec2.SnapshotAttributeNameCreateVolumePermission),
+		SnapshotId: snapshot.SnapshotId,
+		CreateVolumePermission: &ec2.CreateVolumePermissionModifications{
+			Remove: permissions,
+		},
+	})
+	return err
+}
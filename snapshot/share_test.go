@@ -0,0 +1,127 @@
+package snapshot
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+var (
+	publicPermission   = &ec2.CreateVolumePermission{Group: aws.String("all")}
+	externalPermission = &ec2.CreateVolumePermission{UserId: aws.String("123456789012")}
+)
+
+func TestShared_matches(t *testing.T) {
+	permissions := []*ec2.CreateVolumePermission{publicPermission, externalPermission}
+	tests := []struct {
+		shared Shared
+		want   []*ec2.CreateVolumePermission
+	}{
+		{shared: SharedPublic, want: []*ec2.CreateVolumePermission{publicPermission}},
+		{shared: SharedExternal, want: []*ec2.CreateVolumePermission{externalPermission}},
+		{shared: SharedAny, want: permissions},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.shared), func(t *testing.T) {
+			if got := tt.shared.matches(permissions); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func newSharedSnapshotsMock(permissions map[string][]*ec2.CreateVolumePermission, removed map[string][]*ec2.CreateVolumePermission) *ec2SnapshotAPIMock {
+	return &ec2SnapshotAPIMock{
+		DescribeSnapshotAttributeWithContextFunc: func(ctx aws.Context, input *ec2.DescribeSnapshotAttributeInput, opts ...request.Option) (*ec2.DescribeSnapshotAttributeOutput, error) {
+			return &ec2.DescribeSnapshotAttributeOutput{
+				SnapshotId:              input.SnapshotId,
+				CreateVolumePermissions: permissions[aws.StringValue(input.SnapshotId)],
+			}, nil
+		},
+		ModifySnapshotAttributeWithContextFunc: func(ctx aws.Context, input *ec2.ModifySnapshotAttributeInput, opts ...request.Option) (*ec2.ModifySnapshotAttributeOutput, error) {
+			removed[aws.StringValue(input.SnapshotId)] = input.CreateVolumePermission.Remove
+			return &ec2.ModifySnapshotAttributeOutput{}, nil
+		},
+	}
+}
+
+func TestBullDelete_filterShared(t *testing.T) {
+	startTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	permissions := map[string][]*ec2.CreateVolumePermission{
+		"snap-public":   {publicPermission},
+		"snap-external": {externalPermission},
+	}
+	snapshots := []*ec2.Snapshot{
+		newSnapshot("snap-private", startTime, nil),
+		newSnapshot("snap-public", startTime, nil),
+		newSnapshot("snap-external", startTime, nil),
+	}
+	tests := []struct {
+		shared Shared
+		want   []string
+	}{
+		{shared: SharedPublic, want: []string{"snap-public"}},
+		{shared: SharedExternal, want: []string{"snap-external"}},
+		{shared: SharedAny, want: []string{"snap-public", "snap-external"}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.shared), func(t *testing.T) {
+			c := &BullDelete{shared: tt.shared, svc: newSharedSnapshotsMock(permissions, nil)}
+			got, err := c.filterShared(context.Background(), snapshots)
+			if err != nil {
+				t.Fatalf("filterShared() error = %v", err)
+			}
+			var ids []string
+			for _, v := range got {
+				ids = append(ids, aws.StringValue(v.SnapshotId))
+			}
+			if !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("filterShared() = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestBullDelete_deleteSnapshots_unshare(t *testing.T) {
+	startTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	permissions := map[string][]*ec2.CreateVolumePermission{
+		"snap-1": {publicPermission, externalPermission},
+	}
+	removed := make(map[string][]*ec2.CreateVolumePermission)
+	c := &BullDelete{
+		shared: SharedPublic,
+		action: ActionUnshare,
+		svc:    newSharedSnapshotsMock(permissions, removed),
+	}
+	successful, failed, err := c.deleteSnapshots(context.Background(), []*ec2.Snapshot{
+		newSnapshot("snap-1", startTime, nil),
+		newSnapshot("snap-2", startTime, nil),
+	}, nil, Options{})
+	if err != nil {
+		t.Fatalf("deleteSnapshots() error = %v", err)
+	}
+	if len(successful) != 2 || len(failed) != 0 {
+		t.Errorf("deleteSnapshots() successful = %d, failed = %d", len(successful), len(failed))
+	}
+	want := map[string][]*ec2.CreateVolumePermission{
+		"snap-1": {publicPermission},
+	}
+	if !reflect.DeepEqual(removed, want) {
+		t.Errorf("deleteSnapshots() removed = %v, want %v", removed, want)
+	}
+}
+
+func TestNewBulkDelete_unshare(t *testing.T) {
+	_, err := NewBulkDelete(&BulkDeleteConfig{Age: 10, Action: ActionUnshare})
+	if err != errUnshareWithoutShared {
+		t.Errorf("NewBulkDelete() error = %v, want %v", err, errUnshareWithoutShared)
+	}
+	if _, err := NewBulkDelete(&BulkDeleteConfig{Shared: SharedPublic, Action: ActionUnshare}); err != nil {
+		t.Errorf("NewBulkDelete() error = %v", err)
+	}
+}
@@ -2,16 +2,29 @@ package snapshot
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"sort"
 	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
 
+	"github.com/aws/aws-sdk-go/service/backup"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/recyclebin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var errNoAgeOrTags = errors.New("no selector specified (eg. age, tags, state or snapshot ids)")
+
+// ErrCancelled ends a run which the user declined, eg. at the confirmation
+// prompt. The hooks tell it apart from a failed run.
+var ErrCancelled = errors.New("cancelled")
+
 type BulkDeleteConfig struct {
 	Region          string
 	Profile         string
@@ -24,10 +37,61 @@ type BulkDeleteConfig struct {
 
 	Age  uint
 	Tags []string
+
+	// OlderThan is a duration such as 36h or 2w, and CreatedBefore and
+	// CreatedAfter are RFC 3339 times or dates.
+	OlderThan     string
+	CreatedBefore string
+	CreatedAfter  string
+
+	SnapshotIDs []string
+
+	// MinSize and MaxSize are GiB, and Encrypted is "true" or "false".
+	MinSize          int64
+	MaxSize          int64
+	Encrypted        string
+	KmsKeyID         string
+	StorageTier      string
+	State            string
+	VolumeID         string
+	DescriptionRegex string
+
+	Shared Shared
+	// Action is ActionDelete by default. ActionUnshare removes the permissions
+	// selected by Shared instead of deleting.
+	Action Action
+
+	RetryPolicies map[FailureKind]RetryPolicy
+
+	RequireRecycleBin bool
+	UnlockGovernance  bool
+	IncludeManaged    bool
+
+	LaunchTemplateVersions LaunchTemplateVersions
+
+	// CopyToRegion and CopyToAccount copy each snapshot before deleting it.
+	// CopyToAccount needs CopyRoleARN, a role in the account to copy with,
+	// and CopyKmsKeyID re-encrypts the copy with the key of the target.
+	CopyToRegion  string
+	CopyToAccount string
+	CopyRoleARN   string
+	CopyKmsKeyID  string
+	// CopySnapshotIDs holds the copies made by an earlier run, keyed by the
+	// source snapshot id, eg. from a Result. They are waited for instead of
+	// copied again.
+	CopySnapshotIDs map[string]string
+
+	// Logger logs the decisions on each snapshot, retries and timing.
+	// Nothing is logged if it is nil.
+	Logger *slog.Logger `json:"-"`
 }
 
 func (cfg *BulkDeleteConfig) hasAgeOrTags() bool {
-	return cfg.Age > 0 || len(cfg.Tags) > 0
+	return cfg.Age > 0 || len(cfg.Tags) > 0 ||
+		cfg.OlderThan != "" || cfg.CreatedBefore != "" || cfg.CreatedAfter != "" ||
+		len(cfg.SnapshotIDs) > 0 || cfg.MinSize > 0 || cfg.MaxSize > 0 || cfg.Encrypted != "" ||
+		cfg.KmsKeyID != "" || cfg.StorageTier != "" || cfg.State != "" || cfg.VolumeID != "" ||
+		cfg.DescriptionRegex != "" || cfg.Shared != ""
 }
 
 func (cfg *BulkDeleteConfig) awsConfig() *awsConfig {
@@ -43,22 +107,71 @@ func (cfg *BulkDeleteConfig) awsConfig() *awsConfig {
 
 func NewBulkDelete(cfg *BulkDeleteConfig) (*BullDelete, error) {
 	if !cfg.hasAgeOrTags() {
-		return nil, fmt.Errorf("both age and tags not specified")
+		return nil, errNoAgeOrTags
 	}
+	return NewApply(cfg)
+}
+
+// NewApply returns a BullDelete which does not require age or tags.
+// It is meant for ApplyWithOptions, which deletes the given snapshots as-is.
+func NewApply(cfg *BulkDeleteConfig) (*BullDelete, error) {
 	tags, err := tagsMap(cfg.Tags)
 	if err != nil {
 		return nil, err
 	}
-	client, err := newEC2SnapshotAPI(cfg.awsConfig())
+	timeRange, err := cfg.timeRange()
 	if err != nil {
 		return nil, err
 	}
-	return &BullDelete{
-		age:  cfg.Age,
-		tags: tags,
-		plan: cfg.Plan,
-		svc:  client,
-	}, nil
+	attributeFilters, err := cfg.attributeFilters()
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.LaunchTemplateVersions.validate(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Shared.validate(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Action.validate(); err != nil {
+		return nil, err
+	}
+	if cfg.Action == ActionUnshare && cfg.Shared == "" {
+		return nil, errUnshareWithoutShared
+	}
+	c := &BullDelete{
+		age:                    cfg.Age,
+		timeRange:              timeRange,
+		snapshotIDs:            cfg.SnapshotIDs,
+		attributeFilters:       attributeFilters,
+		shared:                 cfg.Shared,
+		action:                 cfg.Action,
+		tags:                   tags,
+		plan:                   cfg.Plan,
+		retryPolicies:          cfg.RetryPolicies,
+		requireRecycleBin:      cfg.RequireRecycleBin,
+		unlockGovernance:       cfg.UnlockGovernance,
+		includeManaged:         cfg.IncludeManaged,
+		launchTemplateVersions: cfg.LaunchTemplateVersions,
+		logger:                 cfg.Logger,
+	}
+	sess, err := newAWSSession(cfg.awsConfig())
+	if err != nil {
+		return nil, err
+	}
+	// Clients copy the handlers of the session, so this has to be added
+	// before any client is created.
+	sess.Handlers.Validate.PushFront(startAPISpan)
+	sess.Handlers.Complete.PushBack(c.afterAPICall)
+	sess.Handlers.Complete.PushBack(endAPISpan)
+	c.copyTarget, err = cfg.copyTarget(sess)
+	if err != nil {
+		return nil, err
+	}
+	c.svc = ec2.New(sess)
+	c.rbin = recyclebin.New(sess)
+	c.backup = backup.New(sess)
+	return c, nil
 }
 
 func tagsMap(tags []string) (map[string]string, error) {
@@ -74,18 +187,53 @@ func tagsMap(tags []string) (map[string]string, error) {
 }
 
 type BullDelete struct {
-	age  uint
-	tags map[string]string
-	plan bool
-	svc  EC2SnapshotAPI
+	age                    uint
+	timeRange              *timeRange
+	snapshotIDs            []string
+	attributeFilters       *attributeFilters
+	shared                 Shared
+	action                 Action
+	copyTarget             *copyTarget
+	tags                   map[string]string
+	plan                   bool
+	retryPolicies          map[FailureKind]RetryPolicy
+	requireRecycleBin      bool
+	unlockGovernance       bool
+	includeManaged         bool
+	launchTemplateVersions LaunchTemplateVersions
+	svc                    EC2SnapshotAPI
+	rbin                   RecycleBinAPI
+	backup                 BackupAPI
+	logger                 *slog.Logger
+	afterAPICallFunc       func(call *APICall)
 }
 
 type Options struct {
 	BeforeDescribeSnapshotsFunc func() error
 	AfterDescribeSnapshotsFunc  func(snapshots []*ec2.Snapshot) error
+	AfterPlanFunc               func(plan *Plan) error
 	BeforeDeleteSnapshotsFunc   func(snapshots []*ec2.Snapshot) error
 	EachDeleteSnapshotsFunc     func(snapshot *ec2.Snapshot) error
+	EachCopySnapshotFunc        func(snapshot *ec2.Snapshot, copySnapshotID string) error
 	AfterDeleteSnapshotsFunc    func(successful []*ec2.Snapshot, failed []*ErrorWithSnapshot) error
+	// AfterAPICallFunc is called after each AWS API call, including retries
+	// of the SDK.
+	AfterAPICallFunc func(call *APICall)
+	// AfterRunFunc is called once the run ends, with the error which ended
+	// it, eg. of a hook, DescribeSnapshots or the interruption of the context.
+	AfterRunFunc func(err error)
+}
+
+type APICall struct {
+	Service   string
+	Operation string
+	Latency   time.Duration
+	Err       error
+}
+
+func (c *BullDelete) hasAgeOrTags() bool {
+	return c.age > 0 || len(c.tags) > 0 || (c.timeRange != nil && *c.timeRange != timeRange{}) ||
+		len(c.snapshotIDs) > 0 || (c.attributeFilters != nil && !c.attributeFilters.empty()) || c.shared != ""
 }
 
 func (c *BullDelete) Run(ctx context.Context) error {
@@ -93,7 +241,46 @@ func (c *BullDelete) Run(ctx context.Context) error {
 }
 
 func (c *BullDelete) RunWithOptions(ctx context.Context, opts Options) error {
+	if !c.hasAgeOrTags() {
+		return errNoAgeOrTags
+	}
+	return c.run(ctx, opts, func(ctx context.Context) ([]*ec2.Snapshot, []string, error) {
+		return c.describeSnapshots(ctx, c.tags, c.age)
+	})
+}
+
+// Describe returns the snapshots matching age and tags without deleting them.
+func (c *BullDelete) Describe(ctx context.Context) ([]*ec2.Snapshot, error) {
+	if !c.hasAgeOrTags() {
+		return nil, errNoAgeOrTags
+	}
+	snapshots, _, err := c.describeSnapshots(setNow(ctx), c.tags, c.age)
+	return snapshots, err
+}
+
+func (c *BullDelete) ApplyWithOptions(ctx context.Context, snapshots []*ec2.Snapshot, opts Options) error {
+	return c.run(ctx, opts, func(ctx context.Context) ([]*ec2.Snapshot, []string, error) {
+		return snapshots, nil, nil
+	})
+}
+
+func (c *BullDelete) run(ctx context.Context, opts Options, describe func(ctx context.Context) ([]*ec2.Snapshot, []string, error)) error {
+	ctx, span := tracer().Start(ctx, "BullDelete.run", trace.WithAttributes(
+		attribute.String("action", string(c.actionOrDefault())),
+		attribute.Bool("plan", c.plan),
+	))
+	err := c.runPipeline(ctx, opts, describe)
+	endSpan(span, err)
+	if opts.AfterRunFunc != nil {
+		opts.AfterRunFunc(err)
+	}
+	return err
+}
+
+func (c *BullDelete) runPipeline(ctx context.Context, opts Options, describe func(ctx context.Context) ([]*ec2.Snapshot, []string, error)) error {
 	ctx = setNow(ctx)
+	c.afterAPICallFunc = opts.AfterAPICallFunc
+	defer func() { c.afterAPICallFunc = nil }()
 	if opts.BeforeDescribeSnapshotsFunc != nil {
 		err := opts.BeforeDescribeSnapshotsFunc()
 		if err != nil {
@@ -101,10 +288,32 @@ func (c *BullDelete) RunWithOptions(ctx context.Context, opts Options) error {
 		}
 	}
 
-	snapshots, err := c.describeSnapshots(ctx, c.tags, c.age)
+	startedAt := time.Now()
+	describeCtx, describeSpan := tracer().Start(ctx, "describeSnapshots")
+	described, notFound, err := describe(describeCtx)
+	describeSpan.SetAttributes(attrSnapshots.Int(len(described)))
+	endSpan(describeSpan, err)
+	if err != nil {
+		return err
+	}
+	c.log().Info("described snapshots", slog.Int("count", len(described)), slog.Duration("duration", time.Since(startedAt)))
+
+	planStartedAt := time.Now()
+	planCtx, planSpan := tracer().Start(ctx, "buildPlan")
+	plan, err := c.buildPlan(planCtx, described)
+	endSpan(planSpan, err)
 	if err != nil {
 		return err
 	}
+	plan.NotFound = notFound
+	snapshots := plan.Snapshots
+	c.logPlan(plan)
+	c.log().Info("built plan", slog.Int("planned", len(snapshots)), slog.Int("excluded", len(plan.Excluded)),
+		slog.Int("not_found", len(notFound)), slog.Duration("duration", time.Since(planStartedAt)))
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.Int("snapshots.planned", len(snapshots)),
+		attribute.Int("snapshots.excluded", len(plan.Excluded)),
+	)
 
 	if opts.AfterDescribeSnapshotsFunc != nil {
 		err := opts.AfterDescribeSnapshotsFunc(snapshots)
@@ -113,6 +322,13 @@ func (c *BullDelete) RunWithOptions(ctx context.Context, opts Options) error {
 		}
 	}
 
+	if opts.AfterPlanFunc != nil {
+		err := opts.AfterPlanFunc(plan)
+		if err != nil {
+			return err
+		}
+	}
+
 	if c.plan {
 		return nil
 	}
@@ -124,10 +340,17 @@ func (c *BullDelete) RunWithOptions(ctx context.Context, opts Options) error {
 		}
 	}
 
-	successful, failed, err := c.deleteSnapshots(ctx, snapshots, opts.EachDeleteSnapshotsFunc)
-	if err != nil {
+	successful, failed, err := c.deleteSnapshots(ctx, snapshots, plan.Unlocks, opts)
+	var interrupted *InterruptedError
+	if err != nil && !errors.As(err, &interrupted) {
 		return err
 	}
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.Int("snapshots.successful", len(successful)),
+		attribute.Int("snapshots.failed", len(failed)),
+	)
+	c.log().Info("run finished", slog.Int("successful", len(successful)), slog.Int("failed", len(failed)),
+		slog.Duration("duration", time.Since(startedAt)))
 
 	if opts.AfterDeleteSnapshotsFunc != nil {
 		err := opts.AfterDeleteSnapshotsFunc(successful, failed)
@@ -136,50 +359,79 @@ func (c *BullDelete) RunWithOptions(ctx context.Context, opts Options) error {
 		}
 	}
 
+	if interrupted != nil {
+		c.log().Warn("run interrupted", slog.Int("remaining", len(interrupted.Remaining)), slog.Any("error", interrupted.Err))
+		return interrupted
+	}
 	return nil
 }
 
-func (c *BullDelete) describeSnapshots(ctx context.Context, tags map[string]string, age uint) ([]*ec2.Snapshot, error) {
-	var snapshots []*ec2.Snapshot
-	err := c.svc.DescribeSnapshotsPagesWithContext(ctx, &ec2.DescribeSnapshotsInput{
-		Filters: tagsMapEC2Filters(tags),
-	}, func(out *ec2.DescribeSnapshotsOutput, lastPage bool) bool {
-		snapshots = append(snapshots, out.Snapshots...)
-		return !lastPage
-	})
-	if err != nil {
-		return nil, err
+func (c *BullDelete) describeSnapshots(ctx context.Context, tags map[string]string, age uint) ([]*ec2.Snapshot, []string, error) {
+	var (
+		snapshots []*ec2.Snapshot
+		notFound  []string
+		err       error
+	)
+	if len(c.snapshotIDs) > 0 {
+		snapshots, notFound, err = c.describeSnapshotsByIDs(ctx, c.snapshotIDs)
+	} else {
+		var filters []*ec2.Filter
+		ownerIDs := c.ownerIDs()
+		if decisions(ctx) == nil {
+			filters = tagsMapEC2Filters(tags)
+			if c.attributeFilters != nil {
+				filters = append(filters, c.attributeFilters.ec2Filters()...)
+			}
+		} else if ownerIDs == nil {
+			// Explain describes the snapshots which the filters drop too, so
+			// that the selectors record why they are excluded. Without any
+			// filter, only the owner keeps the public snapshots out.
+			ownerIDs = aws.StringSlice([]string{"self"})
+		}
+		err = c.svc.DescribeSnapshotsPagesWithContext(ctx, &ec2.DescribeSnapshotsInput{
+			Filters:  filters,
+			OwnerIds: ownerIDs,
+		}, func(out *ec2.DescribeSnapshotsOutput, lastPage bool) bool {
+			snapshots = append(snapshots, out.Snapshots...)
+			return !lastPage
+		})
 	}
-	if age > 0 {
-		expireDate := now(ctx).Add(-time.Duration(age) * 24 * time.Hour)
-		snapshots = filterSnapshots(snapshots, expiredFilterFunc(expireDate))
+	if err != nil {
+		return nil, nil, err
 	}
-	if len(tags) > 0 {
-		snapshots = filterSnapshots(snapshots, tagsFilterFunc(tags))
+	decisions(ctx).discover(snapshots, notFound)
+	snapshots, err = c.selectSnapshots(ctx, snapshots, c.selectors(ctx, tags, age))
+	if err != nil {
+		return nil, nil, err
 	}
 	sort.Slice(snapshots, func(i, j int) bool {
 		return snapshots[i].StartTime.Before(*snapshots[j].StartTime)
 	})
-	return snapshots, nil
+	return snapshots, notFound, nil
 }
 
 func (c *BullDelete) deleteSnapshots(ctx context.Context,
-	snapshots []*ec2.Snapshot, eachFunc func(snapshot *ec2.Snapshot) error) ([]*ec2.Snapshot, []*ErrorWithSnapshot, error) {
+	snapshots []*ec2.Snapshot, unlocks map[string]*ec2.LockedSnapshotsInfo, opts Options) ([]*ec2.Snapshot, []*ErrorWithSnapshot, error) {
 	var (
 		successful []*ec2.Snapshot
 		failed     []*ErrorWithSnapshot
 	)
-	for _, snapshot := range snapshots {
-		_, err := c.svc.DeleteSnapshotWithContext(ctx, &ec2.DeleteSnapshotInput{
-			SnapshotId: snapshot.SnapshotId,
-		})
+	for i, snapshot := range snapshots {
+		if err := ctx.Err(); err != nil {
+			return successful, failed, &InterruptedError{Remaining: snapshots[i:], Err: err}
+		}
+		err := c.processSnapshot(ctx, snapshot, unlocks, opts)
+		if err != nil && ctx.Err() != nil {
+			// The snapshot did not fail, the run was interrupted.
+			return successful, failed, &InterruptedError{Remaining: snapshots[i:], Err: ctx.Err()}
+		}
 		if err != nil {
-			failed = append(failed, &ErrorWithSnapshot{Snapshot: snapshot, Error: err})
+			failed = append(failed, &ErrorWithSnapshot{Snapshot: snapshot, Error: err, Kind: ClassifyError(err)})
 			continue
 		}
 		successful = append(successful, snapshot)
-		if eachFunc != nil {
-			err := eachFunc(snapshot)
+		if opts.EachDeleteSnapshotsFunc != nil {
+			err := opts.EachDeleteSnapshotsFunc(snapshot)
 			if err != nil {
 				return nil, nil, err
 			}
@@ -188,6 +440,104 @@ func (c *BullDelete) deleteSnapshots(ctx context.Context,
 	return successful, failed, nil
 }
 
+// processSnapshot applies the action to the snapshot in a span of its own.
+func (c *BullDelete) processSnapshot(ctx context.Context, snapshot *ec2.Snapshot, unlocks map[string]*ec2.LockedSnapshotsInfo, opts Options) error {
+	ctx, span := tracer().Start(ctx, "deleteSnapshot", trace.WithAttributes(snapshotAttributes(snapshot)...))
+	startedAt := time.Now()
+	var err error
+	if c.action == ActionUnshare {
+		err = c.withRetry(ctx, func() error {
+			return c.unshareSnapshot(ctx, snapshot)
+		})
+	} else {
+		if c.copyTarget != nil {
+			err = c.copyBeforeDelete(ctx, snapshot, opts.EachCopySnapshotFunc)
+		}
+		if _, ok := unlocks[aws.StringValue(snapshot.SnapshotId)]; ok && err == nil {
+			err = c.unlockSnapshot(ctx, snapshot)
+		}
+		if err == nil {
+			err = c.deleteSnapshot(ctx, snapshot)
+		}
+	}
+	endSpan(span, err)
+	if err != nil {
+		c.log().Warn("snapshot failed", snapshotIDAttr(snapshot), slog.String("action", string(c.actionOrDefault())),
+			slog.String("kind", string(ClassifyError(err))), slog.Any("error", err), slog.Duration("duration", time.Since(startedAt)))
+	} else {
+		c.log().Debug("snapshot done", snapshotIDAttr(snapshot), slog.String("action", string(c.actionOrDefault())),
+			slog.Duration("duration", time.Since(startedAt)))
+	}
+	return err
+}
+
+func (c *BullDelete) actionOrDefault() Action {
+	if c.action == "" {
+		return ActionDelete
+	}
+	return c.action
+}
+
+func (c *BullDelete) afterAPICall(r *request.Request) {
+	if c.afterAPICallFunc == nil {
+		return
+	}
+	c.afterAPICallFunc(&APICall{
+		Service:   r.ClientInfo.ServiceName,
+		Operation: r.Operation.Name,
+		Latency:   time.Since(r.Time),
+		Err:       r.Error,
+	})
+}
+
+func (c *BullDelete) deleteSnapshot(ctx context.Context, snapshot *ec2.Snapshot) error {
+	return c.withRetry(ctx, func() error {
+		_, err := c.svc.DeleteSnapshotWithContext(ctx, &ec2.DeleteSnapshotInput{
+			SnapshotId: snapshot.SnapshotId,
+		})
+		return err
+	})
+}
+
+func (c *BullDelete) withRetry(ctx context.Context, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+		kind := ClassifyError(err)
+		policy := c.retryPolicy(kind)
+		if attempt >= policy.MaxAttempts {
+			return err
+		}
+		delay := policy.backoff(attempt)
+		c.log().Warn("retrying", slog.String("kind", string(kind)), slog.Int("attempt", attempt),
+			slog.Int("max_attempts", policy.MaxAttempts), slog.Duration("delay", delay), slog.Any("error", err))
+		if sleepErr := sleep(ctx, delay); sleepErr != nil {
+			return err
+		}
+	}
+}
+
+// ownerIDs limits DescribeSnapshots to the caller's snapshots when it needs
+// to describe their attributes, which is not allowed for others' snapshots.
+func (c *BullDelete) ownerIDs() []*string {
+	if c.shared == "" {
+		return nil
+	}
+	return aws.StringSlice([]string{"self"})
+}
+
+func (c *BullDelete) retryPolicy(kind FailureKind) RetryPolicy {
+	if p, ok := c.retryPolicies[kind]; ok {
+		return p
+	}
+	if p, ok := defaultRetryPolicies[kind]; ok {
+		return p
+	}
+	return RetryPolicy{MaxAttempts: 1}
+}
+
 func tagsMapEC2Filters(tags map[string]string) []*ec2.Filter {
 	var filters []*ec2.Filter
 	if tags == nil {
@@ -207,16 +557,6 @@ func tagsMapEC2Filters(tags map[string]string) []*ec2.Filter {
 	return filters
 }
 
-func filterSnapshots(snapshots []*ec2.Snapshot, fn filterFunc) []*ec2.Snapshot {
-	var matches []*ec2.Snapshot
-	for _, snapshot := range snapshots {
-		if fn(snapshot) {
-			matches = append(matches, snapshot)
-		}
-	}
-	return matches
-}
-
 type filterFunc = func(*ec2.Snapshot) bool
 
 func expiredFilterFunc(expireDate time.Time) filterFunc {
@@ -225,6 +565,34 @@ func expiredFilterFunc(expireDate time.Time) filterFunc {
 	}
 }
 
+// tagsEC2FilterFunc matches the snapshots which the filters of
+// tagsMapEC2Filters return, which have every key with one of its values.
+func tagsEC2FilterFunc(tags map[string]string) filterFunc {
+	return func(snapshot *ec2.Snapshot) bool {
+		values := make(map[string]string)
+		for _, tag := range snapshot.Tags {
+			values[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+		}
+		for k, v := range tags {
+			tagValue, ok := values[strings.TrimSpace(k)]
+			if !ok {
+				return false
+			}
+			var matched bool
+			for _, v := range strings.Split(v, ",") {
+				if strings.TrimSpace(v) == tagValue {
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return false
+			}
+		}
+		return true
+	}
+}
+
 func tagsFilterFunc(tags map[string]string) filterFunc {
 	return func(snapshot *ec2.Snapshot) bool {
 		for _, tag := range snapshot.Tags {
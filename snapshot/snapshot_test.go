@@ -1,8 +1,16 @@
 package snapshot
 
 import (
+	"context"
+	"errors"
 	"reflect"
 	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
 )
 
 func TestConfig_hasAgeOrTags(t *testing.T) {
@@ -270,3 +278,131 @@ func TestNewBulkDelete(t *testing.T) {
 		})
 	}
 }
+
+func TestBullDelete_deleteSnapshots(t *testing.T) {
+	startTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	errs := map[string][]error{
+		"snap-ok":        {nil},
+		"snap-throttled": {awserr.New("RequestLimitExceeded", "", nil), awserr.New("RequestLimitExceeded", "", nil), nil},
+		"snap-in-use":    {awserr.New("InvalidSnapshot.InUse", "", nil), nil},
+		"snap-gone":      {awserr.New("InvalidSnapshot.NotFound", "", nil)},
+		"snap-denied":    {awserr.New("UnauthorizedOperation", "", nil)},
+	}
+	calls := make(map[string]int)
+	c := &BullDelete{
+		retryPolicies: map[FailureKind]RetryPolicy{
+			FailureKindRetryable: {MaxAttempts: 3},
+		},
+		svc: &ec2SnapshotAPIMock{
+			DeleteSnapshotWithContextFunc: func(ctx aws.Context, input *ec2.DeleteSnapshotInput, opts ...request.Option) (*ec2.DeleteSnapshotOutput, error) {
+				id := aws.StringValue(input.SnapshotId)
+				err := errs[id][calls[id]]
+				calls[id]++
+				return &ec2.DeleteSnapshotOutput{}, err
+			},
+		},
+	}
+	snapshots := []*ec2.Snapshot{
+		newSnapshot("snap-ok", startTime, nil),
+		newSnapshot("snap-throttled", startTime, nil),
+		newSnapshot("snap-in-use", startTime, nil),
+		newSnapshot("snap-gone", startTime, nil),
+		newSnapshot("snap-denied", startTime, nil),
+	}
+	successful, failed, err := c.deleteSnapshots(context.Background(), snapshots, nil, Options{})
+	if err != nil {
+		t.Fatalf("deleteSnapshots() error = %v", err)
+	}
+	if len(successful) != 2 {
+		t.Errorf("deleteSnapshots() successful = %d, want %d", len(successful), 2)
+	}
+	wantKinds := map[string]FailureKind{
+		"snap-in-use": FailureKindInUse,
+		"snap-gone":   FailureKindNotFound,
+		"snap-denied": FailureKindDenied,
+	}
+	if len(failed) != len(wantKinds) {
+		t.Errorf("deleteSnapshots() failed = %d, want %d", len(failed), len(wantKinds))
+	}
+	for _, v := range failed {
+		id := aws.StringValue(v.Snapshot.SnapshotId)
+		if v.Kind != wantKinds[id] {
+			t.Errorf("deleteSnapshots() %s kind = %v, want %v", id, v.Kind, wantKinds[id])
+		}
+	}
+	wantCalls := map[string]int{
+		"snap-ok":        1,
+		"snap-throttled": 3,
+		"snap-in-use":    1,
+		"snap-gone":      1,
+		"snap-denied":    1,
+	}
+	if !reflect.DeepEqual(calls, wantCalls) {
+		t.Errorf("deleteSnapshots() calls = %v, want %v", calls, wantCalls)
+	}
+}
+
+func TestBullDelete_deleteSnapshots_interrupted(t *testing.T) {
+	startTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var deleted []string
+	c := &BullDelete{
+		svc: &ec2SnapshotAPIMock{
+			DeleteSnapshotWithContextFunc: func(ctx aws.Context, input *ec2.DeleteSnapshotInput, opts ...request.Option) (*ec2.DeleteSnapshotOutput, error) {
+				id := aws.StringValue(input.SnapshotId)
+				if id == "snap-2" {
+					// The deadline comes while deleting snap-2.
+					cancel()
+					return nil, awserr.New(request.CanceledErrorCode, "", ctx.Err())
+				}
+				deleted = append(deleted, id)
+				return &ec2.DeleteSnapshotOutput{}, nil
+			},
+		},
+	}
+	snapshots := []*ec2.Snapshot{
+		newSnapshot("snap-1", startTime, nil),
+		newSnapshot("snap-2", startTime, nil),
+		newSnapshot("snap-3", startTime, nil),
+	}
+	successful, failed, err := c.deleteSnapshots(ctx, snapshots, nil, Options{})
+	var ierr *InterruptedError
+	if !errors.As(err, &ierr) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("deleteSnapshots() error = %v, want InterruptedError", err)
+	}
+	var remaining []string
+	for _, v := range ierr.Remaining {
+		remaining = append(remaining, aws.StringValue(v.SnapshotId))
+	}
+	if want := []string{"snap-2", "snap-3"}; !reflect.DeepEqual(remaining, want) {
+		t.Errorf("deleteSnapshots() remaining = %v, want %v", remaining, want)
+	}
+	if len(successful) != 1 || len(failed) != 0 || !reflect.DeepEqual(deleted, []string{"snap-1"}) {
+		t.Errorf("deleteSnapshots() successful = %d, failed = %d, deleted = %v", len(successful), len(failed), deleted)
+	}
+}
+
+func TestBullDelete_ApplyWithOptions_afterRun(t *testing.T) {
+	startTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	errHook := errors.New("hook")
+	c := &BullDelete{
+		includeManaged: true,
+		svc:            newLockedSnapshotsMock(nil, startTime),
+	}
+	var got []error
+	err := c.ApplyWithOptions(context.Background(), []*ec2.Snapshot{newSnapshot("snap-1", startTime, nil)}, Options{
+		AfterPlanFunc: func(plan *Plan) error {
+			return errHook
+		},
+		AfterRunFunc: func(err error) {
+			got = append(got, err)
+		},
+	})
+	if !errors.Is(err, errHook) {
+		t.Fatalf("ApplyWithOptions() error = %v, want %v", err, errHook)
+	}
+	if !reflect.DeepEqual(got, []error{errHook}) {
+		t.Errorf("AfterRunFunc() got = %v, want %v", got, []error{errHook})
+	}
+}
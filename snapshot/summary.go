@@ -0,0 +1,112 @@
+package snapshot
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+const (
+	GroupByVolume = "volume"
+	GroupByTag    = "tag"
+	GroupByOwner  = "owner"
+	GroupByRegion = "region"
+	GroupByTier   = "tier"
+	GroupByMonth  = "month"
+)
+
+type GroupBy struct {
+	Field  string
+	TagKey string
+}
+
+func ParseGroupBy(s string) (GroupBy, error) {
+	s = strings.TrimSpace(s)
+	if k, ok := strings.CutPrefix(s, GroupByTag+":"); ok {
+		if k == "" {
+			return GroupBy{}, fmt.Errorf("invalid group by: %s", s)
+		}
+		return GroupBy{Field: GroupByTag, TagKey: k}, nil
+	}
+	switch s {
+	case GroupByVolume, GroupByOwner, GroupByRegion, GroupByTier, GroupByMonth:
+		return GroupBy{Field: s}, nil
+	}
+	return GroupBy{}, fmt.Errorf("invalid group by: %s", s)
+}
+
+func (g GroupBy) String() string {
+	if g.Field == GroupByTag {
+		return GroupByTag + ":" + g.TagKey
+	}
+	return g.Field
+}
+
+func (g GroupBy) key(region string, snapshot *ec2.Snapshot) string {
+	switch g.Field {
+	case GroupByVolume:
+		return aws.StringValue(snapshot.VolumeId)
+	case GroupByTag:
+		return tagValue(snapshot, g.TagKey)
+	case GroupByOwner:
+		return aws.StringValue(snapshot.OwnerId)
+	case GroupByRegion:
+		return region
+	case GroupByTier:
+		return aws.StringValue(snapshot.StorageTier)
+	case GroupByMonth:
+		return aws.TimeValue(snapshot.StartTime).UTC().Format("2006-01")
+	}
+	return ""
+}
+
+type Group struct {
+	Key      string
+	Count    int
+	TotalGiB int64
+	Oldest   time.Time
+	Newest   time.Time
+	Failed   int
+}
+
+// Summarize aggregates snapshots per group, sorted by key.
+// Failed snapshots are counted in both Count and Failed.
+func Summarize(region string, by GroupBy, snapshots []*ec2.Snapshot, failed []*ErrorWithSnapshot) []*Group {
+	groups := make(map[string]*Group)
+	add := func(snapshot *ec2.Snapshot) *Group {
+		k := by.key(region, snapshot)
+		g, ok := groups[k]
+		if !ok {
+			g = &Group{Key: k}
+			groups[k] = g
+		}
+		g.Count++
+		g.TotalGiB += aws.Int64Value(snapshot.VolumeSize)
+		startTime := aws.TimeValue(snapshot.StartTime)
+		if g.Oldest.IsZero() || startTime.Before(g.Oldest) {
+			g.Oldest = startTime
+		}
+		if startTime.After(g.Newest) {
+			g.Newest = startTime
+		}
+		return g
+	}
+	for _, snapshot := range snapshots {
+		add(snapshot)
+	}
+	for _, v := range failed {
+		add(v.Snapshot).Failed++
+	}
+	result := make([]*Group, 0, len(groups))
+	for _, g := range groups {
+		result = append(result, g)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Key < result[j].Key
+	})
+	return result
+}
@@ -0,0 +1,86 @@
+package snapshot
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func TestParseGroupBy(t *testing.T) {
+	type args struct {
+		s string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    GroupBy
+		wantErr bool
+	}{
+		{
+			name:    "volume",
+			args:    args{s: "volume"},
+			want:    GroupBy{Field: GroupByVolume},
+			wantErr: false,
+		},
+		{
+			name:    "tag",
+			args:    args{s: " tag:Team "},
+			want:    GroupBy{Field: GroupByTag, TagKey: "Team"},
+			wantErr: false,
+		},
+		{
+			name:    "tag without key",
+			args:    args{s: "tag:"},
+			want:    GroupBy{},
+			wantErr: true,
+		},
+		{
+			name:    "unknown",
+			args:    args{s: "foo"},
+			want:    GroupBy{},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseGroupBy(tt.args.s)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseGroupBy() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseGroupBy() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	jan := time.Date(2023, 1, 10, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2023, 2, 10, 0, 0, 0, 0, time.UTC)
+	newSizedSnapshot := func(id string, startTime time.Time, size int64) *ec2.Snapshot {
+		s := newSnapshot(id, startTime, nil)
+		s.VolumeSize = aws.Int64(size)
+		return s
+	}
+	successful := []*ec2.Snapshot{
+		newSizedSnapshot("snap-1", jan, 10),
+		newSizedSnapshot("snap-2", jan.Add(24*time.Hour), 20),
+		newSizedSnapshot("snap-3", feb, 30),
+	}
+	failed := []*ErrorWithSnapshot{
+		{Error: errors.New("foo"), Snapshot: newSizedSnapshot("snap-4", feb.Add(time.Hour), 40)},
+	}
+	want := []*Group{
+		{Key: "2023-01", Count: 2, TotalGiB: 30, Oldest: jan, Newest: jan.Add(24 * time.Hour), Failed: 0},
+		{Key: "2023-02", Count: 2, TotalGiB: 70, Oldest: feb, Newest: feb.Add(time.Hour), Failed: 1},
+	}
+	got := Summarize("us-east-1", GroupBy{Field: GroupByMonth}, successful, failed)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Summarize() = %v, want %v", got, want)
+	}
+}
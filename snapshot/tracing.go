@@ -0,0 +1,102 @@
+package snapshot
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/vvatanabe/aws-snapshot-bulk-delete/snapshot"
+
+const (
+	attrSnapshotID = attribute.Key("ec2.snapshot.id")
+	attrVolumeID   = attribute.Key("ec2.volume.id")
+	attrErrorCode  = attribute.Key("aws.error_code")
+	attrRequestID  = attribute.Key("aws.request_id")
+	attrSnapshots  = attribute.Key("snapshots.count")
+)
+
+// tracer returns the tracer of the global provider, which is a no-op unless
+// the application sets one.
+func tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		if code := errorCode(err); code != "" {
+			span.SetAttributes(attrErrorCode.String(code))
+		}
+	}
+	span.End()
+}
+
+func errorCode(err error) string {
+	var aerr awserr.Error
+	if errors.As(err, &aerr) {
+		return aerr.Code()
+	}
+	return ""
+}
+
+type apiSpanKey struct{}
+
+// startAPISpan starts a span for each AWS API request, so that each page of
+// DescribeSnapshots and each DeleteSnapshot call gets its own span.
+func startAPISpan(r *request.Request) {
+	ctx, span := tracer().Start(r.Context(), r.ClientInfo.ServiceName+"."+r.Operation.Name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("rpc.system", "aws-api"),
+			attribute.String("rpc.service", r.ClientInfo.ServiceName),
+			attribute.String("rpc.method", r.Operation.Name),
+		))
+	if id := inputSnapshotID(r.Params); id != "" {
+		span.SetAttributes(attrSnapshotID.String(id))
+	}
+	r.SetContext(context.WithValue(ctx, apiSpanKey{}, span))
+}
+
+func endAPISpan(r *request.Request) {
+	span, ok := r.Context().Value(apiSpanKey{}).(trace.Span)
+	if !ok {
+		return
+	}
+	if r.RequestID != "" {
+		span.SetAttributes(attrRequestID.String(r.RequestID))
+	}
+	endSpan(span, r.Error)
+}
+
+func inputSnapshotID(params interface{}) string {
+	switch v := params.(type) {
+	case *ec2.DeleteSnapshotInput:
+		return aws.StringValue(v.SnapshotId)
+	case *ec2.UnlockSnapshotInput:
+		return aws.StringValue(v.SnapshotId)
+	case *ec2.DescribeSnapshotAttributeInput:
+		return aws.StringValue(v.SnapshotId)
+	case *ec2.ModifySnapshotAttributeInput:
+		return aws.StringValue(v.SnapshotId)
+	case *ec2.CopySnapshotInput:
+		return aws.StringValue(v.SourceSnapshotId)
+	}
+	return ""
+}
+
+func snapshotAttributes(snapshot *ec2.Snapshot) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attrSnapshotID.String(aws.StringValue(snapshot.SnapshotId)),
+		attrVolumeID.String(aws.StringValue(snapshot.VolumeId)),
+	}
+}
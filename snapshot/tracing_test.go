@@ -0,0 +1,131 @@
+package snapshot
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client/metadata"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func setTestTracerProvider(t *testing.T) *tracetest.SpanRecorder {
+	sr := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+	return sr
+}
+
+func spanAttributes(span sdktrace.ReadOnlySpan) map[attribute.Key]string {
+	attrs := make(map[attribute.Key]string)
+	for _, v := range span.Attributes() {
+		attrs[v.Key] = v.Value.Emit()
+	}
+	return attrs
+}
+
+func TestBullDelete_processSnapshot_span(t *testing.T) {
+	sr := setTestTracerProvider(t)
+	startTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := &BullDelete{
+		svc: &ec2SnapshotAPIMock{
+			DeleteSnapshotWithContextFunc: func(ctx aws.Context, input *ec2.DeleteSnapshotInput, opts ...request.Option) (*ec2.DeleteSnapshotOutput, error) {
+				if aws.StringValue(input.SnapshotId) == "snap-in-use" {
+					return nil, awserr.New("InvalidSnapshot.InUse", "in use", nil)
+				}
+				return &ec2.DeleteSnapshotOutput{}, nil
+			},
+		},
+	}
+	snapshots := []*ec2.Snapshot{
+		newSnapshot("snap-ok", startTime, nil),
+		newSnapshot("snap-in-use", startTime, nil),
+	}
+	snapshots[1].VolumeId = aws.String("vol-1")
+	if _, _, err := c.deleteSnapshots(context.Background(), snapshots, nil, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	spans := sr.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("spans = %d, want 2", len(spans))
+	}
+	tests := []struct {
+		name       string
+		attrs      map[attribute.Key]string
+		statusCode codes.Code
+	}{
+		{
+			name:       "ok",
+			attrs:      map[attribute.Key]string{attrSnapshotID: "snap-ok", attrVolumeID: ""},
+			statusCode: codes.Unset,
+		},
+		{
+			name:       "in use",
+			attrs:      map[attribute.Key]string{attrSnapshotID: "snap-in-use", attrVolumeID: "vol-1", attrErrorCode: "InvalidSnapshot.InUse"},
+			statusCode: codes.Error,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			span := spans[i]
+			if span.Name() != "deleteSnapshot" {
+				t.Errorf("name = %v, want deleteSnapshot", span.Name())
+			}
+			if got := spanAttributes(span); !reflect.DeepEqual(got, tt.attrs) {
+				t.Errorf("attributes = %v, want %v", got, tt.attrs)
+			}
+			if span.Status().Code != tt.statusCode {
+				t.Errorf("status = %v, want %v", span.Status().Code, tt.statusCode)
+			}
+		})
+	}
+}
+
+func TestAPISpan(t *testing.T) {
+	sr := setTestTracerProvider(t)
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "parent")
+	httpReq, _ := http.NewRequest(http.MethodPost, "https://ec2.us-east-1.amazonaws.com/", nil)
+	r := &request.Request{
+		ClientInfo:  metadata.ClientInfo{ServiceName: "ec2"},
+		Operation:   &request.Operation{Name: "DeleteSnapshot"},
+		Params:      &ec2.DeleteSnapshotInput{SnapshotId: aws.String("snap-1")},
+		HTTPRequest: httpReq,
+	}
+	r.SetContext(ctx)
+	startAPISpan(r)
+	r.RequestID = "req-1"
+	r.Error = awserr.New("UnauthorizedOperation", "denied", nil)
+	endAPISpan(r)
+	parent.End()
+
+	spans := sr.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("spans = %d, want 2", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "ec2.DeleteSnapshot" || span.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("span = %v, parent %v", span.Name(), span.Parent().SpanID())
+	}
+	want := map[attribute.Key]string{
+		"rpc.system":   "aws-api",
+		"rpc.service":  "ec2",
+		"rpc.method":   "DeleteSnapshot",
+		attrSnapshotID: "snap-1",
+		attrRequestID:  "req-1",
+		attrErrorCode:  "UnauthorizedOperation",
+	}
+	if got := spanAttributes(span); !reflect.DeepEqual(got, want) {
+		t.Errorf("attributes = %v, want %v", got, want)
+	}
+}
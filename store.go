@@ -0,0 +1,236 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/vvatanabe/aws-snapshot-bulk-delete/snapshot"
+	bolt "go.etcd.io/bbolt"
+)
+
+type planStatus string
+
+const (
+	planStatusPlanned  planStatus = "planned"
+	planStatusApplying planStatus = "applying"
+	planStatusApplied  planStatus = "applied"
+	planStatusFailed   planStatus = "failed"
+)
+
+func (s planStatus) finished() bool {
+	return s == planStatusApplied || s == planStatusFailed
+}
+
+// planRecord is a plan created through the API, and its result once it is
+// applied.
+type planRecord struct {
+	ID         string                       `json:"id"`
+	Status     planStatus                   `json:"status"`
+	Config     *snapshot.BulkDeleteConfig   `json:"config"`
+	CreatedAt  time.Time                    `json:"created_at"`
+	ApprovedBy string                       `json:"approved_by,omitempty"`
+	ApprovedAt *time.Time                   `json:"approved_at,omitempty"`
+	Action     snapshot.Action              `json:"action"`
+	Snapshots  []*ec2.Snapshot              `json:"snapshots"`
+	Excluded   []*snapshot.ExcludedSnapshot `json:"excluded"`
+	NotFound   []string                     `json:"not_found"`
+	Result     *snapshot.Result             `json:"result,omitempty"`
+	Error      string                       `json:"error,omitempty"`
+}
+
+var errPlanNotFound = errors.New("plan not found")
+
+// planStore keeps the plans of the API. Records are copied in and out, so
+// that a caller never shares a record with another request.
+type planStore interface {
+	create(rec *planRecord) error
+	get(id string) (*planRecord, error)
+	// list returns every record.
+	list() ([]*planRecord, error)
+	// update applies fn to the record atomically, and stores it unless fn
+	// returns an error.
+	update(id string, fn func(rec *planRecord) error) (*planRecord, error)
+	close() error
+}
+
+func decodePlanRecord(b []byte) (*planRecord, error) {
+	var rec planRecord
+	if err := json.Unmarshal(b, &rec); err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
+
+// memoryStore loses the plans on exit.
+type memoryStore struct {
+	mu    sync.Mutex
+	plans map[string][]byte
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{plans: make(map[string][]byte)}
+}
+
+func (s *memoryStore) create(rec *planRecord) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.plans[rec.ID] = b
+	return nil
+}
+
+func (s *memoryStore) get(id string) (*planRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.plans[id]
+	if !ok {
+		return nil, errPlanNotFound
+	}
+	return decodePlanRecord(b)
+}
+
+func (s *memoryStore) list() ([]*planRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var recs []*planRecord
+	for _, b := range s.plans {
+		rec, err := decodePlanRecord(b)
+		if err != nil {
+			return nil, err
+		}
+		recs = append(recs, rec)
+	}
+	return recs, nil
+}
+
+func (s *memoryStore) update(id string, fn func(rec *planRecord) error) (*planRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.plans[id]
+	if !ok {
+		return nil, errPlanNotFound
+	}
+	rec, err := decodePlanRecord(b)
+	if err != nil {
+		return nil, err
+	}
+	if err := fn(rec); err != nil {
+		return nil, err
+	}
+	b, err = json.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+	s.plans[id] = b
+	return rec, nil
+}
+
+func (s *memoryStore) close() error {
+	return nil
+}
+
+var boltBucketPlans = []byte("plans")
+
+// boltStore keeps the plans in a BoltDB file, so that a plan can be applied
+// after a restart.
+type boltStore struct {
+	db *bolt.DB
+}
+
+func newBoltStore(name string) (*boltStore, error) {
+	// The timeout fails instead of waiting forever if another process holds
+	// the file.
+	db, err := bolt.Open(name, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltBucketPlans)
+		return err
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return &boltStore{db: db}, nil
+}
+
+func (s *boltStore) create(rec *planRecord) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucketPlans).Put([]byte(rec.ID), b)
+	})
+}
+
+func (s *boltStore) get(id string) (*planRecord, error) {
+	var rec *planRecord
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(boltBucketPlans).Get([]byte(id))
+		if b == nil {
+			return errPlanNotFound
+		}
+		var err error
+		rec, err = decodePlanRecord(b)
+		return err
+	})
+	return rec, err
+}
+
+func (s *boltStore) list() ([]*planRecord, error) {
+	var recs []*planRecord
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucketPlans).ForEach(func(k, b []byte) error {
+			rec, err := decodePlanRecord(b)
+			if err != nil {
+				return err
+			}
+			recs = append(recs, rec)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return recs, nil
+}
+
+func (s *boltStore) update(id string, fn func(rec *planRecord) error) (*planRecord, error) {
+	var rec *planRecord
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltBucketPlans)
+		b := bucket.Get([]byte(id))
+		if b == nil {
+			return errPlanNotFound
+		}
+		var err error
+		rec, err = decodePlanRecord(b)
+		if err != nil {
+			return err
+		}
+		if err := fn(rec); err != nil {
+			return err
+		}
+		b, err = json.Marshal(rec)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(id), b)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+func (s *boltStore) close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/vvatanabe/aws-snapshot-bulk-delete/snapshot"
+)
+
+func Test_planStore(t *testing.T) {
+	stores := map[string]func(t *testing.T) planStore{
+		"memory": func(t *testing.T) planStore {
+			return newMemoryStore()
+		},
+		"bolt": func(t *testing.T) planStore {
+			s, err := newBoltStore(filepath.Join(t.TempDir(), "plans.db"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return s
+		},
+	}
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			s := newStore(t)
+			defer s.close()
+			rec := &planRecord{
+				ID:        "plan-1",
+				Status:    planStatusPlanned,
+				Config:    &snapshot.BulkDeleteConfig{Region: "us-east-1", Age: 30},
+				CreatedAt: time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC),
+				Snapshots: []*ec2.Snapshot{{SnapshotId: aws.String("snap-1")}},
+			}
+			if err := s.create(rec); err != nil {
+				t.Fatal(err)
+			}
+			// The stored record is a copy.
+			rec.Status = planStatusFailed
+
+			got, err := s.get("plan-1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Status != planStatusPlanned || got.Config.Age != 30 || aws.StringValue(got.Snapshots[0].SnapshotId) != "snap-1" {
+				t.Errorf("get() = %+v", got)
+			}
+
+			got, err = s.update("plan-1", func(rec *planRecord) error {
+				rec.Status = planStatusApplying
+				return nil
+			})
+			if err != nil || got.Status != planStatusApplying {
+				t.Errorf("update() = %+v, %v", got, err)
+			}
+			errConflict := errors.New("conflict")
+			if _, err := s.update("plan-1", func(rec *planRecord) error {
+				rec.Status = planStatusApplied
+				return errConflict
+			}); err != errConflict {
+				t.Errorf("update() error = %v, want %v", err, errConflict)
+			}
+			if got, _ := s.get("plan-1"); got.Status != planStatusApplying {
+				t.Errorf("update() stored the record of a failed fn: %v", got.Status)
+			}
+
+			recs, err := s.list()
+			if err != nil || len(recs) != 1 || recs[0].ID != "plan-1" || recs[0].Status != planStatusApplying {
+				t.Errorf("list() = %+v, %v", recs, err)
+			}
+
+			if _, err := s.get("plan-2"); err != errPlanNotFound {
+				t.Errorf("get() error = %v, want %v", err, errPlanNotFound)
+			}
+			if _, err := s.update("plan-2", func(rec *planRecord) error { return nil }); err != errPlanNotFound {
+				t.Errorf("update() error = %v, want %v", err, errPlanNotFound)
+			}
+		})
+	}
+}
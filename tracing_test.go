@@ -0,0 +1,37 @@
+package main
+
+import "testing"
+
+func Test_tracingEnabled(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want bool
+	}{
+		{
+			name: "none",
+			env:  map[string]string{},
+			want: false,
+		},
+		{
+			name: "endpoint",
+			env:  map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://localhost:4318"},
+			want: true,
+		},
+		{
+			name: "exporter",
+			env:  map[string]string{"OTEL_TRACES_EXPORTER": "otlp"},
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, v := range tracingEnvVars {
+				t.Setenv(v, tt.env[v])
+			}
+			if got := tracingEnabled(); got != tt.want {
+				t.Errorf("tracingEnabled() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}